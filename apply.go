@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/artur-sak13/gitmv/plan"
+)
+
+const applyHelp = `Execute exactly the steps of a plan file written by plan.`
+
+func (cmd *applyCommand) Name() string      { return "apply" }
+func (cmd *applyCommand) Args() string      { return "[OPTIONS] <plan>" }
+func (cmd *applyCommand) ShortHelp() string { return applyHelp }
+func (cmd *applyCommand) LongHelp() string  { return applyHelp }
+func (cmd *applyCommand) Hidden() bool      { return false }
+
+func (cmd *applyCommand) Register(fs *flag.FlagSet) {}
+
+type applyCommand struct{}
+
+func (cmd *applyCommand) Run(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected one plan file, got %d arguments", len(args))
+	}
+
+	p, err := plan.Read(args[0])
+	if err != nil {
+		return err
+	}
+	if p.Source != from.planEndpoint() {
+		return fmt.Errorf("plan %s was made for source %s, not %s", args[0], p.Source, from.planEndpoint())
+	}
+	if p.Destination != to.planEndpoint() {
+		return fmt.Errorf("plan %s was made for destination %s, not %s", args[0], p.Destination, to.planEndpoint())
+	}
+	return migrate(ctx, p)
+}
@@ -0,0 +1,193 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 Artur Sak
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package filter
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/artur-sak13/gitmv/provider"
+)
+
+// Archived modes select whether archived repositories are migrated
+const (
+	ArchivedInclude = "include"
+	ArchivedExclude = "exclude"
+	ArchivedOnly    = "only"
+)
+
+// Filter selects source repositories by their path, topics, visibility, archived flag and last activity
+// Empty repositories are never selected and forks only when Forks is set, a nil Filter selects everything else.
+type Filter struct {
+	// Include and Exclude are namespaces or group paths, a group also matches its subgroups
+	Include []string
+	Exclude []string
+
+	// IncludePath and ExcludePath are matched against the full path of a repository, e.g. group/sub/repo
+	IncludePath *regexp.Regexp
+	ExcludePath *regexp.Regexp
+
+	// Topics and Visibility select repositories with any of their values, e.g. private, internal or public
+	Topics     []string
+	Visibility []string
+
+	// Archived is one of ArchivedInclude, ArchivedExclude or ArchivedOnly, empty means ArchivedInclude
+	Archived string
+
+	// ActiveSince and ActiveBefore bound the last activity of repositories when they are set.
+	// Repositories whose provider does not report their activity never match them.
+	ActiveSince  time.Time
+	ActiveBefore time.Time
+
+	// Manifest lists the lower case full paths or names of the only repositories to select when it is not empty
+	Manifest map[string]bool
+
+	// Forks selects forked repositories
+	Forks bool
+}
+
+// Validate checks the Archived mode of a Filter
+func (f *Filter) Validate() error {
+	switch f.Archived {
+	case "", ArchivedInclude, ArchivedExclude, ArchivedOnly:
+		return nil
+	}
+	return fmt.Errorf("invalid archived mode %q, must be one of %s, %s or %s", f.Archived, ArchivedInclude, ArchivedExclude, ArchivedOnly)
+}
+
+// Match reports whether a repository is selected
+func (f *Filter) Match(repo *provider.GitRepository) bool {
+	if repo.Empty {
+		return false
+	}
+	if f == nil {
+		return !repo.Fork
+	}
+	if repo.Fork && !f.Forks {
+		return false
+	}
+
+	path := strings.ToLower(repo.FullPath())
+	if len(f.Manifest) > 0 && !f.Manifest[path] && !f.Manifest[strings.ToLower(repo.Name)] {
+		return false
+	}
+	if len(f.Include) > 0 && !inNamespaces(path, f.Include) {
+		return false
+	}
+	if inNamespaces(path, f.Exclude) {
+		return false
+	}
+	if f.IncludePath != nil && !f.IncludePath.MatchString(repo.FullPath()) {
+		return false
+	}
+	if f.ExcludePath != nil && f.ExcludePath.MatchString(repo.FullPath()) {
+		return false
+	}
+	if len(f.Topics) > 0 && !containsAny(repo.Topics, f.Topics) {
+		return false
+	}
+	if len(f.Visibility) > 0 && !containsAny([]string{repo.Visibility}, f.Visibility) {
+		return false
+	}
+
+	switch f.Archived {
+	case ArchivedExclude:
+		if repo.Archived {
+			return false
+		}
+	case ArchivedOnly:
+		if !repo.Archived {
+			return false
+		}
+	}
+
+	if !f.ActiveSince.IsZero() && (repo.LastActivity.IsZero() || repo.LastActivity.Before(f.ActiveSince)) {
+		return false
+	}
+	if !f.ActiveBefore.IsZero() && (repo.LastActivity.IsZero() || !repo.LastActivity.Before(f.ActiveBefore)) {
+		return false
+	}
+	return true
+}
+
+// Select returns the repositories a Filter matches
+func (f *Filter) Select(repos []*provider.GitRepository) []*provider.GitRepository {
+	var selected []*provider.GitRepository
+	for _, repo := range repos {
+		if f.Match(repo) {
+			selected = append(selected, repo)
+		}
+	}
+	return selected
+}
+
+// inNamespaces reports whether a lower case path is one of the namespaces or inside one of them
+func inNamespaces(path string, namespaces []string) bool {
+	for _, ns := range namespaces {
+		ns = strings.ToLower(strings.Trim(ns, "/"))
+		if ns != "" && (path == ns || strings.HasPrefix(path, ns+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAny reports whether values and wanted share a value, ignoring case
+func containsAny(values, wanted []string) bool {
+	for _, v := range values {
+		for _, w := range wanted {
+			if strings.EqualFold(v, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// LoadManifest reads a file listing one repository full path or name per line
+// Blank lines and lines starting with # are ignored.
+func LoadManifest(path string) (map[string]bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open manifest %s due to: %v", path, err)
+	}
+	defer file.Close()
+
+	manifest := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		manifest[strings.ToLower(strings.Trim(line, "/"))] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s due to: %v", path, err)
+	}
+	return manifest, nil
+}
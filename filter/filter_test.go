@@ -0,0 +1,112 @@
+package filter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/artur-sak13/gitmv/provider"
+)
+
+func TestFilter_Match(t *testing.T) {
+	active := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	repo := &provider.GitRepository{
+		Name:         "api",
+		Namespace:    "Platform/backend",
+		Topics:       []string{"go", "service"},
+		Visibility:   "internal",
+		LastActivity: active,
+	}
+
+	tests := []struct {
+		name   string
+		filter *Filter
+		repo   *provider.GitRepository
+		want   bool
+	}{
+		{"nil", nil, repo, true},
+		{"nil skips forks", nil, &provider.GitRepository{Name: "f", Fork: true}, false},
+		{"skips empty", &Filter{Forks: true}, &provider.GitRepository{Name: "e", Empty: true}, false},
+		{"forks", &Filter{Forks: true}, &provider.GitRepository{Name: "f", Fork: true}, true},
+		{"include group", &Filter{Include: []string{"platform"}}, repo, true},
+		{"include subgroup", &Filter{Include: []string{"platform/backend/"}}, repo, true},
+		{"include repo", &Filter{Include: []string{"platform/backend/api"}}, repo, true},
+		{"include prefix is not a group", &Filter{Include: []string{"plat"}}, repo, false},
+		{"exclude group", &Filter{Exclude: []string{"platform/backend"}}, repo, false},
+		{"exclude other", &Filter{Exclude: []string{"platform/frontend"}}, repo, true},
+		{"include path", &Filter{IncludePath: regexp.MustCompile(`/api$`)}, repo, true},
+		{"exclude path", &Filter{ExcludePath: regexp.MustCompile(`^Platform/`)}, repo, false},
+		{"topic", &Filter{Topics: []string{"Go"}}, repo, true},
+		{"missing topic", &Filter{Topics: []string{"rust"}}, repo, false},
+		{"visibility", &Filter{Visibility: []string{"private", "internal"}}, repo, true},
+		{"other visibility", &Filter{Visibility: []string{"public"}}, repo, false},
+		{"archived included", &Filter{}, &provider.GitRepository{Name: "a", Archived: true}, true},
+		{"archived excluded", &Filter{Archived: ArchivedExclude}, &provider.GitRepository{Name: "a", Archived: true}, false},
+		{"archived only", &Filter{Archived: ArchivedOnly}, repo, false},
+		{"active since", &Filter{ActiveSince: active.AddDate(0, -1, 0)}, repo, true},
+		{"inactive", &Filter{ActiveSince: active.AddDate(0, 1, 0)}, repo, false},
+		{"active before", &Filter{ActiveBefore: active}, repo, false},
+		{"unknown activity", &Filter{ActiveBefore: active}, &provider.GitRepository{Name: "u"}, false},
+		{"manifest path", &Filter{Manifest: map[string]bool{"platform/backend/api": true}}, repo, true},
+		{"manifest name", &Filter{Manifest: map[string]bool{"api": true}}, repo, true},
+		{"not in manifest", &Filter{Manifest: map[string]bool{"platform/backend/web": true}}, repo, false},
+		{"owner without namespace", &Filter{Include: []string{"o"}}, &provider.GitRepository{Name: "r", Owner: "o"}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Match(tt.repo); got != tt.want {
+			t.Errorf("%s: Match = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFilter_Select(t *testing.T) {
+	repos := []*provider.GitRepository{
+		{Name: "api", Namespace: "backend"},
+		{Name: "api", Namespace: "frontend"},
+		{Name: "web", Namespace: "frontend"},
+	}
+	if got := (*Filter)(nil).Select(repos); !reflect.DeepEqual(got, repos) {
+		t.Errorf("Select = %v, want %v", got, repos)
+	}
+	if got := (&Filter{Exclude: []string{"backend"}}).Select(repos); !reflect.DeepEqual(got, repos[1:]) {
+		t.Errorf("Select = %v, want %v", got, repos[1:])
+	}
+}
+
+func TestFilter_Validate(t *testing.T) {
+	if err := (&Filter{Archived: ArchivedOnly}).Validate(); err != nil {
+		t.Errorf("Validate returned error: %v", err)
+	}
+	if err := (&Filter{Archived: "never"}).Validate(); err == nil {
+		t.Errorf("Validate accepted an invalid archived mode")
+	}
+}
+
+func TestLoadManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gitmv-manifest")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "manifest.txt")
+	if err := ioutil.WriteFile(path, []byte("# platform\nPlatform/backend/api\n\n  tools/cli/ \n"), 0644); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+
+	got, err := LoadManifest(path)
+	if err != nil {
+		t.Fatalf("LoadManifest returned error: %v", err)
+	}
+	want := map[string]bool{"platform/backend/api": true, "tools/cli": true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadManifest = %v, want %v", got, want)
+	}
+
+	if _, err := LoadManifest(filepath.Join(dir, "missing.txt")); err == nil {
+		t.Errorf("LoadManifest accepted a missing file")
+	}
+}
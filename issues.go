@@ -3,7 +3,12 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
+	"os"
 
+	"github.com/sirupsen/logrus"
+
+	"github.com/artur-sak13/gitmv/migrator"
 	"github.com/artur-sak13/gitmv/provider"
 )
 
@@ -23,7 +28,22 @@ func (cmd *issuesCommand) Run(ctx context.Context, args []string) error {
 	return runCommand(ctx, cmd.handleIssues)
 }
 
-// handleIssues will return
+// handleIssues will migrate the labels, issues and comments of the selected repositories already in the destination
 func (cmd *issuesCommand) handleIssues(ctx context.Context, src, dest provider.GitProvider) error {
+	mig := newMigrator(src, dest)
+	mig.State = journal
+
+	err := mig.RunIssues(ctx)
+	if err != nil && err != migrator.ErrIncomplete && err != context.Canceled {
+		return err
+	}
+
+	fmt.Println()
+	mig.Report.Print(os.Stdout)
+	if err != nil {
+		logrus.Infof("issue migration incomplete, run again to resume: %v", err)
+		journal.Close()
+		os.Exit(2)
+	}
 	return nil
 }
@@ -26,14 +26,22 @@ import (
 	"context"
 	"errors"
 	"flag"
+	"fmt"
 	"os"
 	"os/signal"
+	"regexp"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/artur-sak13/gitmv/migrator"
 
 	"github.com/artur-sak13/gitmv/auth"
+	"github.com/artur-sak13/gitmv/filter"
+	"github.com/artur-sak13/gitmv/naming"
+	"github.com/artur-sak13/gitmv/plan"
 	"github.com/artur-sak13/gitmv/provider"
+	"github.com/artur-sak13/gitmv/state"
 
 	"github.com/artur-sak13/gitmv/version"
 
@@ -46,17 +54,160 @@ import (
 // *     [X] Make it work
 // ?     [?] Make it fast
 // TODO: [ ] Make it elegant
-// TODO: Fix incorrect issue numbers between source and dest repos
 var (
 	githubToken string
+	githubURL   string
 	gitlabToken string
 	gitlabUser  string
 	customURL   string
 	org         string
 	debug       bool
 	dryrun      bool
+
+	preserveIssueNumbers bool
+
+	failFast        bool
+	continueOnError bool
+
+	stateFile string
+	journal   *state.Journal
+
+	repoWorkers    int
+	issueWorkers   int
+	apiConcurrency int
+	requestTimeout time.Duration
+
+	from endpoint
+	to   endpoint
+
+	selected   selection
+	repoFilter *filter.Filter
+
+	namingStrategy string
+	repoNaming     provider.NameFunc
+
+	rulesFile string
+
+	members     bool
+	userMapFile string
+	users       map[string]string
 )
 
+// endpoint stores the flags describing one side of a migration
+type endpoint struct {
+	kind  string
+	url   string
+	token string
+	owner string
+}
+
+// authID resolves an endpoint's authentication data, falling back to the
+// provider specific flags when the endpoint flags are not set
+func (e endpoint) authID() *auth.ID {
+	id := auth.NewAuthID(e.url, e.token, e.owner)
+	switch strings.ToLower(e.kind) {
+	case "github":
+		if id.Token == "" {
+			id.Token = githubToken
+		}
+		if id.Owner == "" {
+			id.Owner = org
+		}
+		if id.URL == "" {
+			id.URL = githubURL
+		}
+	case "gitlab":
+		if id.Token == "" {
+			id.Token = gitlabToken
+		}
+		if id.URL == "" {
+			id.URL = customURL
+		}
+	}
+	return id
+}
+
+// selection stores the flags selecting the source repositories every command works on
+type selection struct {
+	include      string
+	exclude      string
+	includeRegex string
+	excludeRegex string
+	topics       string
+	visibility   string
+	archived     string
+	activeSince  string
+	activeBefore string
+	manifest     string
+	forks        bool
+}
+
+// filter builds the repository filter described by the selection flags
+func (s selection) filter() (*filter.Filter, error) {
+	f := &filter.Filter{
+		Include:    splitList(s.include),
+		Exclude:    splitList(s.exclude),
+		Topics:     splitList(s.topics),
+		Visibility: splitList(s.visibility),
+		Archived:   s.archived,
+		Forks:      s.forks,
+	}
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if s.includeRegex != "" {
+		if f.IncludePath, err = regexp.Compile(s.includeRegex); err != nil {
+			return nil, fmt.Errorf("invalid --include-regex: %v", err)
+		}
+	}
+	if s.excludeRegex != "" {
+		if f.ExcludePath, err = regexp.Compile(s.excludeRegex); err != nil {
+			return nil, fmt.Errorf("invalid --exclude-regex: %v", err)
+		}
+	}
+	if s.activeSince != "" {
+		if f.ActiveSince, err = time.Parse(dateLayout, s.activeSince); err != nil {
+			return nil, fmt.Errorf("invalid --active-since date %q, want YYYY-MM-DD", s.activeSince)
+		}
+	}
+	if s.activeBefore != "" {
+		if f.ActiveBefore, err = time.Parse(dateLayout, s.activeBefore); err != nil {
+			return nil, fmt.Errorf("invalid --active-before date %q, want YYYY-MM-DD", s.activeBefore)
+		}
+	}
+	if s.manifest != "" {
+		if f.Manifest, err = filter.LoadManifest(s.manifest); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// dateLayout is the format of the date flags
+const dateLayout = "2006-01-02"
+
+// splitList splits a comma separated flag value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// needsToken reports whether the endpoint's provider talks to an authenticated API
+func (e endpoint) needsToken() bool {
+	switch strings.ToLower(e.kind) {
+	case "local", "fake":
+		return false
+	}
+	return true
+}
+
 func main() {
 	p := cli.NewProgram()
 	p.Name = "gitmv"
@@ -65,10 +216,18 @@ func main() {
 	p.GitCommit = version.GITCOMMIT
 	p.Version = version.VERSION
 
+	p.Action = runMigration
+
 	p.Commands = []cli.Command{
 		&reposCommand{},
 		&issuesCommand{},
 		&wikisCommand{},
+		&planCommand{},
+		&applyCommand{},
+		&verifyCommand{},
+		&rollbackCommand{},
+		&syncCommand{},
+		&serveCommand{},
 	}
 
 	p.FlagSet = flag.NewFlagSet("global", flag.ExitOnError)
@@ -76,6 +235,8 @@ func main() {
 	p.FlagSet.StringVar(&gitlabToken, "gitlab-token", os.Getenv("GITLAB_TOKEN"), "GitLab API token (or env var GITLAB_TOKEN)")
 	p.FlagSet.StringVar(&gitlabUser, "gitlab-user", os.Getenv("GITLAB_USER"), "GitLab Username")
 
+	p.FlagSet.StringVar(&githubURL, "github-url", os.Getenv("GITHUB_URL"), "GitHub Enterprise Server URL (or env var GITHUB_URL)")
+
 	p.FlagSet.StringVar(&org, "org", os.Getenv("GHORG"), "GitHub org to move repositories")
 
 	p.FlagSet.StringVar(&customURL, "url", os.Getenv("GITLAB_URL"), "Custom GitLab URL")
@@ -86,17 +247,80 @@ func main() {
 
 	p.FlagSet.BoolVar(&dryrun, "dry-run", false, "do not run migration just print the changes that would occur")
 
+	p.FlagSet.BoolVar(&preserveIssueNumbers, "preserve-issue-numbers", false, "create issues in order with closed placeholders for gaps so issue numbers match the source (pull requests are numbered after issues)")
+
+	p.FlagSet.BoolVar(&failFast, "fail-fast", false, "stop starting new work after the first failure")
+	p.FlagSet.BoolVar(&continueOnError, "continue-on-error", true, "keep migrating past failures and report them at the end")
+
+	p.FlagSet.StringVar(&stateFile, "state", "gitmv-state.jsonl", "file recording migrated entities so an interrupted run resumes where it stopped (empty to disable)")
+
+	p.FlagSet.IntVar(&repoWorkers, "repo-workers", migrator.DefaultRepoWorkers, "number of repositories migrated at once")
+	p.FlagSet.IntVar(&issueWorkers, "issue-workers", migrator.DefaultIssueWorkers, "number of labels and issue comments migrated at once within a repository")
+	p.FlagSet.IntVar(&apiConcurrency, "api-concurrency", 8, "maximum concurrent API calls to each Git provider (0 for unlimited)")
+
+	p.FlagSet.DurationVar(&requestTimeout, "request-timeout", 0, "maximum duration of each API call, e.g. 30s (0 for no limit)")
+
+	p.FlagSet.IntVar(&provider.Retry.Retries, "retries", provider.DefaultRetryPolicy.Retries, "number of times a rate limited or failed API request is retried")
+	p.FlagSet.DurationVar(&provider.Retry.MaxWait, "retry-max-wait", provider.DefaultRetryPolicy.MaxWait, "longest wait for an API rate limit to reset before giving up")
+
+	kinds := strings.Join(provider.Kinds(), ", ")
+
+	p.FlagSet.StringVar(&from.kind, "from", "gitlab", fmt.Sprintf("Git provider to migrate from (%s)", kinds))
+	p.FlagSet.StringVar(&from.url, "from-url", "", "API URL of the source Git provider, or a directory for local (defaults to --url for gitlab, --github-url for github)")
+	p.FlagSet.StringVar(&from.token, "from-token", "", "API token of the source Git provider (defaults to the provider's token flag)")
+	p.FlagSet.StringVar(&from.owner, "from-owner", "", "Org, group or user to migrate from")
+
+	p.FlagSet.StringVar(&to.kind, "to", "github", fmt.Sprintf("Git provider to migrate to (%s)", kinds))
+	p.FlagSet.StringVar(&to.url, "to-url", "", "API URL of the destination Git provider, or a directory for local (defaults to --url for gitlab, --github-url for github)")
+	p.FlagSet.StringVar(&to.token, "to-token", "", "API token of the destination Git provider (defaults to the provider's token flag)")
+	p.FlagSet.StringVar(&to.owner, "to-owner", "", "Org, group or user to migrate to (defaults to --org for github)")
+
+	p.FlagSet.StringVar(&selected.include, "include", "", "comma separated namespaces or group paths to migrate, a group includes its subgroups")
+	p.FlagSet.StringVar(&selected.exclude, "exclude", "", "comma separated namespaces or group paths to skip")
+	p.FlagSet.StringVar(&selected.includeRegex, "include-regex", "", "regular expression the full path of migrated repositories must match, e.g. ^group/.*-service$")
+	p.FlagSet.StringVar(&selected.excludeRegex, "exclude-regex", "", "regular expression matching the full path of repositories to skip")
+	p.FlagSet.StringVar(&selected.topics, "topic", "", "comma separated topics, only repositories with one of them are migrated")
+	p.FlagSet.StringVar(&selected.visibility, "visibility", "", "comma separated visibilities to migrate (private, internal, public)")
+	p.FlagSet.StringVar(&selected.archived, "archived", filter.ArchivedInclude, fmt.Sprintf("whether archived repositories are migrated (%s, %s, %s)", filter.ArchivedInclude, filter.ArchivedExclude, filter.ArchivedOnly))
+	p.FlagSet.StringVar(&selected.activeSince, "active-since", "", "only migrate repositories with activity on or after this date (YYYY-MM-DD)")
+	p.FlagSet.StringVar(&selected.activeBefore, "active-before", "", "only migrate repositories without activity since this date (YYYY-MM-DD)")
+	p.FlagSet.StringVar(&selected.manifest, "manifest", "", "file listing the full path or name of each repository to migrate, one per line")
+	p.FlagSet.BoolVar(&selected.forks, "forks", false, "also migrate forked repositories")
+
+	p.FlagSet.StringVar(&namingStrategy, "naming", naming.Path, fmt.Sprintf("name of GitLab repositories in the destination: %s, the namespace and path joined by %s or %s, or a Go template over .Namespace, .Group and .Path", naming.Path, naming.Dash, naming.Underscore))
+
+	p.FlagSet.StringVar(&rulesFile, "rules", "", "file routing repositories to destination owners, each line holds a namespace pattern such as platform/* and an owner")
+
+	p.FlagSet.BoolVar(&members, "members", false, "grant the members of each repository and its groups access to the destination as collaborators with their strongest permission")
+	p.FlagSet.StringVar(&userMapFile, "user-map", "", "file mapping source usernames to destination logins, each line holds a username and a login")
+
 	p.Before = func(ctx context.Context) error {
 		if debug {
 			logrus.SetLevel(logrus.DebugLevel)
 		}
 
-		if len(githubToken) < 1 {
-			return errors.New("github token cannot be empty")
+		var err error
+		if repoFilter, err = selected.filter(); err != nil {
+			return err
+		}
+		if repoNaming, err = naming.Parse(namingStrategy); err != nil {
+			return err
+		}
+		if members {
+			if userMapFile == "" {
+				return fmt.Errorf("--members requires --user-map")
+			}
+			if users, err = migrator.LoadUsers(userMapFile); err != nil {
+				return err
+			}
+		}
+
+		if from.needsToken() && len(from.authID().Token) < 1 {
+			return fmt.Errorf("%s source token cannot be empty", from.kind)
 		}
 
-		if len(gitlabToken) < 1 {
-			return errors.New("gitlab token cannot be empty")
+		if to.needsToken() && len(to.authID().Token) < 1 {
+			return fmt.Errorf("%s destination token cannot be empty", to.kind)
 		}
 
 		return nil
@@ -105,41 +329,57 @@ func main() {
 	p.Run()
 }
 
-func runCommand(ctx context.Context, cmd func(context.Context, provider.GitProvider, provider.GitProvider) error) error {
-	// On ^C, or SIGTERM handle exit.
-	signals := make(chan os.Signal, 0)
-	signal.Notify(signals, os.Interrupt)
-	signal.Notify(signals, syscall.SIGTERM)
-
-	var cancel context.CancelFunc
-	ctx, cancel = context.WithCancel(ctx)
+// withSignals returns a context that is cancelled on ^C or SIGTERM, so that in-flight
+// requests stop and the state file is flushed. A second signal exits immediately.
+func withSignals(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
 
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
 	go func() {
-		for sig := range signals {
-			cancel()
-			logrus.Infof("Received %s, exiting.", sig.String())
-			os.Exit(0)
-		}
+		sig := <-signals
+		logrus.Infof("received %s, stopping (repeat to exit immediately)", sig.String())
+		agent.Close()
+		cancel()
+
+		sig = <-signals
+		logrus.Infof("received %s, exiting.", sig.String())
+		os.Exit(1)
 	}()
 
-	if err := agent.Listen(agent.Options{
-		ShutdownCleanup: true, // automatically closes on os.Interrupt
-	}); err != nil {
-		logrus.Fatalf("gops agent failed: %v", err)
+	return ctx, cancel
+}
+
+// sleepContext waits for d or until ctx is cancelled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
+}
 
-	glAuth := auth.NewAuthID(customURL, gitlabToken, "")
-	ghAuth := auth.NewAuthID("", githubToken, org)
+func runCommand(ctx context.Context, cmd func(context.Context, provider.GitProvider, provider.GitProvider) error) error {
+	ctx, cancel := withSignals(ctx)
+	defer cancel()
 
-	src, err := provider.NewGitlabProvider(glAuth)
+	if err := agent.Listen(agent.Options{}); err != nil {
+		logrus.Fatalf("gops agent failed: %v", err)
+	}
+
+	src, dest, err := newProviders(ctx)
 	if err != nil {
 		return err
 	}
 
-	dest, err := provider.NewGithubProvider(ctx, ghAuth)
+	journal, err = openJournal()
 	if err != nil {
 		return err
 	}
+	defer journal.Close()
 
 	if err := cmd(ctx, src, dest); err != nil {
 		logrus.Fatalf("error: %v", err)
@@ -150,52 +390,163 @@ func runCommand(ctx context.Context, cmd func(context.Context, provider.GitProvi
 }
 
 func runMigration(ctx context.Context, args []string) error {
-	// On ^C, or SIGTERM handle exit.
-	signals := make(chan os.Signal, 1)
-	signal.Notify(signals, os.Interrupt)
-	signal.Notify(signals, syscall.SIGTERM)
+	if dryrun {
+		return runCommand(ctx, printPlan)
+	}
+	return migrate(ctx, nil)
+}
 
-	var cancel context.CancelFunc
-	_, cancel = context.WithCancel(ctx)
-	go func() {
-		for sig := range signals {
-			cancel()
-			logrus.Infof("received %s, exiting.", sig.String())
-			os.Exit(0)
-		}
-	}()
+// migrate runs a full migration, or only the create and update steps of scope if it is not nil
+func migrate(ctx context.Context, scope *plan.Plan) error {
+	ctx, cancel := withSignals(ctx)
+	defer cancel()
 
-	if err := agent.Listen(agent.Options{
-		ShutdownCleanup: true, // automatically closes on os.Interrupt
-	}); err != nil {
+	if err := agent.Listen(agent.Options{}); err != nil {
 		logrus.Fatalf("gops agent failed: %v", err)
 	}
 
-	a := auth.NewAuthID(customURL, gitlabToken, "")
-	src, err := provider.NewGitlabProvider(a)
+	src, dest, err := newProviders(ctx)
 	if err != nil {
 		logrus.Fatalf("error initializing GitProvider: %v", err)
 		os.Exit(1)
 	}
 
-	var dest provider.GitProvider
+	journal, err = openJournal()
+	if err != nil {
+		logrus.Fatalf("error opening state file: %v", err)
+		os.Exit(1)
+	}
+	defer journal.Close()
 
-	if dryrun {
-		dest = provider.NewFakeProvider()
-	} else {
-		id := auth.NewAuthID("", githubToken, org)
-		dest, err = provider.NewGithubProvider(ctx, id)
-		if err != nil {
-			logrus.Fatalf("error initializing GitProvider: %v", err)
+	mig := newMigrator(src, dest)
+	if scope != nil {
+		// skipped steps are journaled so that the migration reuses their existing destination
+		if journal == nil {
+			journal = state.New()
+		}
+		if err := scope.Seed(journal); err != nil {
+			logrus.Fatalf("error recording plan: %v", err)
 			os.Exit(1)
 		}
+		mig.Scope = scope
 	}
-	mig := migrator.NewMigrator(src, dest)
-	err = mig.Run()
-	if err != nil {
+	mig.State = journal
+
+	err = mig.Run(ctx)
+	if err != nil && err != migrator.ErrIncomplete && err != context.Canceled {
 		logrus.Fatalf("error moving repos: %v", err)
 		os.Exit(1)
 	}
 
+	// planned steps the source no longer has are reported rather than silently dropped
+	if err == nil && scope != nil {
+		for _, step := range scope.Pending() {
+			mig.Report.Fail(step.Repo, step.Kind, step.ID, errors.New("planned but not found in the source"))
+			err = migrator.ErrIncomplete
+		}
+	}
+
+	fmt.Println()
+	mig.Report.Print(os.Stdout)
+
+	// a partial failure or interruption exits with a distinct code so scripts can resume the migration
+	if err != nil {
+		logrus.Infof("migration incomplete, run again to resume: %v", err)
+		journal.Close()
+		os.Exit(2)
+	}
+
 	return nil
 }
+
+// newMigrator creates a Migrator configured by the command line flags
+func newMigrator(src, dest provider.GitProvider) *migrator.Migrator {
+	mig := migrator.NewMigrator(src, dest)
+	mig.PreserveIssueNumbers = preserveIssueNumbers
+	mig.SourceKind = from.kind
+	mig.RepoWorkers = repoWorkers
+	mig.IssueWorkers = issueWorkers
+	mig.FailFast = failFast || !continueOnError
+	mig.Filter = repoFilter
+	mig.Members = members
+	mig.Users = users
+	return mig
+}
+
+// newProviders creates the source and destination GitProviders selected by the --from and --to flags
+func newProviders(ctx context.Context) (provider.GitProvider, provider.GitProvider, error) {
+	src, err := provider.New(ctx, from.kind, from.authID())
+	if err != nil {
+		return nil, nil, fmt.Errorf("error initializing source: %v", err)
+	}
+	if repoNaming != nil {
+		gitlabSrc, ok := src.(*provider.GitlabProvider)
+		if !ok {
+			return nil, nil, fmt.Errorf("--naming %s requires a gitlab source", namingStrategy)
+		}
+		gitlabSrc.Naming = repoNaming
+	}
+
+	src = provider.Timeout(provider.Limit(src, apiConcurrency), requestTimeout)
+
+	if err := checkNames(ctx, src); err != nil {
+		return nil, nil, err
+	}
+
+	dest, err := newDestination(ctx, src)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error initializing destination: %v", err)
+	}
+	return src, dest, nil
+}
+
+// checkNames fails when selected source repositories share a name, so that no command writes anything that would merge them
+func checkNames(ctx context.Context, src provider.GitProvider) error {
+	repos, err := src.GetRepositories(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting source repositories: %v", err)
+	}
+	return naming.Check(repoFilter.Select(repos))
+}
+
+// newDestination creates the destination GitProvider
+// With --rules it routes each repository to a provider for its owner, --to-owner receives unmatched repositories.
+func newDestination(ctx context.Context, src provider.GitProvider) (provider.GitProvider, error) {
+	if rulesFile == "" {
+		dest, err := provider.New(ctx, to.kind, to.authID())
+		if err != nil {
+			return nil, err
+		}
+		return provider.Timeout(provider.Limit(dest, apiConcurrency), requestTimeout), nil
+	}
+
+	routes, err := provider.LoadRoutes(rulesFile)
+	if err != nil {
+		return nil, err
+	}
+	id := to.authID()
+	dests := make(map[string]provider.GitProvider)
+	owners := []string{id.Owner}
+	for _, route := range routes {
+		owners = append(owners, route.Owner)
+	}
+	for _, owner := range owners {
+		if _, ok := dests[owner]; ok {
+			continue
+		}
+		dest, err := provider.New(ctx, to.kind, auth.NewAuthID(id.URL, id.Token, owner))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create destination for %s due to: %v", owner, err)
+		}
+		dests[owner] = provider.Timeout(provider.Limit(dest, apiConcurrency), requestTimeout)
+	}
+	return provider.NewRouter(src, routes, dests, id.Owner), nil
+}
+
+// openJournal opens the state file selected by --state, dry runs are never recorded
+func openJournal() (*state.Journal, error) {
+	if dryrun || stateFile == "" {
+		return nil, nil
+	}
+	return state.Open(stateFile)
+}
@@ -0,0 +1,96 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 Artur Sak
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package migrator
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/artur-sak13/gitmv/provider"
+	"github.com/artur-sak13/gitmv/state"
+)
+
+// LoadUsers reads a user map file of "source-username destination-login" lines, blank lines and lines starting with # are ignored
+func LoadUsers(file string) (map[string]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open user map %s due to: %v", file, err)
+	}
+	defer f.Close()
+
+	users := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected a source username and a destination login", file, n)
+		}
+		users[fields[0]] = fields[1]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read user map %s due to: %v", file, err)
+	}
+	return users, nil
+}
+
+// processMembers grants the mapped members of a source repository access to its destination
+func (m *Migrator) processMembers(ctx context.Context, repo, destRepo *provider.GitRepository) {
+	if m.skipped(repo.Name, state.KindMembers, repo.Name) {
+		return
+	}
+	members, err := m.Src.GetMembers(ctx, repo)
+	if err != nil {
+		m.fail(repo.Name, state.KindMembers, "", err)
+		return
+	}
+
+	var granted []*provider.GitMember
+	for _, member := range members {
+		login, ok := m.Users[member.User.Login]
+		if !ok {
+			logrus.WithFields(logrus.Fields{
+				"repo": repo.Name,
+				"user": member.User.Login,
+			}).Warnf("skipping member missing from the user map")
+			continue
+		}
+		mapped := *member
+		mapped.User = provider.GitUser{Login: login}
+		granted = append(granted, &mapped)
+	}
+
+	if err := m.Dest.GrantAccess(ctx, destRepo, granted); err != nil {
+		m.fail(repo.Name, state.KindMembers, "", err)
+		return
+	}
+	m.record(repo.Name, state.KindMembers, repo.Name, granted)
+}
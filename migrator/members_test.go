@@ -0,0 +1,80 @@
+package migrator
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/artur-sak13/gitmv/provider"
+	"github.com/artur-sak13/gitmv/state"
+)
+
+// membersSource serves the members of every repository
+type membersSource struct {
+	provider.GitProvider
+	members []*provider.GitMember
+}
+
+func (s *membersSource) GetMembers(ctx context.Context, repo *provider.GitRepository) ([]*provider.GitMember, error) {
+	return s.members, nil
+}
+
+func TestLoadUsers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gitmv-users")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "users")
+	if err := ioutil.WriteFile(file, []byte("# gitlab github\njane jane-gh\n\njohn  john-gh\n"), 0644); err != nil {
+		t.Fatalf("failed to write user map: %v", err)
+	}
+	users, err := LoadUsers(file)
+	if err != nil {
+		t.Fatalf("LoadUsers returned error: %v", err)
+	}
+	if want := map[string]string{"jane": "jane-gh", "john": "john-gh"}; !reflect.DeepEqual(users, want) {
+		t.Errorf("LoadUsers = %v, want %v", users, want)
+	}
+
+	if err := ioutil.WriteFile(file, []byte("jane\n"), 0644); err != nil {
+		t.Fatalf("failed to write user map: %v", err)
+	}
+	if _, err := LoadUsers(file); err == nil {
+		t.Errorf("LoadUsers expected error for a line without a destination login")
+	}
+}
+
+func TestProcessMembers(t *testing.T) {
+	ctx := context.Background()
+	src := &membersSource{members: []*provider.GitMember{
+		{User: provider.GitUser{Login: "jane", Email: "jane@example.com"}, Permission: provider.PermissionWrite, Group: "g"},
+		{User: provider.GitUser{Login: "john"}, Permission: provider.PermissionAdmin},
+	}}
+	dest := provider.NewFakeProvider().(*provider.FakeProvider)
+	repo := &provider.GitRepository{Name: "r"}
+	destRepo, err := dest.CreateRepository(ctx, repo)
+	if err != nil {
+		t.Fatalf("CreateRepository returned error: %v", err)
+	}
+
+	m := NewMigrator(src, dest)
+	m.State = state.New()
+	m.Users = map[string]string{"jane": "jane-gh"}
+	m.processMembers(ctx, repo, destRepo)
+	// journaled members are not granted again
+	m.processMembers(ctx, repo, destRepo)
+
+	fakeRepo, _ := dest.Repositories.Load("r")
+	want := []*provider.GitMember{{User: provider.GitUser{Login: "jane-gh"}, Permission: provider.PermissionWrite, Group: "g"}}
+	if got := fakeRepo.(*provider.FakeRepository).Members; !reflect.DeepEqual(got, want) {
+		t.Errorf("granted members = %+v, want %+v", got, want)
+	}
+	if m.Report.Failed() {
+		t.Errorf("processMembers reported failures")
+	}
+}
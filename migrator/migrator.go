@@ -23,67 +23,208 @@
 package migrator
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/artur-sak13/gitmv/filter"
+	"github.com/artur-sak13/gitmv/pool"
 	"github.com/artur-sak13/gitmv/provider"
+	"github.com/artur-sak13/gitmv/state"
 )
 
-// Migrator stores the src and target git providers and an error channel
+// Default parallelism of a Migrator
+const (
+	DefaultRepoWorkers  = 4
+	DefaultIssueWorkers = 4
+)
+
+// ErrIncomplete is returned by Run when some entities failed to migrate, the Migrator's Report lists them
+var ErrIncomplete = errors.New("migration completed with errors")
+
+// Scope selects the entities a Migrator creates, e.g. the steps of a reviewed plan
+type Scope interface {
+	// Includes reports whether an entity missing from the state journal should be migrated
+	Includes(repo string, kind state.Kind, id string) bool
+}
+
+// Migrator stores the src and target git providers and a report of the migration's outcome
 type Migrator struct {
 	Src    provider.GitProvider
 	Dest   provider.GitProvider
-	Errors chan error
+	Report *Report
+
+	// FailFast stops scheduling new work after the first failure instead of continuing past it.
+	// Work already in progress finishes.
+	FailFast bool
+
+	// PreserveIssueNumbers creates issues in ascending order and fills gaps with
+	// placeholders so that source issue #N becomes destination issue #N.
+	// Pull requests share the destination's numbering and are created after all issues.
+	PreserveIssueNumbers bool
+
+	// RepoWorkers bounds the repositories migrated at once and IssueWorkers
+	// the labels and issue comments migrated at once within each repository.
+	// Issues themselves are always created in order.
+	RepoWorkers  int
+	IssueWorkers int
+
+	// SourceKind is the registry kind of Src, it identifies the source in the markers of migrated issues and comments
+	SourceKind string
+
+	// State journals every migrated entity so that an interrupted run can resume where it stopped.
+	// Resuming is disabled when it is nil.
+	State *state.Journal
+
+	// Scope limits the entities that are migrated, everything is migrated when it is nil
+	Scope Scope
+
+	// Filter selects the source repositories to migrate, all but forks and empty repositories when it is nil
+	Filter *filter.Filter
+
+	// Members grants the members of each source repository access to its destination.
+	// Users maps source usernames to destination logins, members missing from it are skipped.
+	Members bool
+	Users   map[string]string
 }
 
 // NewMigrator creates a new git migrator
 func NewMigrator(src, dest provider.GitProvider) *Migrator {
 	return &Migrator{
-		Src:    src,
-		Dest:   dest,
-		Errors: make(chan error),
+		Src:          src,
+		Dest:         dest,
+		Report:       NewReport(),
+		RepoWorkers:  DefaultRepoWorkers,
+		IssueWorkers: DefaultIssueWorkers,
 	}
 }
 
-// Run processes git import jobs
-func (m *Migrator) Run() error {
-	repos, err := m.Src.GetRepositories()
+// Run processes git import jobs until they finish or ctx is cancelled
+func (m *Migrator) Run(ctx context.Context) error {
+	repos, err := m.Src.GetRepositories(ctx)
 	if err != nil {
 		return fmt.Errorf("error getting repos: %v", err)
 	}
+	repos = m.Filter.Select(repos)
 
 	start := time.Now()
-	wg := sync.WaitGroup{}
+	repoPool := pool.New(m.RepoWorkers)
 	importwg := sync.WaitGroup{}
 	count := 0
 
 	for _, repo := range repos {
-		if repo.Fork || repo.Empty {
-			continue
+		if m.stopping(ctx) {
+			break
 		}
-		wg.Add(1)
-		importwg.Add(1)
 		count++
 
-		destRepo, err := m.Dest.CreateRepository(repo)
+		destRepo, ok := m.startRepo(ctx, repo, &importwg)
+		if !ok {
+			continue
+		}
+
+		// blocks while RepoWorkers repositories are in progress
+		repo := repo
+		repoPool.Go(func() {
+			m.processRepo(ctx, repo, destRepo)
+		})
+
+	}
+	repoPool.Wait()
+	logrus.Infof("processed %d repositories in %s\n", count, time.Since(start))
+
+	importwg.Wait()
+	logrus.Infof("done waiting for repository imports")
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if m.Report.Failed() {
+		return ErrIncomplete
+	}
+	return nil
+}
+
+// RunIssues migrates the labels, issues and comments of source repositories that already exist in the destination
+func (m *Migrator) RunIssues(ctx context.Context) error {
+	repos, err := m.Src.GetRepositories(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting repos: %v", err)
+	}
+	repos = m.Filter.Select(repos)
+	destRepos, err := m.Dest.GetRepositories(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting destination repos: %v", err)
+	}
+	existing := make(map[string]*provider.GitRepository, len(destRepos))
+	for _, repo := range destRepos {
+		existing[repo.Name] = repo
+	}
+
+	repoPool := pool.New(m.RepoWorkers)
+	for _, repo := range repos {
+		if m.stopping(ctx) {
+			break
+		}
+		destRepo, ok := existing[repo.Name]
+		if !ok {
+			m.fail(repo.Name, state.KindRepo, "", errors.New("missing from the destination, migrate the repository first"))
+			continue
+		}
+
+		// blocks while RepoWorkers repositories are in progress
+		repo := repo
+		repoPool.Go(func() {
+			m.processLabels(ctx, repo, destRepo)
+			m.processIssues(ctx, repo, destRepo)
+		})
+	}
+	repoPool.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if m.Report.Failed() {
+		return ErrIncomplete
+	}
+	return nil
+}
+
+// startRepo creates the destination of a repository and starts importing it unless earlier runs did
+// It reports false when the repository failed or is out of scope.
+func (m *Migrator) startRepo(ctx context.Context, repo *provider.GitRepository, importwg *sync.WaitGroup) (*provider.GitRepository, bool) {
+	var destRepo *provider.GitRepository
+	if !m.State.Lookup(repo.Name, state.KindRepo, repo.Name, &destRepo) {
+		if !m.includes(repo.Name, state.KindRepo, repo.Name) {
+			return nil, false
+		}
+		var err error
+		destRepo, err = m.Dest.CreateRepository(ctx, repo)
 		if err != nil {
-			return fmt.Errorf("error creating repository: %v", err)
+			m.fail(repo.Name, state.KindRepo, "", err)
+			return nil, false
 		}
+		m.record(repo.Name, state.KindRepo, repo.Name, destRepo)
 
 		logrus.WithFields(logrus.Fields{
 			"repo": destRepo.Name,
 			"url":  destRepo.CloneURL,
 		}).Infof("creating new repo")
+	}
 
-		status, err := m.Dest.GetImportProgress(repo.Name)
+	if !m.skipped(repo.Name, state.KindImport, repo.Name) {
+		status, err := m.Dest.GetImportProgress(ctx, repo.Name)
 		if err != nil {
-			status, err = m.Dest.MigrateRepo(repo, m.Src.GetAuth().Token)
+			status, err = provider.MigrateRepo(ctx, m.Src, m.Dest, repo, destRepo)
 			if err != nil {
-				return fmt.Errorf("error failed to migrate repository: %v", err)
+				m.fail(repo.Name, state.KindImport, "", err)
+				return nil, false
 			}
 		}
 
@@ -92,159 +233,411 @@ func (m *Migrator) Run() error {
 			"status": status,
 		}).Infof("importing repo")
 
-		go m.waitForImport(repo.Name, &importwg)
-
-		go func(repo *provider.GitRepository) {
-			m.processLabels(repo)
-			m.processIssues(repo)
-			if err := provider.MigrateWiki(destRepo, m.Dest.GetAuth()); err != nil {
-				m.Errors <- fmt.Errorf("failed to migrate wiki for %s: %v", repo.Name, err)
-			}
-			wg.Done()
-		}(repo)
+		if status == provider.ImportComplete {
+			m.record(repo.Name, state.KindImport, repo.Name, status)
+		} else {
+			importwg.Add(1)
+			go m.waitForImport(ctx, repo.Name, importwg)
+		}
+	}
+	return destRepo, true
+}
 
+// processRepo migrates the labels, issues, pull requests, wiki and members of a repository
+func (m *Migrator) processRepo(ctx context.Context, repo, destRepo *provider.GitRepository) {
+	m.processLabels(ctx, repo, destRepo)
+	m.processIssues(ctx, repo, destRepo)
+	m.processPullRequests(ctx, repo)
+	m.processWiki(ctx, repo, destRepo)
+	if m.Members {
+		m.processMembers(ctx, repo, destRepo)
 	}
-	wg.Wait()
-	logrus.Infof("processed %d repositories in %s\n", count, time.Since(start))
+}
 
-	importwg.Wait()
-	logrus.Infof("done waiting for repository imports")
+// record journals and reports a migrated entity, a journal failure only means the entity is migrated again on resume
+func (m *Migrator) record(repo string, kind state.Kind, id string, dest interface{}) {
+	m.Report.Succeed(repo, kind)
+	if err := m.State.Record(repo, kind, id, dest); err != nil {
+		logrus.Warnf("error recording migration state: %v", err)
+	}
+}
 
-	if len(m.Errors) > 0 {
-		for err := range m.Errors {
-			logrus.Error(err)
-		}
-		return fmt.Errorf("errors occured during migration")
+// adopt records an entity that already existed in the destination, which rollback leaves in place
+func (m *Migrator) adopt(repo string, kind state.Kind, id string, dest interface{}) {
+	m.Report.Succeed(repo, kind)
+	if err := m.State.Adopt(repo, kind, id, dest); err != nil {
+		logrus.Warnf("error recording migration state: %v", err)
 	}
+}
 
-	return nil
+// includes checks the Scope for an entity that is not journaled yet
+func (m *Migrator) includes(repo string, kind state.Kind, id string) bool {
+	return m.Scope == nil || m.Scope.Includes(repo, kind, id)
+}
+
+// skipped reports whether an entity was migrated by an earlier run or is out of scope
+func (m *Migrator) skipped(repo string, kind state.Kind, id string) bool {
+	return m.State.Lookup(repo, kind, id, nil) || !m.includes(repo, kind, id)
+}
+
+// fail reports an entity that could not be migrated
+func (m *Migrator) fail(repo string, kind state.Kind, id string, err error) {
+	logrus.Error(m.Report.Fail(repo, kind, id, err))
 }
 
-func (m *Migrator) waitForImport(repo string, wg *sync.WaitGroup) {
+// stopping reports whether cancellation or a failure should stop new work from starting
+func (m *Migrator) stopping(ctx context.Context) bool {
+	return ctx.Err() != nil || (m.FailFast && m.Report.Failed())
+}
+
+func (m *Migrator) processWiki(ctx context.Context, repo, destRepo *provider.GitRepository) {
+	if m.skipped(repo.Name, state.KindWiki, repo.Name) {
+		return
+	}
+	if err := provider.MigrateWiki(ctx, repo, destRepo); err != nil {
+		m.fail(repo.Name, state.KindWiki, "", err)
+		return
+	}
+	m.record(repo.Name, state.KindWiki, repo.Name, destRepo.Name)
+}
+
+func (m *Migrator) waitForImport(ctx context.Context, repo string, wg *sync.WaitGroup) {
 	retries := 5
 	for retryCount := 1; retryCount <= retries; retryCount++ {
-		status, err := m.Dest.GetImportProgress(repo)
+		status, err := m.Dest.GetImportProgress(ctx, repo)
 		if err != nil {
-			m.Errors <- fmt.Errorf("failed to retrieve import progress: %v", err)
+			logrus.Warnf("failed to retrieve import progress for %s: %v", repo, err)
 		}
-		if status == "complete" {
+		if status == provider.ImportComplete {
 			logrus.Infof("%s finished importing", repo)
+			m.record(repo, state.KindImport, repo, status)
+			wg.Done()
+			return
+		}
+		if err := sleepForAttempt(ctx, retryCount); err != nil {
 			wg.Done()
 			return
 		}
-		sleepForAttempt(retryCount)
 	}
-	logrus.Warnf("import status retries exhausted for %s", repo)
+	m.fail(repo, state.KindImport, "", fmt.Errorf("import did not complete after %d status checks", retries))
 	wg.Done()
 }
 
-func sleepForAttempt(retryCount int) {
+// sleepForAttempt waits out the backoff of a retry, returning early with an error if ctx is cancelled
+func sleepForAttempt(ctx context.Context, retryCount int) error {
 	maxDelay := 20 * time.Second
 	delay := time.Second * time.Duration(math.Exp2(float64(retryCount)))
 	if delay > maxDelay {
 		delay = maxDelay
 	}
-	time.Sleep(delay)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
-func (m *Migrator) processIssues(repo *provider.GitRepository) {
-	issues, err := m.Src.GetIssues(repo.PID, repo.Name)
+func (m *Migrator) processIssues(ctx context.Context, repo, destRepo *provider.GitRepository) {
+	issues, err := m.Src.GetIssues(ctx, repo.PID, repo.Name)
 	if err != nil {
-		logrus.Errorf("error getting issues: %v", err)
-		m.Errors <- fmt.Errorf("failed to retrieve issues: %v", err)
+		m.fail(repo.Name, state.KindIssue, "", fmt.Errorf("failed to retrieve issues: %v", err))
 		return
 	}
 	if issues == nil || len(issues) == 0 {
 		return
 	}
-	var wg sync.WaitGroup
-	wg.Add(len(issues))
 
-	go func() {
-		for _, issue := range issues {
+	// comments are copied while later issues are created
+	commentPool := pool.New(m.IssueWorkers)
+	defer commentPool.Wait()
 
-			logrus.WithFields(logrus.Fields{
-				"IID":   issue.Number,
-				"issue": issue.Title,
-				"state": issue.State,
-			}).Info("creating issue")
+	if m.PreserveIssueNumbers {
+		m.processIssuesInOrder(ctx, repo, destRepo, issues, commentPool)
+		return
+	}
 
-			_, err := m.Dest.CreateIssue(issue)
-			if err != nil {
-				logrus.Errorf("error creating issue: %v", err)
-				m.Errors <- fmt.Errorf("failed to create issue: %v", err)
-				wg.Done()
+	for _, issue := range issues {
+		if m.stopping(ctx) {
+			return
+		}
+
+		if !m.inScope(issue) {
+			continue
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"IID":   issue.Number,
+			"issue": issue.Title,
+			"state": issue.State,
+		}).Info("creating issue")
+
+		number, err := m.createIssue(ctx, provider.MarkIssue(m.SourceKind, issue))
+		if err != nil {
+			m.fail(issue.Repo, state.KindIssue, state.NumberID(issue.Number), err)
+			continue
+		}
+		issue := issue
+		commentPool.Go(func() {
+			m.processComments(ctx, issue, number)
+		})
+	}
+}
+
+// processIssuesInOrder creates issues by ascending number, filling gaps with closed placeholder issues
+// It stops at the first issue or placeholder that fails, is out of scope or whose destination number differs.
+func (m *Migrator) processIssuesInOrder(ctx context.Context, repo, destRepo *provider.GitRepository, issues []*provider.GitIssue, commentPool *pool.Pool) {
+	sort.Slice(issues, func(i, j int) bool {
+		return issues[i].Number < issues[j].Number
+	})
+
+	if len(provider.PlaceholderNumbers(issues)) > 0 {
+		m.createLabel(ctx, provider.NewPlaceholderLabel(repo.Name), m.labelLister(ctx, destRepo))
+	}
+
+	next := 1
+	for _, issue := range issues {
+		if m.stopping(ctx) {
+			return
+		}
+		if issue.Number < next {
+			continue
+		}
+		// an issue left out of scope would shift the numbers of every later one
+		if !m.inScope(issue) {
+			m.fail(repo.Name, state.KindIssue, state.NumberID(issue.Number),
+				fmt.Errorf("issue is out of scope, it and every later issue were not migrated to preserve numbers"))
+			return
+		}
+		for ; next < issue.Number; next++ {
+			placeholder := provider.NewPlaceholderIssue(repo, next)
+			if !m.inScope(placeholder) {
+				m.fail(repo.Name, state.KindIssue, state.NumberID(next),
+					fmt.Errorf("placeholder is out of scope, it and every later issue were not migrated to preserve numbers"))
 				return
 			}
-			m.processComments(issue, &wg)
+			if err := m.createNumberedIssue(ctx, placeholder, next); err != nil {
+				m.fail(repo.Name, state.KindIssue, state.NumberID(next), err)
+				return
+			}
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"IID":   issue.Number,
+			"issue": issue.Title,
+			"state": issue.State,
+		}).Info("creating issue")
+
+		if err := m.createNumberedIssue(ctx, provider.MarkIssue(m.SourceKind, issue), issue.Number); err != nil {
+			m.fail(repo.Name, state.KindIssue, state.NumberID(issue.Number), err)
+			return
 		}
-	}()
-	wg.Wait()
+		next++
+
+		issue := issue
+		commentPool.Go(func() {
+			m.processComments(ctx, issue, issue.Number)
+		})
+	}
+}
+
+// inScope checks if a source issue was migrated by an earlier run or is in scope
+func (m *Migrator) inScope(issue *provider.GitIssue) bool {
+	id := state.NumberID(issue.Number)
+	return m.State.Lookup(issue.Repo, state.KindIssue, id, nil) || m.includes(issue.Repo, state.KindIssue, id)
+}
+
+// createIssue creates an issue unless an earlier run did, and returns its destination number
+func (m *Migrator) createIssue(ctx context.Context, issue *provider.GitIssue) (int, error) {
+	var number int
+	if m.State.Lookup(issue.Repo, state.KindIssue, state.NumberID(issue.Number), &number) {
+		return number, nil
+	}
+	created, err := m.Dest.CreateIssue(ctx, issue)
+	if err != nil {
+		return 0, err
+	}
+	m.record(issue.Repo, state.KindIssue, state.NumberID(issue.Number), created.Number)
+	return created.Number, nil
 }
 
-func (m *Migrator) processComments(issue *provider.GitIssue, wg *sync.WaitGroup) {
-	comments, err := m.Src.GetComments(issue.PID, issue.Number, issue.Repo)
+func (m *Migrator) createNumberedIssue(ctx context.Context, issue *provider.GitIssue, number int) error {
+	created, err := m.createIssue(ctx, issue)
 	if err != nil {
-		logrus.Errorf("error getting comments: %v", err)
-		m.Errors <- fmt.Errorf("failed to retrieve project comments: %v", err)
-		wg.Done()
+		return err
+	}
+	if created != number {
+		return fmt.Errorf("created as #%d, the destination must not contain issues or pull requests to preserve numbers", created)
+	}
+	return nil
+}
+
+// processComments copies the comments of a source issue onto the destination issue with the given number
+func (m *Migrator) processComments(ctx context.Context, issue *provider.GitIssue, number int) {
+	comments, err := m.Src.GetComments(ctx, issue.PID, issue.Number, issue.Repo)
+	if err != nil {
+		m.fail(issue.Repo, state.KindComment, state.NumberID(issue.Number), fmt.Errorf("failed to retrieve comments: %v", err))
 		return
 	}
-	if comments == nil || len(comments) == 0 {
-		wg.Done()
+	for _, comment := range comments {
+		if m.stopping(ctx) {
+			return
+		}
+		id := state.CommentID(issue.Number, comment.ID)
+		if m.skipped(issue.Repo, state.KindComment, id) {
+			continue
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"repo":    comment.Repo,
+			"comment": comment.Body,
+		}).Info("creating comment")
+
+		err := m.Dest.CreateIssueComment(ctx, number, provider.MarkComment(m.SourceKind, issue, comment))
+		if err != nil {
+			m.fail(issue.Repo, state.KindComment, id, err)
+			continue
+		}
+		m.record(issue.Repo, state.KindComment, id, number)
+	}
+}
+
+// processPullRequests recreates the source's pull requests in the destination, which may fall back to issues
+func (m *Migrator) processPullRequests(ctx context.Context, repo *provider.GitRepository) {
+	prs, err := m.Src.GetPullRequests(ctx, repo.PID, repo.Name)
+	if err != nil {
+		m.fail(repo.Name, state.KindPullRequest, "", fmt.Errorf("failed to retrieve pull requests: %v", err))
 		return
 	}
-	go func() {
-		for _, comment := range comments {
 
+	for _, pr := range prs {
+		if m.stopping(ctx) {
+			return
+		}
+		var created *provider.GitPullRequest
+		if !m.State.Lookup(pr.Repo, state.KindPullRequest, state.NumberID(pr.Number), &created) {
+			if !m.includes(pr.Repo, state.KindPullRequest, state.NumberID(pr.Number)) {
+				continue
+			}
 			logrus.WithFields(logrus.Fields{
-				"repo":    comment.Repo,
-				"comment": comment.Body,
-			}).Info("creating comment")
+				"IID":   pr.Number,
+				"pr":    pr.Title,
+				"state": pr.State,
+			}).Info("creating pull request")
 
-			err := m.Dest.CreateIssueComment(issue.Number, comment)
+			created, err = m.Dest.CreatePullRequest(ctx, pr)
 			if err != nil {
-				logrus.Errorf("error creating comments for repo %s: %v", issue.Repo, err)
-				m.Errors <- fmt.Errorf("failed to create comment: %v", err)
-				wg.Done()
-				return
+				m.fail(pr.Repo, state.KindPullRequest, state.NumberID(pr.Number), err)
+				continue
 			}
+			m.record(pr.Repo, state.KindPullRequest, state.NumberID(pr.Number), created)
+		}
+		m.processReviewComments(ctx, pr, created)
+	}
+}
+
+func (m *Migrator) processReviewComments(ctx context.Context, pr, created *provider.GitPullRequest) {
+	comments, err := m.Src.GetReviewComments(ctx, pr.PID, pr.Number, pr.Repo)
+	if err != nil {
+		m.fail(pr.Repo, state.KindReviewComment, state.NumberID(pr.Number), fmt.Errorf("failed to retrieve pull request comments: %v", err))
+		return
+	}
+
+	for _, comment := range comments {
+		if m.stopping(ctx) {
+			return
+		}
+		id := state.CommentID(pr.Number, comment.ID)
+		if m.skipped(pr.Repo, state.KindReviewComment, id) {
+			continue
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"repo":    comment.Repo,
+			"comment": comment.Body,
+			"path":    comment.Path,
+		}).Info("creating pull request comment")
+
+		if err := m.Dest.CreateReviewComment(ctx, created, comment); err != nil {
+			m.fail(pr.Repo, state.KindReviewComment, id, err)
+			continue
 		}
-		wg.Done()
-	}()
+		m.record(pr.Repo, state.KindReviewComment, id, created.Number)
+	}
 }
 
-func (m *Migrator) processLabels(repo *provider.GitRepository) {
-	labels, err := m.Src.GetLabels(repo.PID, repo.Name)
+// processLabels creates the source's labels in the destination and adopts the ones that already exist there
+func (m *Migrator) processLabels(ctx context.Context, repo, destRepo *provider.GitRepository) {
+	labels, err := m.Src.GetLabels(ctx, repo.PID, repo.Name)
 	if err != nil {
-		logrus.Errorf("error getting labels: %v", err)
-		m.Errors <- fmt.Errorf("failed to retrieve labels: %v", err)
+		m.fail(repo.Name, state.KindLabel, "", fmt.Errorf("failed to retrieve labels: %v", err))
 		return
 	}
 	if labels == nil || len(labels) == 0 {
 		return
 	}
-	wg := &sync.WaitGroup{}
-	wg.Add(len(labels))
+	labelPool := pool.New(m.IssueWorkers)
+	exists := m.labelLister(ctx, destRepo)
 
 	for _, label := range labels {
-		go func(label *provider.GitLabel) {
+		if m.stopping(ctx) {
+			break
+		}
+		label := label
+		labelPool.Go(func() {
+			m.createLabel(ctx, label, exists)
+		})
+	}
+	labelPool.Wait()
+}
 
-			logrus.WithFields(logrus.Fields{
-				"repo":  label.Repo,
-				"label": label.Name,
-				"color": label.Color,
-			}).Info("creating label")
+// createLabel creates a label unless an earlier run did, adopting it when exists finds it in the destination
+func (m *Migrator) createLabel(ctx context.Context, label *provider.GitLabel, exists func(name string) (bool, error)) {
+	if m.skipped(label.Repo, state.KindLabel, label.Name) {
+		return
+	}
 
-			_, err := m.Dest.CreateLabel(label)
-			if err != nil {
-				logrus.Errorf("error creating label: %v", err)
-				m.Errors <- fmt.Errorf("failed to create label: %v", err)
-				wg.Done()
-				return
+	logrus.WithFields(logrus.Fields{
+		"repo":  label.Repo,
+		"label": label.Name,
+		"color": label.Color,
+	}).Info("creating label")
+
+	_, err := m.Dest.CreateLabel(ctx, label)
+	if err != nil {
+		if ok, listErr := exists(label.Name); listErr != nil || !ok {
+			m.fail(label.Repo, state.KindLabel, label.Name, err)
+			return
+		}
+		logrus.WithFields(logrus.Fields{
+			"repo":  label.Repo,
+			"label": label.Name,
+		}).Info("adopting existing label")
+		m.adopt(label.Repo, state.KindLabel, label.Name, label.Name)
+		return
+	}
+	m.record(label.Repo, state.KindLabel, label.Name, label.Name)
+}
+
+// labelLister returns a check for labels of a destination repository, which lists them on its first call
+func (m *Migrator) labelLister(ctx context.Context, destRepo *provider.GitRepository) func(name string) (bool, error) {
+	var (
+		once     sync.Once
+		existing map[string]bool
+		listErr  error
+	)
+	return func(name string) (bool, error) {
+		once.Do(func() {
+			var labels []*provider.GitLabel
+			labels, listErr = m.Dest.GetLabels(ctx, destRepo.PID, destRepo.Name)
+			existing = make(map[string]bool, len(labels))
+			for _, label := range labels {
+				existing[label.Name] = true
 			}
-			wg.Done()
-		}(label)
+		})
+		return existing[name], listErr
 	}
-	wg.Wait()
 }
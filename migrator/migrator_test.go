@@ -0,0 +1,259 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/artur-sak13/gitmv/provider"
+	"github.com/artur-sak13/gitmv/state"
+)
+
+// numberingDest numbers issues sequentially after the existing ones, like GitHub and GitLab do
+type numberingDest struct {
+	*provider.FakeProvider
+	existing int
+	created  []*provider.GitIssue
+	labels   []string
+}
+
+func (d *numberingDest) CreateIssue(ctx context.Context, issue *provider.GitIssue) (*provider.GitIssue, error) {
+	d.created = append(d.created, issue)
+	created := *issue
+	created.Number = d.existing + len(d.created)
+	return &created, nil
+}
+
+func (d *numberingDest) CreateLabel(ctx context.Context, label *provider.GitLabel) (*provider.GitLabel, error) {
+	d.labels = append(d.labels, label.Name)
+	return label, nil
+}
+
+// issueScope includes every entity but the listed issues
+type issueScope map[string]bool
+
+func (s issueScope) Includes(repo string, kind state.Kind, id string) bool {
+	return kind != state.KindIssue || !s[id]
+}
+
+func preservingMigrator(dest *numberingDest) *Migrator {
+	src := &syncSource{
+		issues: []*provider.GitIssue{
+			{Repo: "r", PID: 1, Number: 4, Title: "four", State: "opened"},
+			{Repo: "r", PID: 1, Number: 2, Title: "two", State: "closed"},
+		},
+	}
+	m := NewMigrator(src, dest)
+	m.PreserveIssueNumbers = true
+	return m
+}
+
+// createdTitles lists the titles of the issues a destination created in order
+func createdTitles(dest *numberingDest) []string {
+	var titles []string
+	for _, issue := range dest.created {
+		titles = append(titles, issue.Title)
+	}
+	return titles
+}
+
+func TestProcessIssues_PreserveNumbers(t *testing.T) {
+	dest := &numberingDest{FakeProvider: provider.NewFakeProvider().(*provider.FakeProvider)}
+	m := preservingMigrator(dest)
+	m.State = state.New()
+	repo := &provider.GitRepository{Name: "r", PID: 1}
+	m.processIssues(context.Background(), repo, repo)
+
+	if m.Report.Failed() {
+		t.Fatalf("processIssues reported failures: %v", m.Report.Errors())
+	}
+	want := []string{"Placeholder for #1", "two", "Placeholder for #3", "four"}
+	if got := createdTitles(dest); !reflect.DeepEqual(got, want) {
+		t.Errorf("created issues = %v, want %v", got, want)
+	}
+	for _, i := range []int{0, 2} {
+		placeholder := dest.created[i]
+		if placeholder.State != "closed" || !reflect.DeepEqual(placeholder.Labels, []provider.GitLabel{{Name: provider.PlaceholderLabel}}) {
+			t.Errorf("placeholder = %+v, want a closed issue labeled %s", placeholder, provider.PlaceholderLabel)
+		}
+	}
+	if want := []string{provider.PlaceholderLabel}; !reflect.DeepEqual(dest.labels, want) {
+		t.Errorf("created labels = %v, want %v", dest.labels, want)
+	}
+	// rollback removes the placeholders and their label like any created entity
+	for _, w := range []struct {
+		kind state.Kind
+		id   string
+	}{{state.KindLabel, provider.PlaceholderLabel}, {state.KindIssue, "1"}, {state.KindIssue, "3"}} {
+		if !m.State.Lookup("r", w.kind, w.id, nil) {
+			t.Errorf("processIssues did not journal %s %s", w.kind, w.id)
+		}
+	}
+}
+
+func TestProcessIssues_PreserveNumbersMismatch(t *testing.T) {
+	dest := &numberingDest{FakeProvider: provider.NewFakeProvider().(*provider.FakeProvider), existing: 1}
+	m := preservingMigrator(dest)
+	repo := &provider.GitRepository{Name: "r", PID: 1}
+	m.processIssues(context.Background(), repo, repo)
+
+	if want := []string{"Placeholder for #1"}; !reflect.DeepEqual(createdTitles(dest), want) {
+		t.Errorf("created issues = %v, want %v", createdTitles(dest), want)
+	}
+	errs := m.Report.Errors()
+	if len(errs) != 1 || errs[0].Entity != state.KindIssue || errs[0].ID != "1" {
+		t.Errorf("Report errors = %v, want a single failure of issue 1", errs)
+	}
+}
+
+func TestProcessIssues_PreserveNumbersPlaceholderOutOfScope(t *testing.T) {
+	dest := &numberingDest{FakeProvider: provider.NewFakeProvider().(*provider.FakeProvider)}
+	m := preservingMigrator(dest)
+	m.Scope = issueScope{"1": true}
+	repo := &provider.GitRepository{Name: "r", PID: 1}
+	m.processIssues(context.Background(), repo, repo)
+
+	if len(dest.created) != 0 {
+		t.Errorf("created issues = %v, want none", createdTitles(dest))
+	}
+	errs := m.Report.Errors()
+	if len(errs) != 1 || errs[0].Entity != state.KindIssue || errs[0].ID != "1" {
+		t.Errorf("Report errors = %v, want a single failure of placeholder 1", errs)
+	}
+}
+
+func TestProcessIssues_PreserveNumbersOutOfScope(t *testing.T) {
+	dest := &numberingDest{FakeProvider: provider.NewFakeProvider().(*provider.FakeProvider)}
+	m := preservingMigrator(dest)
+	m.Scope = issueScope{"2": true}
+	repo := &provider.GitRepository{Name: "r", PID: 1}
+	m.processIssues(context.Background(), repo, repo)
+
+	if len(dest.created) != 0 {
+		t.Errorf("created issues = %v, want none", createdTitles(dest))
+	}
+	errs := m.Report.Errors()
+	if len(errs) != 1 || errs[0].Entity != state.KindIssue || errs[0].ID != "2" {
+		t.Errorf("Report errors = %v, want a single failure of issue 2", errs)
+	}
+}
+
+// listingDest lists the repositories of a fake destination
+type listingDest struct {
+	*provider.FakeProvider
+}
+
+func (d *listingDest) GetRepositories(ctx context.Context) ([]*provider.GitRepository, error) {
+	var repos []*provider.GitRepository
+	d.Repositories.Range(func(name, repo interface{}) bool {
+		repos = append(repos, repo.(*provider.FakeRepository).GitRepo)
+		return true
+	})
+	return repos, nil
+}
+
+func TestRunIssues(t *testing.T) {
+	ctx := context.Background()
+	src := &syncSource{issues: []*provider.GitIssue{{Repo: "r", PID: 1, Number: 1, Title: "t", State: "opened"}}}
+	dest := &listingDest{FakeProvider: provider.NewFakeProvider().(*provider.FakeProvider)}
+
+	m := NewMigrator(src, dest)
+	if err := m.RunIssues(ctx); err != ErrIncomplete {
+		t.Errorf("RunIssues returned %v for a repository missing from the destination, want %v", err, ErrIncomplete)
+	}
+
+	if _, err := dest.CreateRepository(ctx, &provider.GitRepository{Name: "r"}); err != nil {
+		t.Fatalf("CreateRepository returned error: %v", err)
+	}
+	m = NewMigrator(src, dest)
+	if err := m.RunIssues(ctx); err != nil {
+		t.Fatalf("RunIssues returned error: %v", err)
+	}
+	repo, _ := dest.Repositories.Load("r")
+	if labels := repo.(*provider.FakeRepository).Labels; len(labels) != 1 || labels[0].Name != "bug" {
+		t.Errorf("RunIssues created labels %+v, want bug", labels)
+	}
+	if _, ok := repo.(*provider.FakeRepository).Issues.Load(1); !ok {
+		t.Errorf("RunIssues did not create issue 1")
+	}
+}
+
+func TestProcessComments_SameCreatedAt(t *testing.T) {
+	ctx := context.Background()
+	created := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	issue := &provider.GitIssue{Repo: "r", PID: 1, Number: 1, Title: "t", State: "opened"}
+	src := &syncSource{
+		comments: map[int][]*provider.GitIssueComment{
+			1: {
+				{ID: 1, Repo: "r", IssueNum: 1, Body: "first", CreatedAt: created},
+				{ID: 2, Repo: "r", IssueNum: 1, Body: "second", CreatedAt: created},
+			},
+		},
+	}
+	dest := provider.NewFakeProvider().(*provider.FakeProvider)
+	if _, err := dest.CreateRepository(ctx, &provider.GitRepository{Name: "r"}); err != nil {
+		t.Fatalf("CreateRepository returned error: %v", err)
+	}
+	if _, err := dest.CreateIssue(ctx, issue); err != nil {
+		t.Fatalf("CreateIssue returned error: %v", err)
+	}
+
+	m := NewMigrator(src, dest)
+	m.State = state.New()
+	m.processComments(ctx, issue, 1)
+	// a second pass finds both comments in the journal
+	m.processComments(ctx, issue, 1)
+
+	repo, _ := dest.Repositories.Load("r")
+	fake, _ := repo.(*provider.FakeRepository).Issues.Load(1)
+	var bodies []string
+	for _, comment := range fake.(*provider.FakeIssue).Comments {
+		bodies = append(bodies, comment.Body)
+	}
+	if want := 2; len(bodies) != want {
+		t.Errorf("processComments created comments %q, want %d", bodies, want)
+	}
+}
+
+// labelDest refuses to create the labels it already has, like GitHub does for its default labels
+type labelDest struct {
+	*provider.FakeProvider
+	labels []*provider.GitLabel
+}
+
+func (d *labelDest) CreateLabel(ctx context.Context, label *provider.GitLabel) (*provider.GitLabel, error) {
+	for _, existing := range d.labels {
+		if existing.Name == label.Name {
+			return nil, fmt.Errorf("label %s already exists", label.Name)
+		}
+	}
+	d.labels = append(d.labels, label)
+	return label, nil
+}
+
+func (d *labelDest) GetLabels(ctx context.Context, pid int, repo string) ([]*provider.GitLabel, error) {
+	return d.labels, nil
+}
+
+func TestProcessLabels_Existing(t *testing.T) {
+	ctx := context.Background()
+	repo := &provider.GitRepository{Name: "r", PID: 1}
+	dest := &labelDest{
+		FakeProvider: provider.NewFakeProvider().(*provider.FakeProvider),
+		labels:       []*provider.GitLabel{{Repo: "r", Name: "bug"}},
+	}
+
+	m := NewMigrator(&syncSource{}, dest)
+	m.State = state.New()
+	m.processLabels(ctx, repo, repo)
+
+	if m.Report.Failed() {
+		t.Errorf("processLabels failed for an existing label: %v", m.Report.Errors())
+	}
+	entries := m.State.Entries()
+	if len(entries) != 1 || entries[0].ID != "bug" || !entries[0].Existing {
+		t.Errorf("processLabels journaled %+v, want the existing label adopted", entries)
+	}
+}
@@ -0,0 +1,158 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 Artur Sak
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package migrator
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/artur-sak13/gitmv/state"
+)
+
+// Error is a failure to migrate one entity of a repository
+type Error struct {
+	Repo   string
+	Entity state.Kind
+	// ID identifies the entity within its repository, e.g. an issue number or label name
+	ID  string
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.ID == "" {
+		return fmt.Sprintf("%s %s: %v", e.Repo, e.Entity, e.Err)
+	}
+	return fmt.Sprintf("%s %s %s: %v", e.Repo, e.Entity, e.ID, e.Err)
+}
+
+// Report collects the outcome of a migration per repository and entity
+// It is safe for concurrent use and never blocks the workers reporting to it.
+type Report struct {
+	mu       sync.Mutex
+	errors   []*Error
+	migrated map[reportKey]int
+	failed   map[reportKey]int
+}
+
+type reportKey struct {
+	repo   string
+	entity state.Kind
+}
+
+// NewReport creates an empty migration report
+func NewReport() *Report {
+	return &Report{
+		migrated: make(map[reportKey]int),
+		failed:   make(map[reportKey]int),
+	}
+}
+
+// Fail records an entity that could not be migrated
+func (r *Report) Fail(repo string, entity state.Kind, id string, err error) *Error {
+	e := &Error{Repo: repo, Entity: entity, ID: id, Err: err}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errors = append(r.errors, e)
+	r.failed[reportKey{repo, entity}]++
+	return e
+}
+
+// Succeed records a migrated entity
+func (r *Report) Succeed(repo string, entity state.Kind) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.migrated[reportKey{repo, entity}]++
+}
+
+// Errors returns the recorded failures in the order they occurred
+func (r *Report) Errors() []*Error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*Error(nil), r.errors...)
+}
+
+// Failed reports whether any entity failed to migrate
+func (r *Report) Failed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.errors) > 0
+}
+
+// RepoFailed reports whether any entity of a repository failed to migrate
+func (r *Report) RepoFailed(repo string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key := range r.failed {
+		if key.repo == repo {
+			return true
+		}
+	}
+	return false
+}
+
+// Print writes a table of migrated and failed entities per repository followed by every error
+func (r *Report) Print(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := make(map[reportKey]bool)
+	for key := range r.migrated {
+		keys[key] = true
+	}
+	for key := range r.failed {
+		keys[key] = true
+	}
+	sorted := make([]reportKey, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].repo != sorted[j].repo {
+			return sorted[i].repo < sorted[j].repo
+		}
+		return sorted[i].entity < sorted[j].entity
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "REPO\tENTITY\tMIGRATED\tFAILED")
+	for _, key := range sorted {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\n", key.repo, key.entity, r.migrated[key], r.failed[key])
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(r.errors) > 0 {
+		fmt.Fprintf(w, "\n%d errors:\n", len(r.errors))
+	}
+	for _, e := range r.errors {
+		if _, err := fmt.Fprintf(w, "  %v\n", e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
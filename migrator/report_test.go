@@ -0,0 +1,55 @@
+package migrator
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/artur-sak13/gitmv/state"
+)
+
+func TestReport(t *testing.T) {
+	r := NewReport()
+	if r.Failed() {
+		t.Errorf("Failed = true for an empty report")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.Succeed("b", state.KindIssue)
+		}()
+	}
+	wg.Wait()
+	r.Succeed("a", state.KindLabel)
+	r.Fail("b", state.KindComment, "1@2019-01-01T00:00:00Z", errors.New("boom"))
+	r.Fail("a", state.KindWiki, "", errors.New("no wiki"))
+
+	if !r.Failed() {
+		t.Errorf("Failed = false after a failure")
+	}
+	if got := len(r.Errors()); got != 2 {
+		t.Errorf("Errors returned %d errors, want 2", got)
+	}
+
+	var buf bytes.Buffer
+	if err := r.Print(&buf); err != nil {
+		t.Fatalf("Print returned error: %v", err)
+	}
+	want := `REPO  ENTITY   MIGRATED  FAILED
+a     label    1         0
+a     wiki     0         1
+b     comment  0         1
+b     issue    3         0
+
+2 errors:
+  b comment 1@2019-01-01T00:00:00Z: boom
+  a wiki: no wiki
+`
+	if got := buf.String(); got != want {
+		t.Errorf("Print =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,273 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 Artur Sak
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/artur-sak13/gitmv/pool"
+	"github.com/artur-sak13/gitmv/provider"
+	"github.com/artur-sak13/gitmv/state"
+)
+
+// syncOverlap moves marks back so that clock skew between gitmv and the source cannot hide an update
+const syncOverlap = time.Minute
+
+// Sync brings the destination up to date with the changes made in the source since earlier passes
+// Repositories without a mark are migrated in full. The others copy new branches, tags, labels, issues,
+// pull requests and comments, the color and description of changed labels and the title, body, state
+// and labels of every issue updated since their mark. Pull requests that were already migrated are not updated.
+// A mark only moves once all changes of its repository were copied, so the next pass retries failures.
+func (m *Migrator) Sync(ctx context.Context, marks *state.Marks) error {
+	if m.State == nil {
+		return errors.New("sync requires a state journal to find the destination of source issues")
+	}
+
+	repos, err := m.Src.GetRepositories(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting repos: %v", err)
+	}
+	repos = m.Filter.Select(repos)
+
+	repoPool := pool.New(m.RepoWorkers)
+	importwg := sync.WaitGroup{}
+
+	for _, repo := range repos {
+		if m.stopping(ctx) {
+			break
+		}
+		start := time.Now().Add(-syncOverlap)
+
+		since, synced := marks.Get(repo.Name)
+		var destRepo *provider.GitRepository
+		if !synced || !m.State.Lookup(repo.Name, state.KindRepo, repo.Name, &destRepo) ||
+			!m.State.Lookup(repo.Name, state.KindImport, repo.Name, nil) {
+			var ok bool
+			if destRepo, ok = m.startRepo(ctx, repo, &importwg); !ok {
+				continue
+			}
+			synced = false
+		}
+
+		// blocks while RepoWorkers repositories are in progress
+		repo := repo
+		repoPool.Go(func() {
+			if synced {
+				m.syncRepo(ctx, repo, destRepo, since)
+			} else {
+				m.processRepo(ctx, repo, destRepo)
+			}
+			if ctx.Err() != nil || m.Report.RepoFailed(repo.Name) {
+				return
+			}
+			if err := marks.Set(repo.Name, start); err != nil {
+				logrus.Warnf("error recording sync mark: %v", err)
+			}
+		})
+	}
+	repoPool.Wait()
+	importwg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if m.Report.Failed() {
+		return ErrIncomplete
+	}
+	return nil
+}
+
+// SyncRefs copies the branches and tags of a migrated repository that changed in the source, e.g. after a push webhook
+func (m *Migrator) SyncRefs(ctx context.Context, repo *provider.GitRepository) error {
+	var destRepo *provider.GitRepository
+	if !m.State.Lookup(repo.Name, state.KindRepo, repo.Name, &destRepo) {
+		return fmt.Errorf("repository %s has not been migrated yet", repo.Name)
+	}
+	m.syncRefs(ctx, repo, destRepo)
+	return m.synced(ctx, repo)
+}
+
+// SyncIssues copies the issues and comments of a migrated repository that changed in the source at or after changed,
+// e.g. the time of an issue webhook
+func (m *Migrator) SyncIssues(ctx context.Context, repo *provider.GitRepository, changed time.Time) error {
+	var destRepo *provider.GitRepository
+	if !m.State.Lookup(repo.Name, state.KindRepo, repo.Name, &destRepo) {
+		return fmt.Errorf("repository %s has not been migrated yet", repo.Name)
+	}
+	m.syncLabels(ctx, repo, destRepo)
+	m.syncIssues(ctx, repo, changed.Add(-syncOverlap))
+	return m.synced(ctx, repo)
+}
+
+// synced returns the outcome of syncing a single repository
+func (m *Migrator) synced(ctx context.Context, repo *provider.GitRepository) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if m.Report.RepoFailed(repo.Name) {
+		return ErrIncomplete
+	}
+	return nil
+}
+
+// syncRepo copies the refs, labels, issues, pull requests and comments of a migrated repository that changed since its mark
+func (m *Migrator) syncRepo(ctx context.Context, repo, destRepo *provider.GitRepository, since time.Time) {
+	m.syncRefs(ctx, repo, destRepo)
+	m.syncLabels(ctx, repo, destRepo)
+	m.syncIssues(ctx, repo, since)
+	// pull requests are created after the issues so that preserved issue numbers stay free
+	m.processPullRequests(ctx, repo)
+}
+
+// syncLabels creates the labels added to the source and updates the color and description of those that changed
+func (m *Migrator) syncLabels(ctx context.Context, repo, destRepo *provider.GitRepository) {
+	labels, err := m.Src.GetLabels(ctx, repo.PID, repo.Name)
+	if err != nil {
+		m.fail(repo.Name, state.KindLabel, "", fmt.Errorf("failed to retrieve labels: %v", err))
+		return
+	}
+	destLabels, err := m.Dest.GetLabels(ctx, destRepo.PID, destRepo.Name)
+	if err != nil {
+		m.fail(repo.Name, state.KindLabel, "", fmt.Errorf("failed to retrieve destination labels: %v", err))
+		return
+	}
+	existing := make(map[string]*provider.GitLabel, len(destLabels))
+	for _, label := range destLabels {
+		existing[label.Name] = label
+	}
+	exists := func(name string) (bool, error) {
+		_, ok := existing[name]
+		return ok, nil
+	}
+
+	for _, label := range labels {
+		if m.stopping(ctx) {
+			return
+		}
+		dest, ok := existing[label.Name]
+		if !ok || !m.State.Lookup(repo.Name, state.KindLabel, label.Name, nil) {
+			m.createLabel(ctx, label, exists)
+			continue
+		}
+		if sameLabel(label, dest) {
+			continue
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"repo":  label.Repo,
+			"label": label.Name,
+			"color": label.Color,
+		}).Info("updating label")
+
+		if err := m.Dest.UpdateLabel(ctx, label); err != nil {
+			m.fail(repo.Name, state.KindLabel, label.Name, err)
+			continue
+		}
+		m.Report.Succeed(repo.Name, state.KindLabel)
+	}
+}
+
+// sameLabel reports whether two labels have the same color and description, colors may differ in case and a leading #
+func sameLabel(a, b *provider.GitLabel) bool {
+	return strings.EqualFold(strings.TrimPrefix(a.Color, "#"), strings.TrimPrefix(b.Color, "#")) &&
+		strings.TrimSpace(a.Description) == strings.TrimSpace(b.Description)
+}
+
+func (m *Migrator) syncRefs(ctx context.Context, repo, destRepo *provider.GitRepository) {
+	copied, err := provider.SyncRefs(ctx, m.Src, m.Dest, repo, destRepo)
+	if err != nil {
+		m.fail(repo.Name, state.KindImport, "", err)
+	} else if copied {
+		logrus.WithField("repo", repo.Name).Info("syncing refs")
+		m.Report.Succeed(repo.Name, state.KindImport)
+	}
+}
+
+// syncIssues creates the issues added to the source since a mark and updates the destination of those that changed
+func (m *Migrator) syncIssues(ctx context.Context, repo *provider.GitRepository, since time.Time) {
+	issues, err := m.Src.GetIssuesUpdatedAfter(ctx, repo.PID, repo.Name, since)
+	if err != nil {
+		m.fail(repo.Name, state.KindIssue, "", fmt.Errorf("failed to retrieve issues: %v", err))
+		return
+	}
+
+	// comments are copied while later issues are synced
+	commentPool := pool.New(m.IssueWorkers)
+	defer commentPool.Wait()
+
+	for _, issue := range issues {
+		if m.stopping(ctx) {
+			return
+		}
+		id := state.NumberID(issue.Number)
+		marked := provider.MarkIssue(m.SourceKind, issue)
+
+		var number int
+		if m.State.Lookup(repo.Name, state.KindIssue, id, &number) {
+			logrus.WithFields(logrus.Fields{
+				"IID":   issue.Number,
+				"issue": issue.Title,
+				"state": issue.State,
+			}).Info("updating issue")
+
+			marked.Number = number
+			if err := m.Dest.UpdateIssue(ctx, marked); err != nil {
+				m.fail(repo.Name, state.KindIssue, id, err)
+				continue
+			}
+			m.Report.Succeed(repo.Name, state.KindIssue)
+		} else {
+			if !m.includes(repo.Name, state.KindIssue, id) {
+				continue
+			}
+
+			logrus.WithFields(logrus.Fields{
+				"IID":   issue.Number,
+				"issue": issue.Title,
+				"state": issue.State,
+			}).Info("creating issue")
+
+			if m.PreserveIssueNumbers {
+				number, err = issue.Number, m.createNumberedIssue(ctx, marked, issue.Number)
+			} else {
+				number, err = m.createIssue(ctx, marked)
+			}
+			if err != nil {
+				m.fail(repo.Name, state.KindIssue, id, err)
+				continue
+			}
+		}
+
+		issue, number := issue, number
+		commentPool.Go(func() {
+			m.processComments(ctx, issue, number)
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/artur-sak13/gitmv/provider"
+	"github.com/artur-sak13/gitmv/state"
+)
+
+// syncSource serves one repository and records the time its updated issues were listed after
+type syncSource struct {
+	provider.GitProvider
+	issues   []*provider.GitIssue
+	comments map[int][]*provider.GitIssueComment
+	// labels replace the default bug label when set
+	labels []*provider.GitLabel
+	prs    []*provider.GitPullRequest
+	since  time.Time
+}
+
+func (s *syncSource) GetRepositories(ctx context.Context) ([]*provider.GitRepository, error) {
+	return []*provider.GitRepository{{Name: "r", PID: 1}}, nil
+}
+
+func (s *syncSource) GetLabels(ctx context.Context, pid int, repo string) ([]*provider.GitLabel, error) {
+	if s.labels != nil {
+		return s.labels, nil
+	}
+	return []*provider.GitLabel{{Repo: repo, Name: "bug"}}, nil
+}
+
+func (s *syncSource) GetIssues(ctx context.Context, pid int, repo string) ([]*provider.GitIssue, error) {
+	return s.issues, nil
+}
+
+func (s *syncSource) GetIssuesUpdatedAfter(ctx context.Context, pid int, repo string, since time.Time) ([]*provider.GitIssue, error) {
+	s.since = since
+	return s.issues, nil
+}
+
+func (s *syncSource) GetComments(ctx context.Context, pid, issueNum int, repo string) ([]*provider.GitIssueComment, error) {
+	return s.comments[issueNum], nil
+}
+
+func (s *syncSource) GetPullRequests(ctx context.Context, pid int, repo string) ([]*provider.GitPullRequest, error) {
+	return s.prs, nil
+}
+
+func (s *syncSource) GetReviewComments(ctx context.Context, pid, pullNum int, repo string) ([]*provider.GitReviewComment, error) {
+	return nil, nil
+}
+
+func (s *syncSource) GetRefs(ctx context.Context, pid int, repo string) ([]*provider.GitRef, error) {
+	return []*provider.GitRef{{Name: "master", SHA: "a"}}, nil
+}
+
+// syncDest is a fake destination whose refs match the source's
+type syncDest struct {
+	*provider.FakeProvider
+	failUpdate bool
+}
+
+func (d *syncDest) GetRefs(ctx context.Context, pid int, repo string) ([]*provider.GitRef, error) {
+	return []*provider.GitRef{{Name: "master", SHA: "a"}}, nil
+}
+
+func (d *syncDest) GetLabels(ctx context.Context, pid int, repo string) ([]*provider.GitLabel, error) {
+	fake, ok := d.Repositories.Load(repo)
+	if !ok {
+		return nil, errors.New("repository not found")
+	}
+	return fake.(*provider.FakeRepository).Labels, nil
+}
+
+func (d *syncDest) UpdateIssue(ctx context.Context, issue *provider.GitIssue) error {
+	if d.failUpdate {
+		return errors.New("update failed")
+	}
+	return d.FakeProvider.UpdateIssue(ctx, issue)
+}
+
+func TestSync(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "gitmv-sync")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	created := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	src := &syncSource{
+		issues: []*provider.GitIssue{{Repo: "r", PID: 1, Number: 1, Title: "t", State: "opened"}},
+		comments: map[int][]*provider.GitIssueComment{
+			1: {{ID: 1, Repo: "r", IssueNum: 1, Body: "c", CreatedAt: created}},
+		},
+	}
+	dest := &syncDest{FakeProvider: provider.NewFakeProvider().(*provider.FakeProvider)}
+	marks, err := state.OpenMarks(filepath.Join(dir, "marks.json"))
+	if err != nil {
+		t.Fatalf("OpenMarks returned error: %v", err)
+	}
+
+	m := NewMigrator(src, dest)
+	m.State = state.New()
+	// wikis need an SSH key
+	m.State.Record("r", state.KindWiki, "r", "r")
+
+	if err := m.Sync(ctx, marks); err != nil {
+		t.Fatalf("Sync returned error: %v", err)
+	}
+	mark, ok := marks.Get("r")
+	if !ok {
+		t.Fatalf("Sync did not mark the migrated repository")
+	}
+
+	// the source closes issue 1 and opens issue 2 with a comment
+	src.issues = []*provider.GitIssue{
+		{Repo: "r", PID: 1, Number: 1, Title: "t", State: "closed", Labels: []provider.GitLabel{{Name: "bug"}}},
+		{Repo: "r", PID: 1, Number: 2, Title: "t2", State: "opened"},
+	}
+	src.comments[2] = []*provider.GitIssueComment{{ID: 2, Repo: "r", IssueNum: 2, Body: "c2", CreatedAt: created}}
+	// and changes the color of its label and opens a pull request
+	src.labels = []*provider.GitLabel{{Repo: "r", Name: "bug", Color: "#FF0000", Description: "broken"}}
+	src.prs = []*provider.GitPullRequest{{Repo: "r", PID: 1, Number: 3, Title: "pr", State: "opened"}}
+	m.Report = NewReport()
+	if err := m.Sync(ctx, marks); err != nil {
+		t.Fatalf("Sync returned error: %v", err)
+	}
+	if !src.since.Equal(mark) {
+		t.Errorf("Sync listed issues updated after %v, want the mark %v", src.since, mark)
+	}
+
+	repo, _ := dest.Repositories.Load("r")
+	issues := repo.(*provider.FakeRepository).Issues
+	if issue, ok := issues.Load(1); !ok || issue.(*provider.FakeIssue).Issue.State != "closed" {
+		t.Errorf("Sync did not close issue 1")
+	}
+	if issue, ok := issues.Load(2); !ok || len(issue.(*provider.FakeIssue).Comments) != 1 {
+		t.Errorf("Sync did not create issue 2 with its comment")
+	}
+	if issue, _ := issues.Load(1); len(issue.(*provider.FakeIssue).Comments) != 1 {
+		t.Errorf("Sync copied the comment of issue 1 again")
+	}
+	if labels := repo.(*provider.FakeRepository).Labels; len(labels) != 1 || labels[0].Color != "#FF0000" || labels[0].Description != "broken" {
+		t.Errorf("Sync left labels %+v, want bug updated to #FF0000 broken", labels)
+	}
+	if _, ok := repo.(*provider.FakeRepository).PullRequests.Load(3); !ok {
+		t.Errorf("Sync did not create pull request 3")
+	}
+
+	// a failed update keeps the mark so that the next pass retries it
+	mark, _ = marks.Get("r")
+	dest.failUpdate = true
+	m.Report = NewReport()
+	if err := m.Sync(ctx, marks); err != ErrIncomplete {
+		t.Errorf("Sync returned %v, want %v", err, ErrIncomplete)
+	}
+	if got, _ := marks.Get("r"); !got.Equal(mark) {
+		t.Errorf("Sync moved the mark to %v after a failure, want %v", got, mark)
+	}
+
+	// webhook events sync a single repository from the time of their change
+	dest.failUpdate = false
+	m.Report = NewReport()
+	changed := time.Date(2019, 2, 1, 0, 0, 0, 0, time.UTC)
+	if err := m.SyncIssues(ctx, &provider.GitRepository{Name: "r", PID: 1}, changed); err != nil {
+		t.Errorf("SyncIssues returned error: %v", err)
+	}
+	if want := changed.Add(-syncOverlap); !src.since.Equal(want) {
+		t.Errorf("SyncIssues listed issues updated after %v, want %v", src.since, want)
+	}
+	if err := m.SyncRefs(ctx, &provider.GitRepository{Name: "other"}); err == nil {
+		t.Errorf("SyncRefs expected error for a repository that was not migrated")
+	}
+}
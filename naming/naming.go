@@ -0,0 +1,160 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 Artur Sak
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package naming
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/artur-sak13/gitmv/provider"
+)
+
+// Strategies named by Parse, any other strategy is a Go template
+const (
+	Path       = "path"
+	Dash       = "dash"
+	Underscore = "underscore"
+)
+
+// invalidChars matches the characters GitHub does not allow in repository names
+var invalidChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Data is passed to naming templates
+type Data struct {
+	// Namespace is the full group path, e.g. backend/services
+	Namespace string
+	// Group is the top level group of Namespace
+	Group string
+	Path  string
+}
+
+// funcs are available to naming templates, e.g. {{replace "/" "." .Namespace}}
+var funcs = template.FuncMap{
+	"lower": strings.ToLower,
+	"replace": func(old, new, s string) string {
+		return strings.Replace(s, old, new, -1)
+	},
+}
+
+// Parse returns the naming function of a strategy, nil for Path
+// Dash and Underscore join the namespace and path with - or _, a template such as
+// {{.Group}}-{{.Path}} is executed with Data. Characters GitHub rejects are replaced with -.
+func Parse(strategy string) (provider.NameFunc, error) {
+	switch strategy {
+	case "", Path:
+		return nil, nil
+	case Dash:
+		return joined("-"), nil
+	case Underscore:
+		return joined("_"), nil
+	}
+
+	if !strings.Contains(strategy, "{{") {
+		return nil, fmt.Errorf("unknown naming strategy %q, must be %s, %s, %s or a Go template", strategy, Path, Dash, Underscore)
+	}
+	tmpl, err := template.New("name").Funcs(funcs).Option("missingkey=error").Parse(strategy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse naming template due to: %v", err)
+	}
+	return func(namespace, path string) (string, error) {
+		var buf bytes.Buffer
+		data := Data{
+			Namespace: namespace,
+			Group:     strings.SplitN(namespace, "/", 2)[0],
+			Path:      path,
+		}
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return "", err
+		}
+		return sanitize(buf.String())
+	}, nil
+}
+
+// joined names repositories by their namespace segments and path joined with sep
+func joined(sep string) provider.NameFunc {
+	return func(namespace, path string) (string, error) {
+		if namespace == "" {
+			return sanitize(path)
+		}
+		return sanitize(strings.Replace(namespace, "/", sep, -1) + sep + path)
+	}
+}
+
+// sanitize replaces the characters GitHub rejects, failing on names that end up empty
+func sanitize(name string) (string, error) {
+	name = strings.Trim(invalidChars.ReplaceAllString(strings.TrimSpace(name), "-"), "-")
+	if name == "" || name == "." || name == ".." {
+		return "", fmt.Errorf("naming produced the invalid name %q", name)
+	}
+	return name, nil
+}
+
+// CollisionError lists the source repositories that would share a destination name
+type CollisionError struct {
+	// Names maps each shared name to the full paths of its repositories
+	Names map[string][]string
+}
+
+func (e *CollisionError) Error() string {
+	names := make([]string, 0, len(e.Names))
+	for name := range e.Names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d destination names are shared by several repositories, choose another naming strategy or exclude them:", len(names))
+	for _, name := range names {
+		fmt.Fprintf(&b, "\n  %s: %s", name, strings.Join(e.Names[name], ", "))
+	}
+	return b.String()
+}
+
+// Check fails with a CollisionError when repositories share a name, which would merge them in the destination
+// Names are compared ignoring case like GitHub does.
+func Check(repos []*provider.GitRepository) error {
+	byName := make(map[string][]string)
+	names := make(map[string]string)
+	for _, repo := range repos {
+		key := strings.ToLower(repo.Name)
+		if _, ok := names[key]; !ok {
+			names[key] = repo.Name
+		}
+		byName[key] = append(byName[key], repo.FullPath())
+	}
+
+	collisions := make(map[string][]string)
+	for key, paths := range byName {
+		if len(paths) > 1 {
+			collisions[names[key]] = paths
+		}
+	}
+	if len(collisions) > 0 {
+		return &CollisionError{Names: collisions}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package naming
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/artur-sak13/gitmv/provider"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		strategy  string
+		namespace string
+		path      string
+		want      string
+	}{
+		{Dash, "backend/services", "api", "backend-services-api"},
+		{Underscore, "backend/services", "api", "backend_services_api"},
+		{Dash, "", "api", "api"},
+		{"{{.Group}}-{{.Path}}", "backend/services", "api", "backend-api"},
+		{`{{replace "/" "." .Namespace | lower}}.{{.Path}}`, "Backend/Services", "api", "backend.services.api"},
+		{"{{.Namespace}}/{{.Path}}", "backend", "my api", "backend-my-api"},
+	}
+	for _, tt := range tests {
+		name, err := Parse(tt.strategy)
+		if err != nil {
+			t.Fatalf("Parse(%q) returned error: %v", tt.strategy, err)
+		}
+		got, err := name(tt.namespace, tt.path)
+		if err != nil {
+			t.Errorf("%s(%q, %q) returned error: %v", tt.strategy, tt.namespace, tt.path, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s(%q, %q) = %q, want %q", tt.strategy, tt.namespace, tt.path, got, tt.want)
+		}
+	}
+
+	if name, err := Parse(Path); name != nil || err != nil {
+		t.Errorf("Parse(%q) = %p, %v, want nil", Path, name, err)
+	}
+	for _, strategy := range []string{"slash", "{{.Path", "{{.Missing}}"} {
+		name, err := Parse(strategy)
+		if err == nil {
+			_, err = name("backend", "api")
+		}
+		if err == nil {
+			t.Errorf("strategy %q was accepted", strategy)
+		}
+	}
+	name, _ := Parse("{{.Group}}")
+	if _, err := name("", "api"); err == nil {
+		t.Errorf("template producing an empty name was accepted")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	repos := []*provider.GitRepository{
+		{Name: "api", Namespace: "backend"},
+		{Name: "web", Namespace: "frontend"},
+		{Name: "API", Namespace: "frontend"},
+	}
+	err := Check(repos)
+	collisions, ok := err.(*CollisionError)
+	if !ok {
+		t.Fatalf("Check returned %v, want a CollisionError", err)
+	}
+	want := map[string][]string{"api": {"backend/api", "frontend/API"}}
+	if !reflect.DeepEqual(collisions.Names, want) {
+		t.Errorf("Check found %v, want %v", collisions.Names, want)
+	}
+
+	if err := Check(repos[:2]); err != nil {
+		t.Errorf("Check returned error: %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/artur-sak13/gitmv/plan"
+	"github.com/artur-sak13/gitmv/provider"
+)
+
+const planHelp = `Write the changes a migration would make to a plan file for review, see apply.`
+
+func (cmd *planCommand) Name() string      { return "plan" }
+func (cmd *planCommand) Args() string      { return "[OPTIONS]" }
+func (cmd *planCommand) ShortHelp() string { return planHelp }
+func (cmd *planCommand) LongHelp() string  { return planHelp }
+func (cmd *planCommand) Hidden() bool      { return false }
+
+func (cmd *planCommand) Register(fs *flag.FlagSet) {
+	fs.StringVar(&cmd.out, "out", "gitmv-plan.json", "file the plan is written to")
+	fs.StringVar(&cmd.out, "o", "gitmv-plan.json", "file the plan is written to")
+}
+
+type planCommand struct {
+	out string
+}
+
+func (cmd *planCommand) Run(ctx context.Context, args []string) error {
+	return runCommand(ctx, cmd.handlePlan)
+}
+
+func (cmd *planCommand) handlePlan(ctx context.Context, src, dest provider.GitProvider) error {
+	p, err := buildPlan(ctx, src, dest)
+	if err != nil {
+		return err
+	}
+	if err := p.Write(cmd.out); err != nil {
+		return err
+	}
+	if err := p.Print(os.Stdout); err != nil {
+		return err
+	}
+	fmt.Printf("\nPlan written to %s, run \"gitmv apply %s\" to execute it\n", cmd.out, cmd.out)
+	return nil
+}
+
+// printPlan shows what a migration would change without writing anything, it backs --dry-run
+func printPlan(ctx context.Context, src, dest provider.GitProvider) error {
+	p, err := buildPlan(ctx, src, dest)
+	if err != nil {
+		return err
+	}
+	return p.Print(os.Stdout)
+}
+
+// buildPlan diffs the source against the destination
+func buildPlan(ctx context.Context, src, dest provider.GitProvider) (*plan.Plan, error) {
+	planner := plan.NewPlanner(src, dest, from.kind)
+	planner.State = journal
+	planner.Workers = repoWorkers
+	planner.Filter = repoFilter
+	planner.Members = members
+	planner.PreserveIssueNumbers = preserveIssueNumbers
+
+	p, err := planner.Build(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.Source = from.planEndpoint()
+	p.Destination = to.planEndpoint()
+	return p, nil
+}
+
+// planEndpoint identifies the endpoint in plan files
+func (e endpoint) planEndpoint() plan.Endpoint {
+	id := e.authID()
+	return plan.Endpoint{
+		Kind:  strings.ToLower(e.kind),
+		URL:   id.URL,
+		Owner: id.Owner,
+	}
+}
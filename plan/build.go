@@ -0,0 +1,251 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 Artur Sak
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package plan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/artur-sak13/gitmv/filter"
+	"github.com/artur-sak13/gitmv/pool"
+	"github.com/artur-sak13/gitmv/provider"
+	"github.com/artur-sak13/gitmv/state"
+)
+
+// DefaultWorkers is the number of repositories a Planner reads at once
+const DefaultWorkers = 4
+
+// Planner builds the plan of migrating Src into Dest
+type Planner struct {
+	Src  provider.GitProvider
+	Dest provider.GitProvider
+
+	// SourceKind is the registry kind of Src, it identifies the source in the markers of migrated issues and comments
+	SourceKind string
+
+	// State marks entities journaled by earlier runs as skipped, it may be nil
+	State *state.Journal
+
+	// Filter selects the source repositories to plan, all but forks and empty repositories when it is nil
+	Filter *filter.Filter
+
+	// Members plans granting the members of each repository access to its destination
+	Members bool
+
+	// PreserveIssueNumbers plans the placeholder issues that fill gaps in the source's issue numbers and their label
+	PreserveIssueNumbers bool
+
+	Workers int
+}
+
+// NewPlanner creates a planner reading from src and dest
+func NewPlanner(src, dest provider.GitProvider, sourceKind string) *Planner {
+	return &Planner{
+		Src:        src,
+		Dest:       dest,
+		SourceKind: sourceKind,
+		Workers:    DefaultWorkers,
+	}
+}
+
+// Build reads both providers and plans every repository of the source, failing if any cannot be read
+func (p *Planner) Build(ctx context.Context) (*Plan, error) {
+	repos, err := p.Src.GetRepositories(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source repositories due to: %v", err)
+	}
+	repos = p.Filter.Select(repos)
+	cache, err := provider.LoadCache(ctx, p.Dest, p.Workers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read destination due to: %v", err)
+	}
+
+	steps := make([][]*Step, len(repos))
+	var (
+		mu       sync.Mutex
+		firstErr error
+	)
+	workers := pool.New(p.Workers)
+	for i, repo := range repos {
+		i, repo := i, repo
+		workers.Go(func() {
+			repoSteps, err := p.planRepo(ctx, repo, cache[repo.Name])
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil && firstErr == nil {
+				firstErr = fmt.Errorf("failed to plan %s due to: %v", repo.Name, err)
+			}
+			steps[i] = repoSteps
+		})
+	}
+	workers.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	plan := &Plan{
+		Version:   Version,
+		CreatedAt: time.Now().UTC(),
+	}
+	for _, repoSteps := range steps {
+		plan.Steps = append(plan.Steps, repoSteps...)
+	}
+	return plan, nil
+}
+
+// planRepo plans one repository, cached is its destination counterpart or nil if it does not exist yet
+func (p *Planner) planRepo(ctx context.Context, repo *provider.GitRepository, cached *provider.CachedRepo) ([]*Step, error) {
+	exists := cached != nil
+	if !exists {
+		cached = provider.NewCachedRepo(repo)
+	}
+
+	steps := []*Step{
+		p.step(repo.Name, state.KindRepo, repo.Name, repo.Name, exists, cached.Repo),
+		p.step(repo.Name, state.KindImport, repo.Name, "", exists, provider.ImportComplete),
+	}
+
+	labels, err := p.Src.GetLabels(ctx, repo.PID, repo.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get labels: %v", err)
+	}
+	for _, label := range labels {
+		_, ok := cached.Labels[label.Name]
+		steps = append(steps, p.step(repo.Name, state.KindLabel, label.Name, label.Name, ok, label.Name))
+	}
+
+	issues, err := p.Src.GetIssues(ctx, repo.PID, repo.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get issues: %v", err)
+	}
+	if numbers := provider.PlaceholderNumbers(issues); p.PreserveIssueNumbers && len(numbers) > 0 {
+		if !hasLabel(labels, provider.PlaceholderLabel) {
+			_, ok := cached.Labels[provider.PlaceholderLabel]
+			steps = append(steps, p.step(repo.Name, state.KindLabel, provider.PlaceholderLabel, provider.PlaceholderLabel, ok, provider.PlaceholderLabel))
+		}
+		for _, number := range numbers {
+			placeholder := provider.NewPlaceholderIssue(repo, number)
+			steps = append(steps, p.step(repo.Name, state.KindIssue, state.NumberID(number), placeholder.Title, false, nil))
+		}
+	}
+	for _, issue := range issues {
+		cachedissue, ok := cached.Issues[provider.IssueMarker(p.SourceKind, issue)]
+		var number int
+		if ok {
+			number = cachedissue.Issue.Number
+		}
+		steps = append(steps, p.step(repo.Name, state.KindIssue, state.NumberID(issue.Number), issue.Title, ok, number))
+
+		comments, err := p.Src.GetComments(ctx, issue.PID, issue.Number, repo.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get comments of #%d: %v", issue.Number, err)
+		}
+		for _, comment := range comments {
+			found := false
+			if cachedissue != nil {
+				_, found = cachedissue.Comments[provider.CommentMarker(p.SourceKind, issue, comment)]
+			}
+			steps = append(steps, p.step(repo.Name, state.KindComment, state.CommentID(issue.Number, comment.ID), summary(comment.Body), found, number))
+		}
+	}
+
+	prs, err := p.Src.GetPullRequests(ctx, repo.PID, repo.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pull requests: %v", err)
+	}
+	for _, pr := range prs {
+		steps = append(steps, p.step(repo.Name, state.KindPullRequest, state.NumberID(pr.Number), pr.Title, false, nil))
+
+		comments, err := p.Src.GetReviewComments(ctx, pr.PID, pr.Number, repo.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get comments of pull request #%d: %v", pr.Number, err)
+		}
+		for _, comment := range comments {
+			steps = append(steps, p.step(repo.Name, state.KindReviewComment, state.CommentID(pr.Number, comment.ID), summary(comment.Body), false, nil))
+		}
+	}
+
+	wiki := p.step(repo.Name, state.KindWiki, repo.Name, "", false, nil)
+	if wiki.Action == Create && exists {
+		wiki.Action = Update
+		wiki.Reason = "pushed over the destination wiki"
+	}
+	steps = append(steps, wiki)
+
+	if p.Members {
+		steps = append(steps, p.step(repo.Name, state.KindMembers, repo.Name, "", false, nil))
+	}
+	return steps, nil
+}
+
+// hasLabel reports whether labels contain one with the given name
+func hasLabel(labels []*provider.GitLabel, name string) bool {
+	for _, label := range labels {
+		if label.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// step plans an entity, skipping it if the journal recorded it or it exists in the destination as dest
+func (p *Planner) step(repo string, kind state.Kind, id, title string, exists bool, dest interface{}) *Step {
+	step := &Step{
+		Repo:   repo,
+		Kind:   kind,
+		ID:     id,
+		Action: Create,
+		Title:  title,
+	}
+
+	var recorded json.RawMessage
+	switch {
+	case p.State.Lookup(repo, kind, id, &recorded):
+		step.Action = Skip
+		step.Reason = "recorded in state"
+		step.Dest = recorded
+	case exists:
+		step.Action = Skip
+		step.Reason = "exists in destination"
+		if raw, err := json.Marshal(dest); err == nil {
+			step.Dest = raw
+		}
+	}
+	return step
+}
+
+// summary shortens a comment body to its first line
+func summary(body string) string {
+	line := strings.TrimSpace(body)
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = strings.TrimSpace(line[:i])
+	}
+	if runes := []rune(line); len(runes) > 72 {
+		line = string(runes[:69]) + "..."
+	}
+	return line
+}
@@ -0,0 +1,224 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 Artur Sak
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/artur-sak13/gitmv/state"
+)
+
+// Version is the format of the plan files written by this package
+const Version = 1
+
+// Action is what applying a plan does with one source entity
+type Action string
+
+// Planned actions
+const (
+	Create Action = "create"
+	Update Action = "update"
+	Skip   Action = "skip"
+)
+
+// Endpoint identifies a Git provider a plan was made for
+type Endpoint struct {
+	Kind  string `json:"kind"`
+	URL   string `json:"url,omitempty"`
+	Owner string `json:"owner,omitempty"`
+}
+
+func (e Endpoint) String() string {
+	parts := []string{e.Kind}
+	for _, part := range []string{e.URL, e.Owner} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Step is the planned action for one source entity, identified like its state journal entry
+type Step struct {
+	Repo   string     `json:"repo"`
+	Kind   state.Kind `json:"kind"`
+	ID     string     `json:"id"`
+	Action Action     `json:"action"`
+	Title  string     `json:"title,omitempty"`
+	Reason string     `json:"reason,omitempty"`
+
+	// Dest is the destination entity a skipped step already exists as, in the form the state journal records it
+	Dest json.RawMessage `json:"dest,omitempty"`
+}
+
+// Plan lists the steps of a migration from Source to Destination
+type Plan struct {
+	Version     int       `json:"version"`
+	CreatedAt   time.Time `json:"created_at"`
+	Source      Endpoint  `json:"source"`
+	Destination Endpoint  `json:"destination"`
+	Steps       []*Step   `json:"steps"`
+
+	mu      sync.Mutex
+	index   map[stepKey]*Step
+	applied map[*Step]bool
+}
+
+type stepKey struct {
+	repo string
+	kind state.Kind
+	id   string
+}
+
+// Read loads a plan file
+func Read(path string) (*Plan, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan %s due to: %v", path, err)
+	}
+	var p Plan
+	if err := json.Unmarshal(buf, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse plan %s due to: %v", path, err)
+	}
+	if p.Version != Version {
+		return nil, fmt.Errorf("plan %s has version %d, this gitmv reads version %d", path, p.Version, Version)
+	}
+	return &p, nil
+}
+
+// Write saves the plan to path, replacing any existing file only once the plan is complete
+func (p *Plan) Write(path string) error {
+	buf, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode plan due to: %v", err)
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write plan %s due to: %v", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(buf, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write plan %s due to: %v", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write plan %s due to: %v", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write plan %s due to: %v", path, err)
+	}
+	return nil
+}
+
+// Includes reports whether the plan creates or updates an entity and marks its step as applied
+func (p *Plan) Includes(repo string, kind state.Kind, id string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.index == nil {
+		p.index = make(map[stepKey]*Step, len(p.Steps))
+		p.applied = make(map[*Step]bool)
+		for _, step := range p.Steps {
+			p.index[stepKey{step.Repo, step.Kind, step.ID}] = step
+		}
+	}
+
+	step, ok := p.index[stepKey{repo, kind, id}]
+	if !ok || step.Action == Skip {
+		return false
+	}
+	p.applied[step] = true
+	return true
+}
+
+// Pending returns the create and update steps that Includes was never asked about
+func (p *Plan) Pending() []*Step {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var pending []*Step
+	for _, step := range p.Steps {
+		if step.Action != Skip && !p.applied[step] {
+			pending = append(pending, step)
+		}
+	}
+	return pending
+}
+
+// Seed adopts the destination of every skipped step into a state journal,
+// so that a migration reuses the existing entities instead of creating them
+func (p *Plan) Seed(j *state.Journal) error {
+	for _, step := range p.Steps {
+		if step.Action != Skip || step.Dest == nil || j.Lookup(step.Repo, step.Kind, step.ID, nil) {
+			continue
+		}
+		if err := j.Adopt(step.Repo, step.Kind, step.ID, step.Dest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Print writes a table of the planned actions per repository and entity
+func (p *Plan) Print(w io.Writer) error {
+	type countKey struct {
+		repo string
+		kind state.Kind
+	}
+	counts := make(map[countKey]map[Action]int)
+	var keys []countKey
+	for _, step := range p.Steps {
+		key := countKey{step.Repo, step.Kind}
+		if counts[key] == nil {
+			counts[key] = make(map[Action]int)
+			keys = append(keys, key)
+		}
+		counts[key][step.Action]++
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].repo != keys[j].repo {
+			return keys[i].repo < keys[j].repo
+		}
+		return keys[i].kind < keys[j].kind
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "REPO\tENTITY\tCREATE\tUPDATE\tSKIP")
+	for _, key := range keys {
+		c := counts[key]
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\n", key.repo, key.kind, c[Create], c[Update], c[Skip])
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,230 @@
+package plan
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/artur-sak13/gitmv/provider"
+	"github.com/artur-sak13/gitmv/state"
+)
+
+// memProvider serves the reads of a Planner from memory
+type memProvider struct {
+	provider.GitProvider
+	repos    []*provider.GitRepository
+	labels   map[string][]*provider.GitLabel
+	issues   map[string][]*provider.GitIssue
+	comments map[int][]*provider.GitIssueComment
+}
+
+func (m *memProvider) GetRepositories(ctx context.Context) ([]*provider.GitRepository, error) {
+	return m.repos, nil
+}
+
+func (m *memProvider) GetLabels(ctx context.Context, pid int, repo string) ([]*provider.GitLabel, error) {
+	return m.labels[repo], nil
+}
+
+func (m *memProvider) GetIssues(ctx context.Context, pid int, repo string) ([]*provider.GitIssue, error) {
+	return m.issues[repo], nil
+}
+
+func (m *memProvider) GetComments(ctx context.Context, pid, issueNum int, repo string) ([]*provider.GitIssueComment, error) {
+	return m.comments[issueNum], nil
+}
+
+func (m *memProvider) GetPullRequests(ctx context.Context, pid int, repo string) ([]*provider.GitPullRequest, error) {
+	return nil, nil
+}
+
+func (m *memProvider) GetReviewComments(ctx context.Context, pid, pullNum int, repo string) ([]*provider.GitReviewComment, error) {
+	return nil, nil
+}
+
+func TestPlanner_Build(t *testing.T) {
+	created := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	issue := &provider.GitIssue{Repo: "old", PID: 1, Number: 1, Title: "first"}
+	comment := &provider.GitIssueComment{ID: 10, Repo: "old", IssueNum: 1, Body: "hello\nworld", CreatedAt: created}
+
+	src := &memProvider{
+		repos: []*provider.GitRepository{
+			{Name: "old", PID: 1},
+			{Name: "new", PID: 2},
+			{Name: "fork", PID: 3, Fork: true},
+		},
+		labels: map[string][]*provider.GitLabel{
+			"old": {{Repo: "old", Name: "bug"}, {Repo: "old", Name: "docs"}},
+		},
+		issues: map[string][]*provider.GitIssue{
+			"old": {issue, {Repo: "old", PID: 1, Number: 2, Title: "second"}},
+		},
+		comments: map[int][]*provider.GitIssueComment{1: {comment}},
+	}
+	dest := &memProvider{
+		repos: []*provider.GitRepository{{Name: "old"}},
+		labels: map[string][]*provider.GitLabel{
+			"old": {{Repo: "old", Name: "bug"}},
+		},
+		issues: map[string][]*provider.GitIssue{
+			"old": {{Repo: "old", Number: 7, Body: provider.MarkIssue("gitlab", issue).Body}},
+		},
+		comments: map[int][]*provider.GitIssueComment{7: {provider.MarkComment("gitlab", issue, comment)}},
+	}
+
+	p, err := NewPlanner(src, dest, "gitlab").Build(context.Background())
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+
+	want := []struct {
+		repo   string
+		kind   state.Kind
+		id     string
+		action Action
+		dest   string
+	}{
+		{"old", state.KindRepo, "old", Skip, `{"Name":"old","Description":"","CloneURL":"","SSHURL":"","Owner":"","Archived":false,"Fork":false,"Empty":false,"PID":0,"Namespace":"","Topics":null,"Visibility":"","LastActivity":"0001-01-01T00:00:00Z","Path":""}`},
+		{"old", state.KindImport, "old", Skip, `"complete"`},
+		{"old", state.KindLabel, "bug", Skip, `"bug"`},
+		{"old", state.KindLabel, "docs", Create, ""},
+		{"old", state.KindIssue, "1", Skip, "7"},
+		{"old", state.KindComment, state.CommentID(1, 10), Skip, "7"},
+		{"old", state.KindIssue, "2", Create, ""},
+		{"old", state.KindWiki, "old", Update, ""},
+		{"new", state.KindRepo, "new", Create, ""},
+		{"new", state.KindImport, "new", Create, ""},
+		{"new", state.KindWiki, "new", Create, ""},
+	}
+	if len(p.Steps) != len(want) {
+		t.Fatalf("Build planned %d steps, want %d: %+v", len(p.Steps), len(want), p.Steps)
+	}
+	for i, w := range want {
+		step := p.Steps[i]
+		if step.Repo != w.repo || step.Kind != w.kind || step.ID != w.id || step.Action != w.action || string(step.Dest) != w.dest {
+			t.Errorf("step %d = %s %s %s %s %s, want %s %s %s %s %s", i,
+				step.Repo, step.Kind, step.ID, step.Action, step.Dest, w.repo, w.kind, w.id, w.action, w.dest)
+		}
+	}
+	if p.Steps[5].Title != "hello" {
+		t.Errorf("comment title = %q, want %q", p.Steps[5].Title, "hello")
+	}
+}
+
+func TestPlanner_BuildMembers(t *testing.T) {
+	src := &memProvider{repos: []*provider.GitRepository{{Name: "r", PID: 1}}}
+	planner := NewPlanner(src, &memProvider{}, "gitlab")
+	planner.Members = true
+
+	p, err := planner.Build(context.Background())
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	last := p.Steps[len(p.Steps)-1]
+	if last.Kind != state.KindMembers || last.ID != "r" || last.Action != Create {
+		t.Errorf("last step = %s %s %s, want members r create", last.Kind, last.ID, last.Action)
+	}
+}
+
+func TestPlanner_BuildPreserveIssueNumbers(t *testing.T) {
+	src := &memProvider{
+		repos:  []*provider.GitRepository{{Name: "r", PID: 1}},
+		issues: map[string][]*provider.GitIssue{"r": {{Repo: "r", PID: 1, Number: 3, Title: "third"}}},
+	}
+	planner := NewPlanner(src, &memProvider{}, "gitlab")
+	planner.PreserveIssueNumbers = true
+
+	p, err := planner.Build(context.Background())
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	want := []struct {
+		kind state.Kind
+		id   string
+	}{
+		{state.KindLabel, provider.PlaceholderLabel},
+		{state.KindIssue, "1"},
+		{state.KindIssue, "2"},
+		{state.KindIssue, "3"},
+	}
+	for i, w := range want {
+		step := p.Steps[i+2]
+		if step.Kind != w.kind || step.ID != w.id || step.Action != Create {
+			t.Errorf("step %d = %s %s %s, want %s %s create", i+2, step.Kind, step.ID, step.Action, w.kind, w.id)
+		}
+	}
+}
+
+func TestPlan(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gitmv-plan")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "plan.json")
+
+	written := &Plan{
+		Version: Version,
+		Source:  Endpoint{Kind: "gitlab", URL: "https://gitlab.example.com"},
+		Steps: []*Step{
+			{Repo: "r", Kind: state.KindRepo, ID: "r", Action: Skip, Dest: []byte(`{"Name":"r"}`)},
+			{Repo: "r", Kind: state.KindIssue, ID: "1", Action: Create},
+			{Repo: "r", Kind: state.KindIssue, ID: "2", Action: Create},
+		},
+	}
+	if err := written.Write(path); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	p, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+	if p.Source != written.Source || len(p.Steps) != len(written.Steps) {
+		t.Fatalf("Read returned %+v, want %+v", p, written)
+	}
+
+	j := state.New()
+	if err := p.Seed(j); err != nil {
+		t.Fatalf("Seed returned error: %v", err)
+	}
+	var repo provider.GitRepository
+	if !j.Lookup("r", state.KindRepo, "r", &repo) || repo.Name != "r" {
+		t.Errorf("Seed did not journal the skipped repository")
+	}
+	if entries := j.Entries(); len(entries) != 1 || !entries[0].Existing {
+		t.Errorf("Seed journaled %+v, want the skipped repository as existing", entries)
+	}
+	if j.Lookup("r", state.KindIssue, "1", nil) {
+		t.Errorf("Seed journaled a planned issue")
+	}
+
+	if p.Includes("r", state.KindRepo, "r") {
+		t.Errorf("Includes returned true for a skipped step")
+	}
+	if p.Includes("r", state.KindIssue, "3") {
+		t.Errorf("Includes returned true for an unplanned entity")
+	}
+	if !p.Includes("r", state.KindIssue, "1") {
+		t.Errorf("Includes returned false for a planned step")
+	}
+	if pending := p.Pending(); len(pending) != 1 || pending[0].ID != "2" {
+		t.Errorf("Pending = %+v, want issue 2", pending)
+	}
+}
+
+func TestRead_Version(t *testing.T) {
+	f, err := ioutil.TempFile("", "gitmv-plan")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`{"version": 99}`)
+	f.Close()
+
+	if _, err := Read(f.Name()); err == nil {
+		t.Errorf("Read accepted an unknown plan version")
+	}
+}
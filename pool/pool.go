@@ -0,0 +1,60 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 Artur Sak
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pool
+
+import "sync"
+
+// Pool runs tasks on at most a fixed number of goroutines
+// Go blocks while every worker is busy, so callers cannot queue more work than the pool can run.
+type Pool struct {
+	sem chan struct{}
+	wg  sync.WaitGroup
+}
+
+// New creates a pool of size workers, a size below one runs one task at a time
+func New(size int) *Pool {
+	if size < 1 {
+		size = 1
+	}
+	return &Pool{
+		sem: make(chan struct{}, size),
+	}
+}
+
+// Go runs a task once a worker is free, blocking until then
+func (p *Pool) Go(task func()) {
+	p.sem <- struct{}{}
+	p.wg.Add(1)
+	go func() {
+		defer func() {
+			<-p.sem
+			p.wg.Done()
+		}()
+		task()
+	}()
+}
+
+// Wait blocks until every task started by Go has finished
+func (p *Pool) Wait() {
+	p.wg.Wait()
+}
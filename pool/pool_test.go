@@ -0,0 +1,41 @@
+package pool
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	p := New(2)
+
+	var running, peak, done int32
+	for i := 0; i < 10; i++ {
+		p.Go(func() {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				max := atomic.LoadInt32(&peak)
+				if n <= max || atomic.CompareAndSwapInt32(&peak, max, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			atomic.AddInt32(&done, 1)
+		})
+	}
+	p.Wait()
+
+	if done != 10 {
+		t.Errorf("Wait returned after %d tasks, want 10", done)
+	}
+	if peak > 2 {
+		t.Errorf("pool ran %d tasks at once, want at most 2", peak)
+	}
+}
+
+func TestPool_Size(t *testing.T) {
+	if got := cap(New(0).sem); got != 1 {
+		t.Errorf("New(0) has %d workers, want 1", got)
+	}
+}
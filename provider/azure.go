@@ -0,0 +1,513 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 Artur Sak
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/artur-sak13/gitmv/auth"
+)
+
+const (
+	azureAPIVersion         = "6.0"
+	azureCommentsAPIVersion = "6.0-preview.3"
+	azureBatchSize          = 200
+)
+
+// AzureProvider implements the read side of the provider interface for Azure DevOps Repos and Boards
+// The URL is the organization or collection URL and the owner is the project.
+// Work items belong to the repository named after the leaf of their area path,
+// so items in the project's root area land in the repository named after the project.
+type AzureProvider struct {
+	Client *restClient
+	ID     *auth.ID
+
+	workItemsMu sync.Mutex
+	workItems   []*azureWorkItem
+}
+
+type (
+	azureIdentity struct {
+		DisplayName string `json:"displayName"`
+		UniqueName  string `json:"uniqueName"`
+	}
+
+	azureRepository struct {
+		ID            string `json:"id"`
+		Name          string `json:"name"`
+		RemoteURL     string `json:"remoteUrl"`
+		SSHURL        string `json:"sshUrl"`
+		DefaultBranch string `json:"defaultBranch"`
+		Size          int64  `json:"size"`
+		IsFork        bool   `json:"isFork"`
+		IsDisabled    bool   `json:"isDisabled"`
+		Project       struct {
+			Name string `json:"name"`
+		} `json:"project"`
+	}
+
+	azureWorkItem struct {
+		ID     int `json:"id"`
+		Fields struct {
+			AreaPath    string         `json:"System.AreaPath"`
+			Title       string         `json:"System.Title"`
+			Description string         `json:"System.Description"`
+			State       string         `json:"System.State"`
+			Tags        string         `json:"System.Tags"`
+			CreatedBy   *azureIdentity `json:"System.CreatedBy"`
+			AssignedTo  *azureIdentity `json:"System.AssignedTo"`
+		} `json:"fields"`
+	}
+
+	azureRef struct {
+		Name           string `json:"name"`
+		ObjectID       string `json:"objectId"`
+		PeeledObjectID string `json:"peeledObjectId"`
+	}
+
+	azureComment struct {
+		ID           int            `json:"id"`
+		Text         string         `json:"text"`
+		CreatedBy    *azureIdentity `json:"createdBy"`
+		CreatedDate  time.Time      `json:"createdDate"`
+		ModifiedDate time.Time      `json:"modifiedDate"`
+	}
+)
+
+var azureWorkItemFields = []string{
+	"System.AreaPath",
+	"System.Title",
+	"System.Description",
+	"System.State",
+	"System.Tags",
+	"System.CreatedBy",
+	"System.AssignedTo",
+}
+
+// NewAzureProvider creates a new Azure DevOps client which implements the provider interface
+func NewAzureProvider(id *auth.ID) (GitProvider, error) {
+	return WithAzureClient(newHTTPClient(), id)
+}
+
+// WithAzureClient creates a new GitProvider with an HTTP client
+// This function is exported to create mock clients in tests
+func WithAzureClient(client *http.Client, id *auth.ID) (GitProvider, error) {
+	if id.URL == "" {
+		return nil, fmt.Errorf("azure devops organization url cannot be empty")
+	}
+	if id.Owner == "" {
+		return nil, fmt.Errorf("azure devops project cannot be empty")
+	}
+
+	header := make(http.Header)
+	if id.Token != "" {
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(":"+id.Token)))
+	}
+
+	rest, err := newRESTClient(client, strings.TrimSuffix(id.URL, "/")+"/"+url.PathEscape(id.Owner)+"/_apis", header)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AzureProvider{
+		Client: rest,
+		ID:     id,
+	}, nil
+}
+
+// GetRepositories retrieves the Azure Repos of the project
+func (a *AzureProvider) GetRepositories(ctx context.Context) ([]*GitRepository, error) {
+	var result struct {
+		Value []*azureRepository `json:"value"`
+	}
+	if _, err := a.Client.do(ctx, http.MethodGet, "git/repositories", a.query(azureAPIVersion), nil, &result); err != nil {
+		return nil, err
+	}
+
+	var repos []*GitRepository
+	for _, repo := range result.Value {
+		repos = append(repos, fromAzureRepo(repo))
+	}
+	return repos, nil
+}
+
+func fromAzureRepo(repo *azureRepository) *GitRepository {
+	return &GitRepository{
+		Name:     repo.Name,
+		CloneURL: repo.RemoteURL,
+		SSHURL:   repo.SSHURL,
+		Owner:    repo.Project.Name,
+		Archived: repo.IsDisabled,
+		Fork:     repo.IsFork,
+		Empty:    repo.DefaultBranch == "" || repo.Size == 0,
+		PID:      hashPID(repo.ID),
+	}
+}
+
+// GetIssues retrieves the work items whose area path ends in the repository's name
+func (a *AzureProvider) GetIssues(ctx context.Context, pid int, repo string) ([]*GitIssue, error) {
+	workItems, err := a.getWorkItems(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	issues := []*GitIssue{}
+	for _, item := range workItems {
+		if !a.inRepo(item, repo) {
+			continue
+		}
+		issue := fromAzureWorkItem(item)
+		issue.Repo = repo
+		issue.PID = pid
+		issues = append(issues, issue)
+	}
+	return issues, nil
+}
+
+// GetIssuesUpdatedAfter retrieves every work item of the repository since work item queries are not filtered by date
+func (a *AzureProvider) GetIssuesUpdatedAfter(ctx context.Context, pid int, repo string, since time.Time) ([]*GitIssue, error) {
+	return a.GetIssues(ctx, pid, repo)
+}
+
+func (a *AzureProvider) inRepo(item *azureWorkItem, repo string) bool {
+	area := strings.Replace(item.Fields.AreaPath, `\`, "/", -1)
+	return strings.EqualFold(path.Base(area), repo)
+}
+
+func fromAzureWorkItem(item *azureWorkItem) *GitIssue {
+	assignees := []GitUser{}
+	if item.Fields.AssignedTo != nil {
+		assignees = append(assignees, *fromAzureIdentity(item.Fields.AssignedTo))
+	}
+
+	return &GitIssue{
+		Number:    item.ID,
+		Title:     item.Fields.Title,
+		Body:      item.Fields.Description,
+		State:     fromAzureState(item.Fields.State),
+		Labels:    ToGitLabels(splitAzureTags(item.Fields.Tags)),
+		User:      fromAzureIdentity(item.Fields.CreatedBy),
+		Assignees: assignees,
+	}
+}
+
+// fromAzureState maps the states of the default process templates onto open and closed
+func fromAzureState(state string) string {
+	switch strings.ToLower(state) {
+	case "closed", "done", "removed", "resolved":
+		return "closed"
+	default:
+		return "open"
+	}
+}
+
+func splitAzureTags(tags string) []string {
+	result := []string{}
+	for _, tag := range strings.Split(tags, ";") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+func fromAzureIdentity(identity *azureIdentity) *GitUser {
+	if identity == nil {
+		return &GitUser{}
+	}
+	user := &GitUser{
+		Login: identity.UniqueName,
+		Name:  identity.DisplayName,
+	}
+	if strings.Contains(identity.UniqueName, "@") {
+		user.Email = identity.UniqueName
+	}
+	return user
+}
+
+// getWorkItems queries every work item of the project once and caches them for each repository
+func (a *AzureProvider) getWorkItems(ctx context.Context) ([]*azureWorkItem, error) {
+	a.workItemsMu.Lock()
+	defer a.workItemsMu.Unlock()
+
+	if a.workItems != nil {
+		return a.workItems, nil
+	}
+
+	wiql := map[string]string{
+		"query": "SELECT [System.Id] FROM WorkItems WHERE [System.TeamProject] = @project ORDER BY [System.Id]",
+	}
+	var refs struct {
+		WorkItems []struct {
+			ID int `json:"id"`
+		} `json:"workItems"`
+	}
+	if _, err := a.Client.do(ctx, http.MethodPost, "wit/wiql", a.query(azureAPIVersion), wiql, &refs); err != nil {
+		return nil, err
+	}
+
+	workItems := []*azureWorkItem{}
+	for start := 0; start < len(refs.WorkItems); start += azureBatchSize {
+		end := start + azureBatchSize
+		if end > len(refs.WorkItems) {
+			end = len(refs.WorkItems)
+		}
+
+		var ids []string
+		for _, ref := range refs.WorkItems[start:end] {
+			ids = append(ids, strconv.Itoa(ref.ID))
+		}
+
+		opts := a.query(azureAPIVersion)
+		opts.Set("ids", strings.Join(ids, ","))
+		opts.Set("fields", strings.Join(azureWorkItemFields, ","))
+
+		var batch struct {
+			Value []*azureWorkItem `json:"value"`
+		}
+		if _, err := a.Client.do(ctx, http.MethodGet, "wit/workitems", opts, nil, &batch); err != nil {
+			return nil, err
+		}
+		workItems = append(workItems, batch.Value...)
+	}
+
+	a.workItems = workItems
+	return workItems, nil
+}
+
+// GetComments retrieves the discussion of a work item, following continuation tokens
+func (a *AzureProvider) GetComments(ctx context.Context, pid, issueNum int, repo string) ([]*GitIssueComment, error) {
+	opts := a.query(azureCommentsAPIVersion)
+	opts.Set("order", "asc")
+
+	var list []*azureComment
+	for {
+		var page struct {
+			Comments          []*azureComment `json:"comments"`
+			ContinuationToken string          `json:"continuationToken"`
+		}
+		if _, err := a.Client.do(ctx, http.MethodGet, fmt.Sprintf("wit/workItems/%d/comments", issueNum), opts, nil, &page); err != nil {
+			return nil, err
+		}
+		list = append(list, page.Comments...)
+
+		if page.ContinuationToken == "" {
+			break
+		}
+		opts.Set("continuationToken", page.ContinuationToken)
+	}
+
+	var comments []*GitIssueComment
+	for _, comment := range list {
+		comments = append(comments, &GitIssueComment{
+			ID:        comment.ID,
+			Repo:      repo,
+			IssueNum:  issueNum,
+			User:      *fromAzureIdentity(comment.CreatedBy),
+			Body:      comment.Text,
+			CreatedAt: comment.CreatedDate,
+			UpdatedAt: comment.ModifiedDate,
+		})
+	}
+	return comments, nil
+}
+
+// GetLabels retrieves the tags used by a repository's work items as labels
+func (a *AzureProvider) GetLabels(ctx context.Context, pid int, repo string) ([]*GitLabel, error) {
+	issues, err := a.GetIssues(ctx, pid, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, issue := range issues {
+		for _, label := range issue.Labels {
+			if !seen[label.Name] {
+				seen[label.Name] = true
+				names = append(names, label.Name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	labels := []*GitLabel{}
+	for _, name := range names {
+		labels = append(labels, &GitLabel{Repo: repo, Name: name})
+	}
+	return labels, nil
+}
+
+// GetRefs retrieves the branches and tags of an Azure Repos repository, following continuation tokens
+func (a *AzureProvider) GetRefs(ctx context.Context, pid int, repo string) ([]*GitRef, error) {
+	opts := a.query(azureAPIVersion)
+	opts.Set("peelTags", "true")
+
+	var refs []*GitRef
+	for {
+		var page struct {
+			Value []*azureRef `json:"value"`
+		}
+		resp, err := a.Client.do(ctx, http.MethodGet, "git/repositories/"+url.PathEscape(repo)+"/refs", opts, nil, &page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list refs of %s due to: %v", repo, err)
+		}
+		for _, ref := range page.Value {
+			if gitref := fromAzureRef(ref); gitref != nil {
+				refs = append(refs, gitref)
+			}
+		}
+
+		token := resp.Header.Get("X-MS-ContinuationToken")
+		if token == "" {
+			return refs, nil
+		}
+		opts.Set("continuationToken", token)
+	}
+}
+
+// fromAzureRef converts a branch or tag, annotated tags are peeled to their commit
+func fromAzureRef(ref *azureRef) *GitRef {
+	switch {
+	case strings.HasPrefix(ref.Name, "refs/heads/"):
+		return &GitRef{Name: strings.TrimPrefix(ref.Name, "refs/heads/"), SHA: ref.ObjectID}
+	case strings.HasPrefix(ref.Name, "refs/tags/"):
+		sha := ref.ObjectID
+		if ref.PeeledObjectID != "" {
+			sha = ref.PeeledObjectID
+		}
+		return &GitRef{Name: strings.TrimPrefix(ref.Name, "refs/tags/"), Tag: true, SHA: sha}
+	}
+	return nil
+}
+
+// GetPullRequests returns no pull requests since reading them from Azure Repos is not supported
+func (a *AzureProvider) GetPullRequests(ctx context.Context, pid int, repo string) ([]*GitPullRequest, error) {
+	return []*GitPullRequest{}, nil
+}
+
+// GetReviewComments returns no pull request comments since reading pull requests from Azure Repos is not supported
+func (a *AzureProvider) GetReviewComments(ctx context.Context, pid, pullNum int, repo string) ([]*GitReviewComment, error) {
+	return []*GitReviewComment{}, nil
+}
+
+// GetAuth returns a string with a user's api authentication token
+func (a *AzureProvider) GetAuth() *auth.ID {
+	return a.ID
+}
+
+// CreateRepository is not supported since Azure DevOps is only a migration source
+func (a *AzureProvider) CreateRepository(ctx context.Context, repo *GitRepository) (*GitRepository, error) {
+	return nil, fmt.Errorf("azure devops CreateRepository not supported")
+}
+
+// DeleteRepository is not supported since Azure DevOps is only a migration source
+func (a *AzureProvider) DeleteRepository(ctx context.Context, repo *GitRepository) error {
+	return fmt.Errorf("azure devops DeleteRepository not supported")
+}
+
+// ArchiveRepository is not supported since Azure DevOps is only a migration source
+func (a *AzureProvider) ArchiveRepository(ctx context.Context, repo *GitRepository) error {
+	return fmt.Errorf("azure devops ArchiveRepository not supported")
+}
+
+// UpdateIssue is not supported since Azure DevOps is only a migration source
+func (a *AzureProvider) UpdateIssue(ctx context.Context, issue *GitIssue) error {
+	return fmt.Errorf("azure devops UpdateIssue not supported")
+}
+
+// UpdateLabel is not supported since Azure DevOps is only a migration source
+func (a *AzureProvider) UpdateLabel(ctx context.Context, label *GitLabel) error {
+	return fmt.Errorf("azure devops UpdateLabel not supported")
+}
+
+// DeleteLabel is not supported since Azure DevOps is only a migration source
+func (a *AzureProvider) DeleteLabel(ctx context.Context, label *GitLabel) error {
+	return fmt.Errorf("azure devops DeleteLabel not supported")
+}
+
+// DeleteIssue is not supported since Azure DevOps is only a migration source
+func (a *AzureProvider) DeleteIssue(ctx context.Context, issue *GitIssue) error {
+	return fmt.Errorf("azure devops DeleteIssue not supported")
+}
+
+// MigrateRepo is not supported since Azure DevOps is only a migration source
+func (a *AzureProvider) MigrateRepo(ctx context.Context, repo *GitRepository, token string) (string, error) {
+	return "", fmt.Errorf("azure devops MigrateRepo not supported")
+}
+
+// GetImportProgress is not supported since Azure DevOps is only a migration source
+func (a *AzureProvider) GetImportProgress(ctx context.Context, repo string) (string, error) {
+	return "", fmt.Errorf("azure devops GetImportProgress not supported")
+}
+
+// CreateIssue is not supported since Azure DevOps is only a migration source
+func (a *AzureProvider) CreateIssue(ctx context.Context, issue *GitIssue) (*GitIssue, error) {
+	return nil, fmt.Errorf("azure devops CreateIssue not supported")
+}
+
+// CreateIssueComment is not supported since Azure DevOps is only a migration source
+func (a *AzureProvider) CreateIssueComment(ctx context.Context, issueNum int, comment *GitIssueComment) error {
+	return fmt.Errorf("azure devops CreateIssueComment not supported")
+}
+
+// CreateLabel is not supported since Azure DevOps is only a migration source
+func (a *AzureProvider) CreateLabel(ctx context.Context, label *GitLabel) (*GitLabel, error) {
+	return nil, fmt.Errorf("azure devops CreateLabel not supported")
+}
+
+// CreatePullRequest is not supported since Azure DevOps is only a migration source
+func (a *AzureProvider) CreatePullRequest(ctx context.Context, pr *GitPullRequest) (*GitPullRequest, error) {
+	return nil, fmt.Errorf("azure devops CreatePullRequest not supported")
+}
+
+// CreateReviewComment is not supported since Azure DevOps is only a migration source
+func (a *AzureProvider) CreateReviewComment(ctx context.Context, pr *GitPullRequest, comment *GitReviewComment) error {
+	return fmt.Errorf("azure devops CreateReviewComment not supported")
+}
+
+func (a *AzureProvider) query(version string) url.Values {
+	opts := url.Values{}
+	opts.Set("api-version", version)
+	return opts
+}
+
+// GrantAccess is not supported since Azure DevOps is only a migration source
+func (a *AzureProvider) GrantAccess(ctx context.Context, repo *GitRepository, members []*GitMember) error {
+	return fmt.Errorf("azure devops GrantAccess not supported")
+}
+
+// GetMembers is not supported since reading the members of Azure DevOps repositories is not implemented
+func (a *AzureProvider) GetMembers(ctx context.Context, repo *GitRepository) ([]*GitMember, error) {
+	return nil, fmt.Errorf("azure devops GetMembers not supported")
+}
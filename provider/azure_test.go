@@ -0,0 +1,226 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/artur-sak13/gitmv/auth"
+)
+
+func setupAzure(t *testing.T) (*AzureProvider, *http.ServeMux, func()) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	id := auth.NewAuthID(server.URL+"/org", "pat", "proj")
+	prov, err := WithAzureClient(server.Client(), id)
+	if err != nil {
+		t.Fatalf("WithAzureClient returned error: %v", err)
+	}
+
+	return prov.(*AzureProvider), mux, server.Close
+}
+
+func TestAzure_GetRepositories(t *testing.T) {
+	prov, mux, teardown := setupAzure(t)
+	defer teardown()
+
+	mux.HandleFunc("/org/proj/_apis/git/repositories", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		if user, password, ok := r.BasicAuth(); !ok || user != "" || password != "pat" {
+			t.Errorf("BasicAuth = %q, %q, want empty user and pat", user, password)
+		}
+		if got := r.URL.Query().Get("api-version"); got != azureAPIVersion {
+			t.Errorf("api-version = %q, want %q", got, azureAPIVersion)
+		}
+		fmt.Fprint(w, `{"value":[
+			{"id":"a1","name":"proj","remoteUrl":"https://dev.azure.com/org/proj/_git/proj","sshUrl":"git@ssh.dev.azure.com:v3/org/proj/proj","defaultBranch":"refs/heads/main","size":1024,"project":{"name":"proj"}},
+			{"id":"b2","name":"empty","isFork":true,"project":{"name":"proj"}}]}`)
+	})
+
+	got, err := prov.GetRepositories(context.Background())
+	if err != nil {
+		t.Errorf("GetRepositories returned error: %v", err)
+	}
+	want := []*GitRepository{
+		{
+			Name:     "proj",
+			CloneURL: "https://dev.azure.com/org/proj/_git/proj",
+			SSHURL:   "git@ssh.dev.azure.com:v3/org/proj/proj",
+			Owner:    "proj",
+			PID:      hashPID("a1"),
+		},
+		{Name: "empty", Owner: "proj", Fork: true, Empty: true, PID: hashPID("b2")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetRepositories = %+v, want %+v", got, want)
+	}
+}
+
+func setupAzureWorkItems(t *testing.T, mux *http.ServeMux) {
+	mux.HandleFunc("/org/proj/_apis/wit/wiql", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode wiql query: %v", err)
+		}
+		if body["query"] == "" {
+			t.Errorf("wiql query is empty")
+		}
+		fmt.Fprint(w, `{"workItems":[{"id":1},{"id":2},{"id":3}]}`)
+	})
+	mux.HandleFunc("/org/proj/_apis/wit/workitems", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		if got := r.URL.Query().Get("ids"); got != "1,2,3" {
+			t.Errorf("ids = %q, want 1,2,3", got)
+		}
+		fmt.Fprint(w, `{"value":[
+			{"id":1,"fields":{"System.AreaPath":"proj","System.Title":"t1","System.Description":"d1","System.State":"Active","System.Tags":"bug; ui",
+				"System.CreatedBy":{"displayName":"Jane","uniqueName":"jane@example.com"},
+				"System.AssignedTo":{"displayName":"Bob","uniqueName":"bob@example.com"}}},
+			{"id":2,"fields":{"System.AreaPath":"proj","System.Title":"t2","System.State":"Done","System.Tags":"bug",
+				"System.CreatedBy":{"displayName":"Jane","uniqueName":"jane@example.com"}}},
+			{"id":3,"fields":{"System.AreaPath":"proj\\other","System.Title":"t3","System.State":"New"}}]}`)
+	})
+}
+
+func TestAzure_GetIssues(t *testing.T) {
+	prov, mux, teardown := setupAzure(t)
+	defer teardown()
+	setupAzureWorkItems(t, mux)
+
+	got, err := prov.GetIssues(context.Background(), 5, "proj")
+	if err != nil {
+		t.Errorf("GetIssues returned error: %v", err)
+	}
+	jane := &GitUser{Login: "jane@example.com", Name: "Jane", Email: "jane@example.com"}
+	want := []*GitIssue{
+		{
+			Repo:      "proj",
+			PID:       5,
+			Number:    1,
+			Title:     "t1",
+			Body:      "d1",
+			State:     "open",
+			Labels:    []GitLabel{{Name: "bug"}, {Name: "ui"}},
+			User:      jane,
+			Assignees: []GitUser{{Login: "bob@example.com", Name: "Bob", Email: "bob@example.com"}},
+		},
+		{
+			Repo:      "proj",
+			PID:       5,
+			Number:    2,
+			Title:     "t2",
+			State:     "closed",
+			Labels:    []GitLabel{{Name: "bug"}},
+			User:      jane,
+			Assignees: []GitUser{},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetIssues = %+v, want %+v", got, want)
+	}
+
+	other, err := prov.GetIssues(context.Background(), 6, "other")
+	if err != nil {
+		t.Errorf("GetIssues returned error: %v", err)
+	}
+	if len(other) != 1 || other[0].Number != 3 {
+		t.Errorf("GetIssues(other) = %+v, want work item 3", other)
+	}
+}
+
+func TestAzure_GetLabels(t *testing.T) {
+	prov, mux, teardown := setupAzure(t)
+	defer teardown()
+	setupAzureWorkItems(t, mux)
+
+	got, err := prov.GetLabels(context.Background(), 5, "proj")
+	if err != nil {
+		t.Errorf("GetLabels returned error: %v", err)
+	}
+	want := []*GitLabel{{Repo: "proj", Name: "bug"}, {Repo: "proj", Name: "ui"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetLabels = %+v, want %+v", got, want)
+	}
+}
+
+func TestAzure_GetComments(t *testing.T) {
+	prov, mux, teardown := setupAzure(t)
+	defer teardown()
+
+	mux.HandleFunc("/org/proj/_apis/wit/workItems/1/comments", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		if r.URL.Query().Get("continuationToken") == "next" {
+			fmt.Fprint(w, `{"comments":[{"id":2,"text":"second","createdBy":{"displayName":"Bob","uniqueName":"bob"},"createdDate":"2019-01-02T00:00:00Z","modifiedDate":"2019-01-02T00:00:00Z"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"continuationToken":"next","comments":[{"id":1,"text":"first","createdBy":{"displayName":"Jane","uniqueName":"jane@example.com"},"createdDate":"2019-01-01T00:00:00Z","modifiedDate":"2019-01-01T00:00:00Z"}]}`)
+	})
+
+	got, err := prov.GetComments(context.Background(), 5, 1, "proj")
+	if err != nil {
+		t.Errorf("GetComments returned error: %v", err)
+	}
+	first := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	second := time.Date(2019, 1, 2, 0, 0, 0, 0, time.UTC)
+	want := []*GitIssueComment{
+		{
+			ID:        1,
+			Repo:      "proj",
+			IssueNum:  1,
+			User:      GitUser{Login: "jane@example.com", Name: "Jane", Email: "jane@example.com"},
+			Body:      "first",
+			CreatedAt: first,
+			UpdatedAt: first,
+		},
+		{
+			ID:        2,
+			Repo:      "proj",
+			IssueNum:  1,
+			User:      GitUser{Login: "bob", Name: "Bob"},
+			Body:      "second",
+			CreatedAt: second,
+			UpdatedAt: second,
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetComments = %+v, want %+v", got, want)
+	}
+}
+
+func TestAzure_GetRefs(t *testing.T) {
+	prov, mux, teardown := setupAzure(t)
+	defer teardown()
+
+	mux.HandleFunc("/org/proj/_apis/git/repositories/r/refs", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		if got := r.URL.Query().Get("peelTags"); got != "true" {
+			t.Errorf("peelTags = %q, want true", got)
+		}
+		if r.URL.Query().Get("continuationToken") == "next" {
+			fmt.Fprint(w, `{"value":[{"name":"refs/tags/v1","objectId":"tag","peeledObjectId":"def"},{"name":"refs/tags/v2","objectId":"ghi"}]}`)
+			return
+		}
+		w.Header().Set("X-MS-ContinuationToken", "next")
+		fmt.Fprint(w, `{"value":[{"name":"refs/heads/master","objectId":"abc"},{"name":"refs/pull/1/merge","objectId":"xyz"}]}`)
+	})
+
+	got, err := prov.GetRefs(context.Background(), 1, "r")
+	if err != nil {
+		t.Errorf("GetRefs returned error: %v", err)
+	}
+	want := []*GitRef{
+		{Name: "master", SHA: "abc"},
+		{Name: "v1", Tag: true, SHA: "def"},
+		{Name: "v2", Tag: true, SHA: "ghi"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetRefs = %+v, want %+v", got, want)
+	}
+}
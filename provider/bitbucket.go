@@ -0,0 +1,484 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 Artur Sak
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/artur-sak13/gitmv/auth"
+)
+
+const bitbucketHostedURL = "https://api.bitbucket.org/2.0"
+
+// BitbucketProvider implements the read side of the provider interface for Bitbucket Cloud and its issue tracker
+type BitbucketProvider struct {
+	Client *restClient
+	ID     *auth.ID
+}
+
+type (
+	bitbucketPage struct {
+		Values json.RawMessage `json:"values"`
+		Next   string          `json:"next"`
+	}
+
+	bitbucketUser struct {
+		DisplayName string `json:"display_name"`
+		Nickname    string `json:"nickname"`
+		Username    string `json:"username"`
+	}
+
+	bitbucketRepo struct {
+		UUID        string         `json:"uuid"`
+		Slug        string         `json:"slug"`
+		Description string         `json:"description"`
+		Owner       *bitbucketUser `json:"owner"`
+		Parent      *struct {
+			FullName string `json:"full_name"`
+		} `json:"parent"`
+		MainBranch *struct {
+			Name string `json:"name"`
+		} `json:"mainbranch"`
+		Links struct {
+			Clone []struct {
+				Href string `json:"href"`
+				Name string `json:"name"`
+			} `json:"clone"`
+		} `json:"links"`
+	}
+
+	bitbucketContent struct {
+		Raw string `json:"raw"`
+	}
+
+	bitbucketIssue struct {
+		ID        int              `json:"id"`
+		Title     string           `json:"title"`
+		Content   bitbucketContent `json:"content"`
+		State     string           `json:"state"`
+		Reporter  *bitbucketUser   `json:"reporter"`
+		Assignee  *bitbucketUser   `json:"assignee"`
+		Component *struct {
+			Name string `json:"name"`
+		} `json:"component"`
+	}
+
+	bitbucketComment struct {
+		ID        int              `json:"id"`
+		Content   bitbucketContent `json:"content"`
+		User      *bitbucketUser   `json:"user"`
+		CreatedOn time.Time        `json:"created_on"`
+		UpdatedOn *time.Time       `json:"updated_on"`
+	}
+
+	bitbucketComponent struct {
+		Name string `json:"name"`
+	}
+
+	bitbucketRef struct {
+		Name   string `json:"name"`
+		Target struct {
+			Hash string `json:"hash"`
+		} `json:"target"`
+	}
+)
+
+// NewBitbucketProvider creates a new Bitbucket Cloud client which implements the provider interface
+// The token is either an access token or a "username:app-password" pair
+func NewBitbucketProvider(id *auth.ID) (GitProvider, error) {
+	return WithBitbucketClient(newHTTPClient(), id)
+}
+
+// WithBitbucketClient creates a new GitProvider with an HTTP client
+// This function is exported to create mock clients in tests
+func WithBitbucketClient(client *http.Client, id *auth.ID) (GitProvider, error) {
+	if id.Owner == "" {
+		return nil, fmt.Errorf("bitbucket workspace cannot be empty")
+	}
+
+	baseURL := id.URL
+	if baseURL == "" {
+		baseURL = bitbucketHostedURL
+	}
+
+	header := make(http.Header)
+	switch {
+	case strings.Contains(id.Token, ":"):
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(id.Token)))
+	case id.Token != "":
+		header.Set("Authorization", "Bearer "+id.Token)
+	}
+
+	rest, err := newRESTClient(client, baseURL, header)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BitbucketProvider{
+		Client: rest,
+		ID:     id,
+	}, nil
+}
+
+// GetRepositories retrieves the repositories of a Bitbucket workspace
+func (b *BitbucketProvider) GetRepositories(ctx context.Context) ([]*GitRepository, error) {
+	var result []*bitbucketRepo
+	err := b.depaginate(ctx, "repositories/"+url.PathEscape(b.ID.Owner), func(values json.RawMessage) error {
+		var repos []*bitbucketRepo
+		if err := json.Unmarshal(values, &repos); err != nil {
+			return err
+		}
+		result = append(result, repos...)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var repos []*GitRepository
+	for _, repo := range result {
+		repos = append(repos, fromBitbucketRepo(repo))
+	}
+	return repos, nil
+}
+
+func fromBitbucketRepo(repo *bitbucketRepo) *GitRepository {
+	owner := ""
+	if repo.Owner != nil {
+		owner = fromBitbucketUser(repo.Owner).Login
+	}
+
+	gitrepo := &GitRepository{
+		Name:        repo.Slug,
+		Description: repo.Description,
+		Owner:       owner,
+		Fork:        repo.Parent != nil,
+		Empty:       repo.MainBranch == nil,
+		PID:         hashPID(repo.UUID),
+	}
+	for _, link := range repo.Links.Clone {
+		switch link.Name {
+		case "https":
+			gitrepo.CloneURL = link.Href
+		case "ssh":
+			gitrepo.SSHURL = link.Href
+		}
+	}
+	return gitrepo
+}
+
+// GetIssues retrieves the issues of a Bitbucket repository, or none when its issue tracker is disabled
+func (b *BitbucketProvider) GetIssues(ctx context.Context, pid int, repo string) ([]*GitIssue, error) {
+	var result []*bitbucketIssue
+	err := b.depaginate(ctx, b.repoPath(repo)+"/issues", func(values json.RawMessage) error {
+		var issues []*bitbucketIssue
+		if err := json.Unmarshal(values, &issues); err != nil {
+			return err
+		}
+		result = append(result, issues...)
+		return nil
+	})
+
+	if isNotFound(err) {
+		return []*GitIssue{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []*GitIssue
+	for _, issue := range result {
+		gitissue := fromBitbucketIssue(issue)
+		gitissue.Repo = repo
+		gitissue.PID = pid
+		issues = append(issues, gitissue)
+	}
+	return issues, nil
+}
+
+// GetIssuesUpdatedAfter retrieves every issue of a Bitbucket repository, which syncs them again unchanged
+func (b *BitbucketProvider) GetIssuesUpdatedAfter(ctx context.Context, pid int, repo string, since time.Time) ([]*GitIssue, error) {
+	return b.GetIssues(ctx, pid, repo)
+}
+
+func fromBitbucketIssue(issue *bitbucketIssue) *GitIssue {
+	labels := []GitLabel{}
+	if issue.Component != nil {
+		labels = append(labels, GitLabel{Name: issue.Component.Name})
+	}
+
+	assignees := []GitUser{}
+	if issue.Assignee != nil {
+		assignees = append(assignees, *fromBitbucketUser(issue.Assignee))
+	}
+
+	return &GitIssue{
+		Number:    issue.ID,
+		Title:     issue.Title,
+		Body:      issue.Content.Raw,
+		State:     fromBitbucketState(issue.State),
+		Labels:    labels,
+		User:      fromBitbucketUser(issue.Reporter),
+		Assignees: assignees,
+	}
+}
+
+// fromBitbucketState maps Bitbucket's workflow states onto open and closed
+func fromBitbucketState(state string) string {
+	switch state {
+	case "new", "open", "on hold":
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+func fromBitbucketUser(user *bitbucketUser) *GitUser {
+	if user == nil {
+		return &GitUser{}
+	}
+	login := user.Nickname
+	if login == "" {
+		login = user.Username
+	}
+	return &GitUser{
+		Login: login,
+		Name:  user.DisplayName,
+	}
+}
+
+// GetComments retrieves the comments of a Bitbucket issue, skipping the empty comments left by state changes
+func (b *BitbucketProvider) GetComments(ctx context.Context, pid, issueNum int, repo string) ([]*GitIssueComment, error) {
+	var list []*bitbucketComment
+	path := fmt.Sprintf("%s/issues/%d/comments", b.repoPath(repo), issueNum)
+	err := b.depaginate(ctx, path, func(values json.RawMessage) error {
+		var comments []*bitbucketComment
+		if err := json.Unmarshal(values, &comments); err != nil {
+			return err
+		}
+		list = append(list, comments...)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var comments []*GitIssueComment
+	for _, comment := range list {
+		if strings.TrimSpace(comment.Content.Raw) == "" {
+			continue
+		}
+		updatedAt := comment.CreatedOn
+		if comment.UpdatedOn != nil {
+			updatedAt = *comment.UpdatedOn
+		}
+		comments = append(comments, &GitIssueComment{
+			ID:        comment.ID,
+			Repo:      repo,
+			IssueNum:  issueNum,
+			User:      *fromBitbucketUser(comment.User),
+			Body:      comment.Content.Raw,
+			CreatedAt: comment.CreatedOn,
+			UpdatedAt: updatedAt,
+		})
+	}
+	return comments, nil
+}
+
+// GetLabels retrieves the issue tracker components of a Bitbucket repository as labels
+func (b *BitbucketProvider) GetLabels(ctx context.Context, pid int, repo string) ([]*GitLabel, error) {
+	var list []*bitbucketComponent
+	err := b.depaginate(ctx, b.repoPath(repo)+"/components", func(values json.RawMessage) error {
+		var components []*bitbucketComponent
+		if err := json.Unmarshal(values, &components); err != nil {
+			return err
+		}
+		list = append(list, components...)
+		return nil
+	})
+
+	if isNotFound(err) {
+		return []*GitLabel{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var labels []*GitLabel
+	for _, component := range list {
+		labels = append(labels, &GitLabel{
+			Repo: repo,
+			Name: component.Name,
+		})
+	}
+	return labels, nil
+}
+
+// GetRefs retrieves the branches and tags of a Bitbucket repository along with the commits they point at
+func (b *BitbucketProvider) GetRefs(ctx context.Context, pid int, repo string) ([]*GitRef, error) {
+	var refs []*GitRef
+	for _, kind := range []string{"branches", "tags"} {
+		tag := kind == "tags"
+		err := b.depaginate(ctx, b.repoPath(repo)+"/refs/"+kind, func(values json.RawMessage) error {
+			var page []*bitbucketRef
+			if err := json.Unmarshal(values, &page); err != nil {
+				return err
+			}
+			for _, ref := range page {
+				refs = append(refs, &GitRef{Name: ref.Name, Tag: tag, SHA: ref.Target.Hash})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s of %s due to: %v", kind, repo, err)
+		}
+	}
+	return refs, nil
+}
+
+// GetPullRequests returns no pull requests since reading them from Bitbucket Cloud is not supported
+func (b *BitbucketProvider) GetPullRequests(ctx context.Context, pid int, repo string) ([]*GitPullRequest, error) {
+	return []*GitPullRequest{}, nil
+}
+
+// GetReviewComments returns no pull request comments since reading pull requests from Bitbucket Cloud is not supported
+func (b *BitbucketProvider) GetReviewComments(ctx context.Context, pid, pullNum int, repo string) ([]*GitReviewComment, error) {
+	return []*GitReviewComment{}, nil
+}
+
+// GetAuth returns a string with a user's api authentication token
+func (b *BitbucketProvider) GetAuth() *auth.ID {
+	return b.ID
+}
+
+// CreateRepository is not supported since Bitbucket Cloud is only a migration source
+func (b *BitbucketProvider) CreateRepository(ctx context.Context, repo *GitRepository) (*GitRepository, error) {
+	return nil, fmt.Errorf("bitbucket CreateRepository not supported")
+}
+
+// DeleteRepository is not supported since Bitbucket Cloud is only a migration source
+func (b *BitbucketProvider) DeleteRepository(ctx context.Context, repo *GitRepository) error {
+	return fmt.Errorf("bitbucket DeleteRepository not supported")
+}
+
+// ArchiveRepository is not supported since Bitbucket Cloud is only a migration source
+func (b *BitbucketProvider) ArchiveRepository(ctx context.Context, repo *GitRepository) error {
+	return fmt.Errorf("bitbucket ArchiveRepository not supported")
+}
+
+// UpdateIssue is not supported since Bitbucket Cloud is only a migration source
+func (b *BitbucketProvider) UpdateIssue(ctx context.Context, issue *GitIssue) error {
+	return fmt.Errorf("bitbucket UpdateIssue not supported")
+}
+
+// UpdateLabel is not supported since Bitbucket Cloud is only a migration source
+func (b *BitbucketProvider) UpdateLabel(ctx context.Context, label *GitLabel) error {
+	return fmt.Errorf("bitbucket UpdateLabel not supported")
+}
+
+// DeleteLabel is not supported since Bitbucket Cloud is only a migration source
+func (b *BitbucketProvider) DeleteLabel(ctx context.Context, label *GitLabel) error {
+	return fmt.Errorf("bitbucket DeleteLabel not supported")
+}
+
+// DeleteIssue is not supported since Bitbucket Cloud is only a migration source
+func (b *BitbucketProvider) DeleteIssue(ctx context.Context, issue *GitIssue) error {
+	return fmt.Errorf("bitbucket DeleteIssue not supported")
+}
+
+// MigrateRepo is not supported since Bitbucket Cloud is only a migration source
+func (b *BitbucketProvider) MigrateRepo(ctx context.Context, repo *GitRepository, token string) (string, error) {
+	return "", fmt.Errorf("bitbucket MigrateRepo not supported")
+}
+
+// GetImportProgress is not supported since Bitbucket Cloud is only a migration source
+func (b *BitbucketProvider) GetImportProgress(ctx context.Context, repo string) (string, error) {
+	return "", fmt.Errorf("bitbucket GetImportProgress not supported")
+}
+
+// CreateIssue is not supported since Bitbucket Cloud is only a migration source
+func (b *BitbucketProvider) CreateIssue(ctx context.Context, issue *GitIssue) (*GitIssue, error) {
+	return nil, fmt.Errorf("bitbucket CreateIssue not supported")
+}
+
+// CreateIssueComment is not supported since Bitbucket Cloud is only a migration source
+func (b *BitbucketProvider) CreateIssueComment(ctx context.Context, issueNum int, comment *GitIssueComment) error {
+	return fmt.Errorf("bitbucket CreateIssueComment not supported")
+}
+
+// CreateLabel is not supported since Bitbucket Cloud is only a migration source
+func (b *BitbucketProvider) CreateLabel(ctx context.Context, label *GitLabel) (*GitLabel, error) {
+	return nil, fmt.Errorf("bitbucket CreateLabel not supported")
+}
+
+// CreatePullRequest is not supported since Bitbucket Cloud is only a migration source
+func (b *BitbucketProvider) CreatePullRequest(ctx context.Context, pr *GitPullRequest) (*GitPullRequest, error) {
+	return nil, fmt.Errorf("bitbucket CreatePullRequest not supported")
+}
+
+// CreateReviewComment is not supported since Bitbucket Cloud is only a migration source
+func (b *BitbucketProvider) CreateReviewComment(ctx context.Context, pr *GitPullRequest, comment *GitReviewComment) error {
+	return fmt.Errorf("bitbucket CreateReviewComment not supported")
+}
+
+func (b *BitbucketProvider) repoPath(repo string) string {
+	return fmt.Sprintf("repositories/%s/%s", url.PathEscape(b.ID.Owner), url.PathEscape(repo))
+}
+
+// depaginate follows the next URL of each Bitbucket page, passing each page's values to the closure
+func (b *BitbucketProvider) depaginate(ctx context.Context, path string, closure func(json.RawMessage) error) error {
+	query := url.Values{}
+	query.Set("pagelen", "50")
+
+	for path != "" {
+		var page bitbucketPage
+		if _, err := b.Client.do(ctx, http.MethodGet, path, query, nil, &page); err != nil {
+			return err
+		}
+		if err := closure(page.Values); err != nil {
+			return err
+		}
+		// The next URL already carries every query parameter
+		path, query = page.Next, nil
+	}
+	return nil
+}
+
+// GrantAccess is not supported since Bitbucket Cloud is only a migration source
+func (b *BitbucketProvider) GrantAccess(ctx context.Context, repo *GitRepository, members []*GitMember) error {
+	return fmt.Errorf("bitbucket GrantAccess not supported")
+}
+
+// GetMembers is not supported since reading the members of Bitbucket Cloud repositories is not implemented
+func (b *BitbucketProvider) GetMembers(ctx context.Context, repo *GitRepository) ([]*GitMember, error) {
+	return nil, fmt.Errorf("bitbucket GetMembers not supported")
+}
@@ -0,0 +1,494 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 Artur Sak
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/artur-sak13/gitmv/auth"
+)
+
+// BitbucketServerProvider implements the read side of the provider interface for Bitbucket Server and Data Center
+// Bitbucket Server has no issue tracker, so pull requests are read as issues and their comments as issue comments
+type BitbucketServerProvider struct {
+	Client *restClient
+	ID     *auth.ID
+
+	reposMu sync.RWMutex
+	repos   map[int]*bitbucketServerRepo
+}
+
+type (
+	bitbucketServerPage struct {
+		Values        json.RawMessage `json:"values"`
+		IsLastPage    bool            `json:"isLastPage"`
+		NextPageStart int             `json:"nextPageStart"`
+	}
+
+	bitbucketServerLink struct {
+		Href string `json:"href"`
+		Name string `json:"name"`
+	}
+
+	bitbucketServerRepo struct {
+		ID          int    `json:"id"`
+		Slug        string `json:"slug"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Archived    bool   `json:"archived"`
+		Project     struct {
+			Key string `json:"key"`
+		} `json:"project"`
+		Origin *struct {
+			ID int `json:"id"`
+		} `json:"origin"`
+		Links struct {
+			Clone []bitbucketServerLink `json:"clone"`
+		} `json:"links"`
+	}
+
+	bitbucketServerRef struct {
+		DisplayID    string `json:"displayId"`
+		LatestCommit string `json:"latestCommit"`
+	}
+
+	bitbucketServerUser struct {
+		Name         string `json:"name"`
+		DisplayName  string `json:"displayName"`
+		EmailAddress string `json:"emailAddress"`
+	}
+
+	bitbucketServerPullRequest struct {
+		ID          int    `json:"id"`
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		State       string `json:"state"`
+		Author      struct {
+			User bitbucketServerUser `json:"user"`
+		} `json:"author"`
+		Reviewers []struct {
+			User bitbucketServerUser `json:"user"`
+		} `json:"reviewers"`
+	}
+
+	bitbucketServerComment struct {
+		ID          int                       `json:"id"`
+		Text        string                    `json:"text"`
+		Author      bitbucketServerUser       `json:"author"`
+		CreatedDate int64                     `json:"createdDate"`
+		UpdatedDate int64                     `json:"updatedDate"`
+		Comments    []*bitbucketServerComment `json:"comments"`
+	}
+
+	bitbucketServerActivity struct {
+		Action  string                  `json:"action"`
+		Comment *bitbucketServerComment `json:"comment"`
+	}
+)
+
+// NewBitbucketServerProvider creates a new Bitbucket Server client which implements the provider interface
+func NewBitbucketServerProvider(id *auth.ID) (GitProvider, error) {
+	return WithBitbucketServerClient(newHTTPClient(), id)
+}
+
+// WithBitbucketServerClient creates a new GitProvider with an HTTP client
+// This function is exported to create mock clients in tests
+func WithBitbucketServerClient(client *http.Client, id *auth.ID) (GitProvider, error) {
+	if id.URL == "" {
+		return nil, fmt.Errorf("bitbucket server url cannot be empty")
+	}
+
+	header := make(http.Header)
+	if id.Token != "" {
+		header.Set("Authorization", "Bearer "+id.Token)
+	}
+
+	rest, err := newRESTClient(client, strings.TrimSuffix(id.URL, "/")+"/rest/api/1.0", header)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BitbucketServerProvider{
+		Client: rest,
+		ID:     id,
+		repos:  make(map[int]*bitbucketServerRepo),
+	}, nil
+}
+
+// GetRepositories retrieves the repositories of a Bitbucket project, or every repository visible to the user when no owner is set
+func (b *BitbucketServerProvider) GetRepositories(ctx context.Context) ([]*GitRepository, error) {
+	path := "repos"
+	if b.ID.Owner != "" {
+		path = fmt.Sprintf("projects/%s/repos", url.PathEscape(b.ID.Owner))
+	}
+
+	var result []*bitbucketServerRepo
+	err := b.depaginate(ctx, path, nil, func(values json.RawMessage) error {
+		var repos []*bitbucketServerRepo
+		if err := json.Unmarshal(values, &repos); err != nil {
+			return err
+		}
+		result = append(result, repos...)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var repos []*GitRepository
+	for _, repo := range result {
+		b.reposMu.Lock()
+		b.repos[repo.ID] = repo
+		b.reposMu.Unlock()
+
+		gitrepo := fromBitbucketServerRepo(repo)
+		if gitrepo.Empty, err = b.isEmpty(ctx, repo); err != nil {
+			return nil, err
+		}
+		repos = append(repos, gitrepo)
+	}
+	return repos, nil
+}
+
+func fromBitbucketServerRepo(repo *bitbucketServerRepo) *GitRepository {
+	gitrepo := &GitRepository{
+		Name:        repo.Slug,
+		Description: repo.Description,
+		Owner:       repo.Project.Key,
+		Archived:    repo.Archived,
+		Fork:        repo.Origin != nil,
+		PID:         repo.ID,
+	}
+	for _, link := range repo.Links.Clone {
+		switch link.Name {
+		case "http", "https":
+			gitrepo.CloneURL = link.Href
+		case "ssh":
+			gitrepo.SSHURL = link.Href
+		}
+	}
+	return gitrepo
+}
+
+// isEmpty checks whether a repository has no branches, which is the case until it has commits
+func (b *BitbucketServerProvider) isEmpty(ctx context.Context, repo *bitbucketServerRepo) (bool, error) {
+	opts := url.Values{}
+	opts.Set("limit", "1")
+
+	var page bitbucketServerPage
+	if _, err := b.Client.do(ctx, http.MethodGet, b.repoPath(repo.Project.Key, repo.Slug)+"/branches", opts, nil, &page); err != nil {
+		return false, fmt.Errorf("failed to list branches of %s/%s due to: %v", repo.Project.Key, repo.Slug, err)
+	}
+	var branches []json.RawMessage
+	if err := json.Unmarshal(page.Values, &branches); err != nil {
+		return false, fmt.Errorf("failed to list branches of %s/%s due to: %v", repo.Project.Key, repo.Slug, err)
+	}
+	return len(branches) == 0, nil
+}
+
+// GetIssues retrieves the pull requests of a Bitbucket repository as issues
+func (b *BitbucketServerProvider) GetIssues(ctx context.Context, pid int, repo string) ([]*GitIssue, error) {
+	opts := url.Values{}
+	opts.Set("state", "ALL")
+
+	var result []*bitbucketServerPullRequest
+	err := b.depaginate(ctx, b.lookupRepoPath(pid, repo)+"/pull-requests", opts, func(values json.RawMessage) error {
+		var prs []*bitbucketServerPullRequest
+		if err := json.Unmarshal(values, &prs); err != nil {
+			return err
+		}
+		result = append(result, prs...)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []*GitIssue
+	for _, pr := range result {
+		gitissue := fromBitbucketServerPullRequest(pr)
+		gitissue.Repo = repo
+		gitissue.PID = pid
+		issues = append(issues, gitissue)
+	}
+	return issues, nil
+}
+
+// GetIssuesUpdatedAfter retrieves every pull request of a Bitbucket repository, which syncs them again unchanged
+func (b *BitbucketServerProvider) GetIssuesUpdatedAfter(ctx context.Context, pid int, repo string, since time.Time) ([]*GitIssue, error) {
+	return b.GetIssues(ctx, pid, repo)
+}
+
+func fromBitbucketServerPullRequest(pr *bitbucketServerPullRequest) *GitIssue {
+	state := "closed"
+	if pr.State == "OPEN" {
+		state = "open"
+	}
+
+	assignees := []GitUser{}
+	for _, reviewer := range pr.Reviewers {
+		assignees = append(assignees, *fromBitbucketServerUser(&reviewer.User))
+	}
+
+	return &GitIssue{
+		Number:    pr.ID,
+		Title:     pr.Title,
+		Body:      pr.Description,
+		State:     state,
+		Labels:    []GitLabel{},
+		User:      fromBitbucketServerUser(&pr.Author.User),
+		Assignees: assignees,
+	}
+}
+
+func fromBitbucketServerUser(user *bitbucketServerUser) *GitUser {
+	return &GitUser{
+		Login: user.Name,
+		Name:  user.DisplayName,
+		Email: user.EmailAddress,
+	}
+}
+
+// GetComments retrieves the comments and replies of a Bitbucket pull request in the order they were made
+func (b *BitbucketServerProvider) GetComments(ctx context.Context, pid, issueNum int, repo string) ([]*GitIssueComment, error) {
+	path := fmt.Sprintf("%s/pull-requests/%d/activities", b.lookupRepoPath(pid, repo), issueNum)
+
+	var activities []*bitbucketServerActivity
+	err := b.depaginate(ctx, path, nil, func(values json.RawMessage) error {
+		var page []*bitbucketServerActivity
+		if err := json.Unmarshal(values, &page); err != nil {
+			return err
+		}
+		activities = append(activities, page...)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Activities are returned newest first
+	var comments []*GitIssueComment
+	for i := len(activities) - 1; i >= 0; i-- {
+		activity := activities[i]
+		if activity.Action != "COMMENTED" || activity.Comment == nil {
+			continue
+		}
+		comments = append(comments, fromBitbucketServerComments(repo, issueNum, activity.Comment)...)
+	}
+	return comments, nil
+}
+
+// fromBitbucketServerComments flattens a comment thread into its comment followed by its replies
+func fromBitbucketServerComments(repo string, issueNum int, comment *bitbucketServerComment) []*GitIssueComment {
+	result := []*GitIssueComment{{
+		ID:        comment.ID,
+		Repo:      repo,
+		IssueNum:  issueNum,
+		User:      *fromBitbucketServerUser(&comment.Author),
+		Body:      comment.Text,
+		CreatedAt: fromBitbucketServerTime(comment.CreatedDate),
+		UpdatedAt: fromBitbucketServerTime(comment.UpdatedDate),
+	}}
+	for _, reply := range comment.Comments {
+		result = append(result, fromBitbucketServerComments(repo, issueNum, reply)...)
+	}
+	return result
+}
+
+func fromBitbucketServerTime(millis int64) time.Time {
+	return time.Unix(0, millis*int64(time.Millisecond)).UTC()
+}
+
+// GetLabels returns no labels since Bitbucket Server has no issue labels
+func (b *BitbucketServerProvider) GetLabels(ctx context.Context, pid int, repo string) ([]*GitLabel, error) {
+	return []*GitLabel{}, nil
+}
+
+// GetRefs retrieves the branches and tags of a Bitbucket Server repository along with the commits they point at
+func (b *BitbucketServerProvider) GetRefs(ctx context.Context, pid int, repo string) ([]*GitRef, error) {
+	var refs []*GitRef
+	for _, kind := range []string{"branches", "tags"} {
+		tag := kind == "tags"
+		err := b.depaginate(ctx, b.lookupRepoPath(pid, repo)+"/"+kind, nil, func(values json.RawMessage) error {
+			var page []*bitbucketServerRef
+			if err := json.Unmarshal(values, &page); err != nil {
+				return err
+			}
+			for _, ref := range page {
+				refs = append(refs, &GitRef{Name: ref.DisplayID, Tag: tag, SHA: ref.LatestCommit})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s of %s due to: %v", kind, repo, err)
+		}
+	}
+	return refs, nil
+}
+
+// GetPullRequests returns no pull requests since Bitbucket Server pull requests are migrated as issues
+func (b *BitbucketServerProvider) GetPullRequests(ctx context.Context, pid int, repo string) ([]*GitPullRequest, error) {
+	return []*GitPullRequest{}, nil
+}
+
+// GetReviewComments returns no pull request comments since Bitbucket Server pull requests are migrated as issues
+func (b *BitbucketServerProvider) GetReviewComments(ctx context.Context, pid, pullNum int, repo string) ([]*GitReviewComment, error) {
+	return []*GitReviewComment{}, nil
+}
+
+// GetAuth returns a string with a user's api authentication token
+func (b *BitbucketServerProvider) GetAuth() *auth.ID {
+	return b.ID
+}
+
+// CreateRepository is not supported since Bitbucket Server is only a migration source
+func (b *BitbucketServerProvider) CreateRepository(ctx context.Context, repo *GitRepository) (*GitRepository, error) {
+	return nil, fmt.Errorf("bitbucket server CreateRepository not supported")
+}
+
+// DeleteRepository is not supported since Bitbucket Server is only a migration source
+func (b *BitbucketServerProvider) DeleteRepository(ctx context.Context, repo *GitRepository) error {
+	return fmt.Errorf("bitbucket server DeleteRepository not supported")
+}
+
+// ArchiveRepository is not supported since Bitbucket Server is only a migration source
+func (b *BitbucketServerProvider) ArchiveRepository(ctx context.Context, repo *GitRepository) error {
+	return fmt.Errorf("bitbucket server ArchiveRepository not supported")
+}
+
+// UpdateIssue is not supported since Bitbucket Server is only a migration source
+func (b *BitbucketServerProvider) UpdateIssue(ctx context.Context, issue *GitIssue) error {
+	return fmt.Errorf("bitbucket server UpdateIssue not supported")
+}
+
+// UpdateLabel is not supported since Bitbucket Server is only a migration source
+func (b *BitbucketServerProvider) UpdateLabel(ctx context.Context, label *GitLabel) error {
+	return fmt.Errorf("bitbucket server UpdateLabel not supported")
+}
+
+// DeleteLabel is not supported since Bitbucket Server is only a migration source
+func (b *BitbucketServerProvider) DeleteLabel(ctx context.Context, label *GitLabel) error {
+	return fmt.Errorf("bitbucket server DeleteLabel not supported")
+}
+
+// DeleteIssue is not supported since Bitbucket Server is only a migration source
+func (b *BitbucketServerProvider) DeleteIssue(ctx context.Context, issue *GitIssue) error {
+	return fmt.Errorf("bitbucket server DeleteIssue not supported")
+}
+
+// MigrateRepo is not supported since Bitbucket Server is only a migration source
+func (b *BitbucketServerProvider) MigrateRepo(ctx context.Context, repo *GitRepository, token string) (string, error) {
+	return "", fmt.Errorf("bitbucket server MigrateRepo not supported")
+}
+
+// GetImportProgress is not supported since Bitbucket Server is only a migration source
+func (b *BitbucketServerProvider) GetImportProgress(ctx context.Context, repo string) (string, error) {
+	return "", fmt.Errorf("bitbucket server GetImportProgress not supported")
+}
+
+// CreateIssue is not supported since Bitbucket Server is only a migration source
+func (b *BitbucketServerProvider) CreateIssue(ctx context.Context, issue *GitIssue) (*GitIssue, error) {
+	return nil, fmt.Errorf("bitbucket server CreateIssue not supported")
+}
+
+// CreateIssueComment is not supported since Bitbucket Server is only a migration source
+func (b *BitbucketServerProvider) CreateIssueComment(ctx context.Context, issueNum int, comment *GitIssueComment) error {
+	return fmt.Errorf("bitbucket server CreateIssueComment not supported")
+}
+
+// CreateLabel is not supported since Bitbucket Server is only a migration source
+func (b *BitbucketServerProvider) CreateLabel(ctx context.Context, label *GitLabel) (*GitLabel, error) {
+	return nil, fmt.Errorf("bitbucket server CreateLabel not supported")
+}
+
+// CreatePullRequest is not supported since Bitbucket Server is only a migration source
+func (b *BitbucketServerProvider) CreatePullRequest(ctx context.Context, pr *GitPullRequest) (*GitPullRequest, error) {
+	return nil, fmt.Errorf("bitbucket server CreatePullRequest not supported")
+}
+
+// CreateReviewComment is not supported since Bitbucket Server is only a migration source
+func (b *BitbucketServerProvider) CreateReviewComment(ctx context.Context, pr *GitPullRequest, comment *GitReviewComment) error {
+	return fmt.Errorf("bitbucket server CreateReviewComment not supported")
+}
+
+// lookupRepoPath finds the project of a repository listed by GetRepositories, falling back to the owner's project
+func (b *BitbucketServerProvider) lookupRepoPath(pid int, repo string) string {
+	b.reposMu.RLock()
+	defer b.reposMu.RUnlock()
+
+	if cached, ok := b.repos[pid]; ok {
+		return b.repoPath(cached.Project.Key, cached.Slug)
+	}
+	return b.repoPath(b.ID.Owner, repo)
+}
+
+func (b *BitbucketServerProvider) repoPath(project, slug string) string {
+	return fmt.Sprintf("projects/%s/repos/%s", url.PathEscape(project), url.PathEscape(slug))
+}
+
+// depaginate follows Bitbucket's start/limit paging until the last page, passing each page's values to the closure
+func (b *BitbucketServerProvider) depaginate(ctx context.Context, path string, query url.Values, closure func(json.RawMessage) error) error {
+	opts := url.Values{}
+	for key, values := range query {
+		opts[key] = values
+	}
+	opts.Set("limit", "100")
+
+	start := 0
+	for {
+		opts.Set("start", strconv.Itoa(start))
+
+		var page bitbucketServerPage
+		if _, err := b.Client.do(ctx, http.MethodGet, path, opts, nil, &page); err != nil {
+			return err
+		}
+		if err := closure(page.Values); err != nil {
+			return err
+		}
+		if page.IsLastPage || page.NextPageStart <= start {
+			return nil
+		}
+		start = page.NextPageStart
+	}
+}
+
+// GrantAccess is not supported since Bitbucket Server is only a migration source
+func (b *BitbucketServerProvider) GrantAccess(ctx context.Context, repo *GitRepository, members []*GitMember) error {
+	return fmt.Errorf("bitbucket server GrantAccess not supported")
+}
+
+// GetMembers is not supported since reading the members of Bitbucket Server repositories is not implemented
+func (b *BitbucketServerProvider) GetMembers(ctx context.Context, repo *GitRepository) ([]*GitMember, error) {
+	return nil, fmt.Errorf("bitbucket server GetMembers not supported")
+}
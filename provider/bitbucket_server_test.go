@@ -0,0 +1,209 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/artur-sak13/gitmv/auth"
+)
+
+func setupBitbucketServer(t *testing.T) (*BitbucketServerProvider, *http.ServeMux, func()) {
+	mux := http.NewServeMux()
+
+	apiHandler := http.NewServeMux()
+	apiHandler.Handle("/rest/api/1.0/", http.StripPrefix("/rest/api/1.0", mux))
+	apiHandler.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		t.Errorf("Request URL %s is missing the /rest/api/1.0 prefix", req.URL)
+		http.Error(w, "missing API prefix", http.StatusInternalServerError)
+	})
+
+	server := httptest.NewServer(apiHandler)
+
+	id := auth.NewAuthID(server.URL, "p", "PRJ")
+	prov, err := WithBitbucketServerClient(server.Client(), id)
+	if err != nil {
+		t.Fatalf("WithBitbucketServerClient returned error: %v", err)
+	}
+
+	return prov.(*BitbucketServerProvider), mux, server.Close
+}
+
+func TestBitbucketServer_GetRepositories(t *testing.T) {
+	prov, mux, teardown := setupBitbucketServer(t)
+	defer teardown()
+
+	mux.HandleFunc("/projects/PRJ/repos", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		testHeader(t, r, "Authorization", "Bearer p")
+
+		switch r.URL.Query().Get("start") {
+		case "0":
+			fmt.Fprint(w, `{"isLastPage":false,"nextPageStart":1,"values":[
+				{"id":1,"slug":"r","description":"d","project":{"key":"PRJ"},"links":{"clone":[
+					{"href":"https://bitbucket.example.com/scm/prj/r.git","name":"http"},
+					{"href":"ssh://git@bitbucket.example.com:7999/prj/r.git","name":"ssh"}]}}]}`)
+		case "1":
+			fmt.Fprint(w, `{"isLastPage":true,"values":[{"id":2,"slug":"f","project":{"key":"PRJ"},"origin":{"id":9}}]}`)
+		default:
+			t.Errorf("unexpected start %q", r.URL.Query().Get("start"))
+		}
+	})
+	mux.HandleFunc("/projects/PRJ/repos/r/branches", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"isLastPage":true,"values":[{"displayId":"master","latestCommit":"abc"}]}`)
+	})
+	mux.HandleFunc("/projects/PRJ/repos/f/branches", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"isLastPage":true,"values":[]}`)
+	})
+
+	got, err := prov.GetRepositories(context.Background())
+	if err != nil {
+		t.Errorf("GetRepositories returned error: %v", err)
+	}
+	want := []*GitRepository{
+		{
+			Name:        "r",
+			Description: "d",
+			CloneURL:    "https://bitbucket.example.com/scm/prj/r.git",
+			SSHURL:      "ssh://git@bitbucket.example.com:7999/prj/r.git",
+			Owner:       "PRJ",
+			PID:         1,
+		},
+		{Name: "f", Owner: "PRJ", Fork: true, Empty: true, PID: 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetRepositories = %+v, want %+v", got, want)
+	}
+}
+
+func TestBitbucketServer_GetRepositoriesError(t *testing.T) {
+	prov, mux, teardown := setupBitbucketServer(t)
+	defer teardown()
+
+	mux.HandleFunc("/projects/PRJ/repos", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"isLastPage":true,"values":[{"id":1,"slug":"r","project":{"key":"PRJ"}}]}`)
+	})
+	mux.HandleFunc("/projects/PRJ/repos/r/branches", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"errors":[{"message":"Authentication failed"}]}`, http.StatusUnauthorized)
+	})
+
+	if _, err := prov.GetRepositories(context.Background()); err == nil {
+		t.Errorf("GetRepositories treated a failed branch listing as an empty repository")
+	}
+}
+
+func TestBitbucketServer_GetIssues(t *testing.T) {
+	prov, mux, teardown := setupBitbucketServer(t)
+	defer teardown()
+
+	mux.HandleFunc("/projects/PRJ/repos/r/pull-requests", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		if got := r.URL.Query().Get("state"); got != "ALL" {
+			t.Errorf("state = %q, want ALL", got)
+		}
+		fmt.Fprint(w, `{"isLastPage":true,"values":[
+			{"id":3,"title":"t","description":"b","state":"MERGED","author":{"user":{"name":"u","displayName":"U","emailAddress":"u@example.com"}},"reviewers":[{"user":{"name":"v"}}]},
+			{"id":4,"title":"o","state":"OPEN","author":{"user":{"name":"u"}}}]}`)
+	})
+
+	got, err := prov.GetIssues(context.Background(), 1, "r")
+	if err != nil {
+		t.Errorf("GetIssues returned error: %v", err)
+	}
+	want := []*GitIssue{
+		{
+			Repo:      "r",
+			PID:       1,
+			Number:    3,
+			Title:     "t",
+			Body:      "b",
+			State:     "closed",
+			Labels:    []GitLabel{},
+			User:      &GitUser{Login: "u", Name: "U", Email: "u@example.com"},
+			Assignees: []GitUser{{Login: "v"}},
+		},
+		{
+			Repo:      "r",
+			PID:       1,
+			Number:    4,
+			Title:     "o",
+			State:     "open",
+			Labels:    []GitLabel{},
+			User:      &GitUser{Login: "u"},
+			Assignees: []GitUser{},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetIssues = %+v, want %+v", got, want)
+	}
+}
+
+func TestBitbucketServer_GetComments(t *testing.T) {
+	prov, mux, teardown := setupBitbucketServer(t)
+	defer teardown()
+
+	mux.HandleFunc("/projects/PRJ/repos/r/pull-requests/3/activities", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `{"isLastPage":true,"values":[
+			{"action":"APPROVED"},
+			{"action":"COMMENTED","comment":{"id":2,"text":"second","author":{"name":"v"},"createdDate":1551600000000,"updatedDate":1551600000000}},
+			{"action":"COMMENTED","comment":{"id":1,"text":"first","author":{"name":"u"},"createdDate":1551427200000,"updatedDate":1551427200000,
+				"comments":[{"id":5,"text":"reply","author":{"name":"v"},"createdDate":1551513600000,"updatedDate":1551513600000}]}}]}`)
+	})
+
+	got, err := prov.GetComments(context.Background(), 1, 3, "r")
+	if err != nil {
+		t.Errorf("GetComments returned error: %v", err)
+	}
+
+	first := time.Date(2019, 3, 1, 8, 0, 0, 0, time.UTC)
+	reply := time.Date(2019, 3, 2, 8, 0, 0, 0, time.UTC)
+	second := time.Date(2019, 3, 3, 8, 0, 0, 0, time.UTC)
+	want := []*GitIssueComment{
+		{ID: 1, Repo: "r", IssueNum: 3, User: GitUser{Login: "u"}, Body: "first", CreatedAt: first, UpdatedAt: first},
+		{ID: 5, Repo: "r", IssueNum: 3, User: GitUser{Login: "v"}, Body: "reply", CreatedAt: reply, UpdatedAt: reply},
+		{ID: 2, Repo: "r", IssueNum: 3, User: GitUser{Login: "v"}, Body: "second", CreatedAt: second, UpdatedAt: second},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetComments = %+v, want %+v", got, want)
+	}
+}
+
+func TestBitbucketServer_GetRefs(t *testing.T) {
+	prov, mux, teardown := setupBitbucketServer(t)
+	defer teardown()
+
+	mux.HandleFunc("/projects/PRJ/repos/r/branches", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `{"isLastPage":true,"values":[{"displayId":"master","latestCommit":"abc"}]}`)
+	})
+	mux.HandleFunc("/projects/PRJ/repos/r/tags", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `{"isLastPage":true,"values":[{"displayId":"v1","latestCommit":"def"}]}`)
+	})
+
+	got, err := prov.GetRefs(context.Background(), 1, "r")
+	if err != nil {
+		t.Errorf("GetRefs returned error: %v", err)
+	}
+	want := []*GitRef{
+		{Name: "master", SHA: "abc"},
+		{Name: "v1", Tag: true, SHA: "def"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetRefs = %+v, want %+v", got, want)
+	}
+}
+
+func TestBitbucketServer_CreateRepository(t *testing.T) {
+	prov, _, teardown := setupBitbucketServer(t)
+	defer teardown()
+
+	if _, err := prov.CreateRepository(context.Background(), &GitRepository{Name: "r"}); err == nil {
+		t.Errorf("CreateRepository returned no error for a source only provider")
+	}
+}
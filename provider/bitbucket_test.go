@@ -0,0 +1,203 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/artur-sak13/gitmv/auth"
+)
+
+func setupBitbucket(t *testing.T, token string) (*BitbucketProvider, *http.ServeMux, string, func()) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	id := auth.NewAuthID(server.URL, token, "w")
+	prov, err := WithBitbucketClient(server.Client(), id)
+	if err != nil {
+		t.Fatalf("WithBitbucketClient returned error: %v", err)
+	}
+
+	return prov.(*BitbucketProvider), mux, server.URL, server.Close
+}
+
+func TestBitbucket_GetRepositories(t *testing.T) {
+	prov, mux, serverURL, teardown := setupBitbucket(t, "p")
+	defer teardown()
+
+	mux.HandleFunc("/repositories/w", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		testHeader(t, r, "Authorization", "Bearer p")
+
+		if r.URL.Query().Get("page") == "2" {
+			fmt.Fprint(w, `{"values":[{"uuid":"{2}","slug":"f","parent":{"full_name":"x/f"},"owner":{"nickname":"w"}}]}`)
+			return
+		}
+		fmt.Fprintf(w, `{"next":"%s/repositories/w?pagelen=50&page=2","values":[
+			{"uuid":"{1}","slug":"r","description":"d","mainbranch":{"name":"master"},"owner":{"nickname":"w"},"links":{"clone":[
+				{"href":"https://bitbucket.org/w/r.git","name":"https"},
+				{"href":"git@bitbucket.org:w/r.git","name":"ssh"}]}}]}`, serverURL)
+	})
+
+	got, err := prov.GetRepositories(context.Background())
+	if err != nil {
+		t.Errorf("GetRepositories returned error: %v", err)
+	}
+	want := []*GitRepository{
+		{
+			Name:        "r",
+			Description: "d",
+			CloneURL:    "https://bitbucket.org/w/r.git",
+			SSHURL:      "git@bitbucket.org:w/r.git",
+			Owner:       "w",
+			PID:         hashPID("{1}"),
+		},
+		{Name: "f", Owner: "w", Fork: true, Empty: true, PID: hashPID("{2}")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetRepositories = %+v, want %+v", got, want)
+	}
+}
+
+func TestBitbucket_AppPassword(t *testing.T) {
+	prov, mux, _, teardown := setupBitbucket(t, "u:secret")
+	defer teardown()
+
+	mux.HandleFunc("/repositories/w", func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "u" || password != "secret" {
+			t.Errorf("BasicAuth = %q, %q, want u, secret", user, password)
+		}
+		fmt.Fprint(w, `{"values":[]}`)
+	})
+
+	if _, err := prov.GetRepositories(context.Background()); err != nil {
+		t.Errorf("GetRepositories returned error: %v", err)
+	}
+}
+
+func TestBitbucket_GetIssues(t *testing.T) {
+	prov, mux, _, teardown := setupBitbucket(t, "p")
+	defer teardown()
+
+	mux.HandleFunc("/repositories/w/r/issues", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `{"values":[
+			{"id":1,"title":"t","content":{"raw":"b"},"state":"resolved","reporter":{"nickname":"u","display_name":"U"},"assignee":{"nickname":"v"},"component":{"name":"api"}},
+			{"id":2,"title":"o","content":{"raw":""},"state":"new","reporter":{"nickname":"u"}}]}`)
+	})
+	mux.HandleFunc("/repositories/w/disabled/issues", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"type":"error","error":{"message":"Repository has no issue tracker."}}`, http.StatusNotFound)
+	})
+
+	got, err := prov.GetIssues(context.Background(), 1, "r")
+	if err != nil {
+		t.Errorf("GetIssues returned error: %v", err)
+	}
+	want := []*GitIssue{
+		{
+			Repo:      "r",
+			PID:       1,
+			Number:    1,
+			Title:     "t",
+			Body:      "b",
+			State:     "closed",
+			Labels:    []GitLabel{{Name: "api"}},
+			User:      &GitUser{Login: "u", Name: "U"},
+			Assignees: []GitUser{{Login: "v"}},
+		},
+		{
+			Repo:      "r",
+			PID:       1,
+			Number:    2,
+			Title:     "o",
+			State:     "open",
+			Labels:    []GitLabel{},
+			User:      &GitUser{Login: "u"},
+			Assignees: []GitUser{},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetIssues = %+v, want %+v", got, want)
+	}
+
+	got, err = prov.GetIssues(context.Background(), 2, "disabled")
+	if err != nil {
+		t.Errorf("GetIssues returned error for a disabled issue tracker: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("GetIssues = %+v, want no issues", got)
+	}
+}
+
+func TestBitbucket_GetComments(t *testing.T) {
+	prov, mux, _, teardown := setupBitbucket(t, "p")
+	defer teardown()
+
+	mux.HandleFunc("/repositories/w/r/issues/1/comments", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `{"values":[
+			{"id":1,"content":{"raw":"c"},"user":{"nickname":"u"},"created_on":"2019-03-01T10:00:00Z","updated_on":null},
+			{"id":2,"content":{"raw":""},"user":{"nickname":"u"},"created_on":"2019-03-02T10:00:00Z"}]}`)
+	})
+
+	got, err := prov.GetComments(context.Background(), 1, 1, "r")
+	if err != nil {
+		t.Errorf("GetComments returned error: %v", err)
+	}
+	created := time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC)
+	want := []*GitIssueComment{
+		{ID: 1, Repo: "r", IssueNum: 1, User: GitUser{Login: "u"}, Body: "c", CreatedAt: created, UpdatedAt: created},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetComments = %+v, want %+v", got, want)
+	}
+}
+
+func TestBitbucket_GetLabels(t *testing.T) {
+	prov, mux, _, teardown := setupBitbucket(t, "p")
+	defer teardown()
+
+	mux.HandleFunc("/repositories/w/r/components", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `{"values":[{"id":1,"name":"api"},{"id":2,"name":"ui"}]}`)
+	})
+
+	got, err := prov.GetLabels(context.Background(), 1, "r")
+	if err != nil {
+		t.Errorf("GetLabels returned error: %v", err)
+	}
+	want := []*GitLabel{{Repo: "r", Name: "api"}, {Repo: "r", Name: "ui"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetLabels = %+v, want %+v", got, want)
+	}
+}
+
+func TestBitbucket_GetRefs(t *testing.T) {
+	prov, mux, _, teardown := setupBitbucket(t, "p")
+	defer teardown()
+
+	mux.HandleFunc("/repositories/w/r/refs/branches", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `{"values":[{"name":"master","target":{"hash":"abc"}}]}`)
+	})
+	mux.HandleFunc("/repositories/w/r/refs/tags", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `{"values":[{"name":"v1","target":{"hash":"def"}}]}`)
+	})
+
+	got, err := prov.GetRefs(context.Background(), 1, "r")
+	if err != nil {
+		t.Errorf("GetRefs returned error: %v", err)
+	}
+	want := []*GitRef{
+		{Name: "master", SHA: "abc"},
+		{Name: "v1", Tag: true, SHA: "def"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetRefs = %+v, want %+v", got, want)
+	}
+}
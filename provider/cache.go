@@ -1,25 +1,133 @@
 package provider
 
 import (
+	"context"
 	"sync"
-	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/artur-sak13/gitmv/pool"
 )
 
-// TODO: Pull out generic cache (possibly in GitCache interface)
+// defaultCacheWorkers bounds the repositories cached at once when no limit is given
+const defaultCacheWorkers = 4
 
+// CachedIssue stores an issue along with its comments keyed by source marker
 type CachedIssue struct {
 	Issue *GitIssue
 
 	commentMu sync.RWMutex
-	Comments  map[time.Time]*GitIssueComment
+	Comments  map[Marker]*GitIssueComment
 }
 
+// CachedRepo stores a repository along with its issues keyed by source marker and its labels keyed by name
 type CachedRepo struct {
 	Repo *GitRepository
 
 	issueMu sync.RWMutex
-	Issues  map[string]*CachedIssue
+	Issues  map[Marker]*CachedIssue
 
 	labelMu sync.RWMutex
 	Labels  map[string]*GitLabel
 }
+
+// RepoCache stores cached repositories keyed by name
+type RepoCache map[string]*CachedRepo
+
+// LoadCache reads the repositories, issues, comments and labels of any GitProvider into a RepoCache
+// At most workers repositories are read at once.
+func LoadCache(ctx context.Context, p GitProvider, workers int) (RepoCache, error) {
+	repos, err := p.GetRepositories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := make(RepoCache)
+
+	workerPool := pool.New(workers)
+	for _, repo := range repos {
+		cachedrepo := NewCachedRepo(repo)
+		cache[repo.Name] = cachedrepo
+
+		workerPool.Go(func() {
+			if err := fillIssues(ctx, p, cachedrepo); err != nil {
+				logrus.Warnf("failed to cache issues for %s: %v", cachedrepo.Repo.Name, err)
+			}
+			if err := fillLabels(ctx, p, cachedrepo); err != nil {
+				logrus.Warnf("failed to cache labels for %s: %v", cachedrepo.Repo.Name, err)
+			}
+		})
+	}
+	workerPool.Wait()
+	return cache, nil
+}
+
+// NewCachedRepo creates an empty cache entry for a repository
+func NewCachedRepo(repo *GitRepository) *CachedRepo {
+	return &CachedRepo{
+		Repo:    repo,
+		issueMu: sync.RWMutex{},
+		Issues:  make(map[Marker]*CachedIssue),
+		labelMu: sync.RWMutex{},
+		Labels:  make(map[string]*GitLabel),
+	}
+}
+
+// NewCachedIssue creates an empty cache entry for an issue
+func NewCachedIssue(issue *GitIssue) *CachedIssue {
+	return &CachedIssue{
+		Issue:    issue,
+		Comments: make(map[Marker]*GitIssueComment),
+	}
+}
+
+func fillIssues(ctx context.Context, p GitProvider, cachedrepo *CachedRepo) error {
+	issues, err := p.GetIssues(ctx, cachedrepo.Repo.PID, cachedrepo.Repo.Name)
+	if err != nil {
+		return err
+	}
+
+	for _, issue := range issues {
+		// issues that were not migrated by gitmv cannot match a source issue
+		marker, ok := ParseMarker(issue.Body)
+		if !ok {
+			continue
+		}
+
+		cacheissue := NewCachedIssue(issue)
+		comments, err := p.GetComments(ctx, cachedrepo.Repo.PID, issue.Number, cachedrepo.Repo.Name)
+		if err != nil {
+			return err
+		}
+
+		for _, comment := range comments {
+			commentMarker, ok := ParseMarker(comment.Body)
+			if !ok {
+				continue
+			}
+			cacheissue.commentMu.Lock()
+			cacheissue.Comments[commentMarker] = comment
+			cacheissue.commentMu.Unlock()
+		}
+
+		cachedrepo.issueMu.Lock()
+		cachedrepo.Issues[marker] = cacheissue
+		cachedrepo.issueMu.Unlock()
+	}
+
+	return nil
+}
+
+func fillLabels(ctx context.Context, p GitProvider, cachedrepo *CachedRepo) error {
+	labels, err := p.GetLabels(ctx, cachedrepo.Repo.PID, cachedrepo.Repo.Name)
+	if err != nil {
+		return err
+	}
+
+	for _, label := range labels {
+		cachedrepo.labelMu.Lock()
+		cachedrepo.Labels[label.Name] = label
+		cachedrepo.labelMu.Unlock()
+	}
+	return nil
+}
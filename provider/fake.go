@@ -1,8 +1,10 @@
 package provider
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/artur-sak13/gitmv/auth"
 )
@@ -13,14 +15,22 @@ type FakeIssue struct {
 	Comments []*GitIssueComment
 }
 
+// FakePullRequest stores information about pull requests and their associated comments
+type FakePullRequest struct {
+	PullRequest *GitPullRequest
+	Comments    []*GitReviewComment
+}
+
 // FakeRepository stores information about a new git repository
 type FakeRepository struct {
-	GitRepo     *GitRepository
-	Issues      *sync.Map
-	Labels      []*GitLabel
-	Private     bool
-	Description string
-	issueCount  int
+	GitRepo      *GitRepository
+	Issues       *sync.Map
+	PullRequests *sync.Map
+	Labels       []*GitLabel
+	Members      []*GitMember
+	Private      bool
+	Description  string
+	issueCount   int
 }
 
 // FakeProvider stores a thread safe hashmap of repository data
@@ -28,8 +38,6 @@ type FakeProvider struct {
 	Repositories *sync.Map
 }
 
-const status = "complete"
-
 // NewFakeProvider creates a new fake provider
 func NewFakeProvider() GitProvider {
 	provider := &FakeProvider{
@@ -40,18 +48,19 @@ func NewFakeProvider() GitProvider {
 }
 
 // CreateRepository creates a new Fake repository
-func (f *FakeProvider) CreateRepository(srcRepo *GitRepository) (*GitRepository, error) {
+func (f *FakeProvider) CreateRepository(ctx context.Context, srcRepo *GitRepository) (*GitRepository, error) {
 	gitRepo := &GitRepository{
 		Name: srcRepo.Name,
 	}
 
 	repo := &FakeRepository{
-		GitRepo:     gitRepo,
-		Private:     true,
-		Description: srcRepo.Description,
-		Issues:      &sync.Map{},
-		Labels:      []*GitLabel{},
-		issueCount:  0,
+		GitRepo:      gitRepo,
+		Private:      true,
+		Description:  srcRepo.Description,
+		Issues:       &sync.Map{},
+		PullRequests: &sync.Map{},
+		Labels:       []*GitLabel{},
+		issueCount:   0,
 	}
 
 	result, loaded := f.Repositories.LoadOrStore(srcRepo.Name, repo)
@@ -63,16 +72,16 @@ func (f *FakeProvider) CreateRepository(srcRepo *GitRepository) (*GitRepository,
 }
 
 // MigrateRepo migrates a git repo from an existing provider
-func (f *FakeProvider) MigrateRepo(repo *GitRepository, token string) (string, error) {
-	return status, nil
+func (f *FakeProvider) MigrateRepo(ctx context.Context, repo *GitRepository, token string) (string, error) {
+	return ImportComplete, nil
 }
 
-func (f *FakeProvider) GetImportProgress(repo string) (string, error) {
-	return status, nil
+func (f *FakeProvider) GetImportProgress(ctx context.Context, repo string) (string, error) {
+	return ImportComplete, nil
 }
 
 // CreateIssue creates a new fake issue
-func (f *FakeProvider) CreateIssue(issue *GitIssue) (*GitIssue, error) {
+func (f *FakeProvider) CreateIssue(ctx context.Context, issue *GitIssue) (*GitIssue, error) {
 	fakeRepo, ok := f.Repositories.Load(issue.Repo)
 	if !ok {
 		return nil, fmt.Errorf("repository '%s' not found", issue.Repo)
@@ -91,7 +100,7 @@ func (f *FakeProvider) CreateIssue(issue *GitIssue) (*GitIssue, error) {
 }
 
 // CreateIssueComment creates a new fake issue comment
-func (f *FakeProvider) CreateIssueComment(issueNum int, comment *GitIssueComment) error {
+func (f *FakeProvider) CreateIssueComment(ctx context.Context, issueNum int, comment *GitIssueComment) error {
 	number := comment.IssueNum
 
 	fakeRepo, ok := f.Repositories.Load(comment.Repo)
@@ -109,8 +118,39 @@ func (f *FakeProvider) CreateIssueComment(issueNum int, comment *GitIssueComment
 	return nil
 }
 
+// CreatePullRequest creates a new fake pull request
+func (f *FakeProvider) CreatePullRequest(ctx context.Context, pr *GitPullRequest) (*GitPullRequest, error) {
+	fakeRepo, ok := f.Repositories.Load(pr.Repo)
+	if !ok {
+		return nil, fmt.Errorf("repository '%s' not found", pr.Repo)
+	}
+
+	fakeRepo.(*FakeRepository).PullRequests.Store(pr.Number, &FakePullRequest{
+		PullRequest: pr,
+		Comments:    []*GitReviewComment{},
+	})
+
+	return pr, nil
+}
+
+// CreateReviewComment creates a new fake pull request comment
+func (f *FakeProvider) CreateReviewComment(ctx context.Context, pr *GitPullRequest, comment *GitReviewComment) error {
+	fakeRepo, ok := f.Repositories.Load(comment.Repo)
+	if !ok {
+		return fmt.Errorf("repository '%s' not found", comment.Repo)
+	}
+
+	repoPR, ok := fakeRepo.(*FakeRepository).PullRequests.Load(pr.Number)
+	if !ok {
+		return fmt.Errorf("pull request number '%d' does not exist for %s", pr.Number, comment.Repo)
+	}
+	fakePR := repoPR.(*FakePullRequest)
+	fakePR.Comments = append(fakePR.Comments, comment)
+	return nil
+}
+
 // CreateLabel creates a new fake issue label
-func (f *FakeProvider) CreateLabel(label *GitLabel) (*GitLabel, error) {
+func (f *FakeProvider) CreateLabel(ctx context.Context, label *GitLabel) (*GitLabel, error) {
 	fakeRepo, ok := f.Repositories.Load(label.Repo)
 	if !ok {
 		return nil, fmt.Errorf("repository '%s' not found", label.Repo)
@@ -122,27 +162,143 @@ func (f *FakeProvider) CreateLabel(label *GitLabel) (*GitLabel, error) {
 	return label, nil
 }
 
+// GrantAccess records the members of a fake repository
+func (f *FakeProvider) GrantAccess(ctx context.Context, repo *GitRepository, members []*GitMember) error {
+	fakeRepo, ok := f.Repositories.Load(repo.Name)
+	if !ok {
+		return fmt.Errorf("repository '%s' not found", repo.Name)
+	}
+	fakeRepo.(*FakeRepository).Members = append(fakeRepo.(*FakeRepository).Members, members...)
+	return nil
+}
+
+// DeleteRepository deletes a fake repository
+func (f *FakeProvider) DeleteRepository(ctx context.Context, repo *GitRepository) error {
+	if _, ok := f.Repositories.Load(repo.Name); !ok {
+		return fmt.Errorf("repository '%s' not found", repo.Name)
+	}
+	f.Repositories.Delete(repo.Name)
+	return nil
+}
+
+// ArchiveRepository archives a fake repository
+func (f *FakeProvider) ArchiveRepository(ctx context.Context, repo *GitRepository) error {
+	fakeRepo, ok := f.Repositories.Load(repo.Name)
+	if !ok {
+		return fmt.Errorf("repository '%s' not found", repo.Name)
+	}
+	fakeRepo.(*FakeRepository).GitRepo.Archived = true
+	return nil
+}
+
+// UpdateIssue replaces a fake issue, keeping its comments
+func (f *FakeProvider) UpdateIssue(ctx context.Context, issue *GitIssue) error {
+	fakeRepo, ok := f.Repositories.Load(issue.Repo)
+	if !ok {
+		return fmt.Errorf("repository '%s' not found", issue.Repo)
+	}
+	repoIssue, ok := fakeRepo.(*FakeRepository).Issues.Load(issue.Number)
+	if !ok {
+		return fmt.Errorf("issue number '%d' does not exist for %s", issue.Number, issue.Repo)
+	}
+	repoIssue.(*FakeIssue).Issue = issue
+	return nil
+}
+
+// UpdateLabel replaces a fake issue label
+func (f *FakeProvider) UpdateLabel(ctx context.Context, label *GitLabel) error {
+	fakeRepo, ok := f.Repositories.Load(label.Repo)
+	if !ok {
+		return fmt.Errorf("repository '%s' not found", label.Repo)
+	}
+	repo := fakeRepo.(*FakeRepository)
+
+	for i, existing := range repo.Labels {
+		if existing.Name == label.Name {
+			repo.Labels[i] = label
+			return nil
+		}
+	}
+	return fmt.Errorf("label '%s' does not exist for %s", label.Name, label.Repo)
+}
+
+// DeleteLabel deletes a fake issue label
+func (f *FakeProvider) DeleteLabel(ctx context.Context, label *GitLabel) error {
+	fakeRepo, ok := f.Repositories.Load(label.Repo)
+	if !ok {
+		return fmt.Errorf("repository '%s' not found", label.Repo)
+	}
+	repo := fakeRepo.(*FakeRepository)
+
+	for i, existing := range repo.Labels {
+		if existing.Name == label.Name {
+			repo.Labels = append(repo.Labels[:i], repo.Labels[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("label '%s' does not exist for %s", label.Name, label.Repo)
+}
+
+// DeleteIssue deletes a fake issue
+func (f *FakeProvider) DeleteIssue(ctx context.Context, issue *GitIssue) error {
+	fakeRepo, ok := f.Repositories.Load(issue.Repo)
+	if !ok {
+		return fmt.Errorf("repository '%s' not found", issue.Repo)
+	}
+	issues := fakeRepo.(*FakeRepository).Issues
+	if _, ok := issues.Load(issue.Number); !ok {
+		return fmt.Errorf("issue number '%d' does not exist for %s", issue.Number, issue.Repo)
+	}
+	issues.Delete(issue.Number)
+	return nil
+}
+
 // GetAuthToken returns a string with a user's api authentication token
 func (f *FakeProvider) GetAuth() *auth.ID {
 	return auth.NewAuthID("git.example.com", "test-token", "fakeorg")
 }
 
 // GetRepositories gets the fake provider's repositories
-func (f *FakeProvider) GetRepositories() ([]*GitRepository, error) {
+func (f *FakeProvider) GetRepositories(ctx context.Context) ([]*GitRepository, error) {
 	return nil, fmt.Errorf("not implemented")
 }
 
 // GetIssues gets the fake provider's issues
-func (f *FakeProvider) GetIssues(pid int, repo string) ([]*GitIssue, error) {
+func (f *FakeProvider) GetIssues(ctx context.Context, pid int, repo string) ([]*GitIssue, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+// GetIssuesUpdatedAfter gets the fake provider's recently updated issues
+func (f *FakeProvider) GetIssuesUpdatedAfter(ctx context.Context, pid int, repo string, since time.Time) ([]*GitIssue, error) {
 	return nil, fmt.Errorf("not implemented")
 }
 
 // GetComments gets the fake provider's comments
-func (f *FakeProvider) GetComments(pid, issueNum int, repo string) ([]*GitIssueComment, error) {
+func (f *FakeProvider) GetComments(ctx context.Context, pid, issueNum int, repo string) ([]*GitIssueComment, error) {
 	return nil, fmt.Errorf("not implemented")
 }
 
 // GetLabels gets the fake provider's labels
-func (f *FakeProvider) GetLabels(pid int, repo string) ([]*GitLabel, error) {
+func (f *FakeProvider) GetLabels(ctx context.Context, pid int, repo string) ([]*GitLabel, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+// GetPullRequests gets the fake provider's pull requests
+func (f *FakeProvider) GetPullRequests(ctx context.Context, pid int, repo string) ([]*GitPullRequest, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+// GetReviewComments gets the fake provider's pull request comments
+func (f *FakeProvider) GetReviewComments(ctx context.Context, pid, pullNum int, repo string) ([]*GitReviewComment, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+// GetRefs gets the fake provider's branches and tags
+func (f *FakeProvider) GetRefs(ctx context.Context, pid int, repo string) ([]*GitRef, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+// GetMembers gets the fake provider's repository members
+func (f *FakeProvider) GetMembers(ctx context.Context, repo *GitRepository) ([]*GitMember, error) {
 	return nil, fmt.Errorf("not implemented")
 }
@@ -1,6 +1,7 @@
 package provider
 
 import (
+	"context"
 	"reflect"
 	"sync"
 	"testing"
@@ -65,7 +66,7 @@ func TestFakeProvider_CreateRepository(t *testing.T) {
 			f := &FakeProvider{
 				Repositories: tt.fields.Repositories,
 			}
-			got, err := f.CreateRepository(tt.args.GitRepository)
+			got, err := f.CreateRepository(context.Background(), tt.args.GitRepository)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("FakeProvider.CreateRepository() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -99,7 +100,7 @@ func TestFakeProvider_CreateLabel(t *testing.T) {
 			f := &FakeProvider{
 				Repositories: tt.fields.Repositories,
 			}
-			got, err := f.CreateLabel(tt.args.label)
+			got, err := f.CreateLabel(context.Background(), tt.args.label)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("FakeProvider.CreateLabel() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -132,7 +133,7 @@ func TestFakeProvider_CreateIssueComment(t *testing.T) {
 			f := &FakeProvider{
 				Repositories: tt.fields.Repositories,
 			}
-			if err := f.CreateIssueComment(tt.args.comment.IssueNum, tt.args.comment); (err != nil) != tt.wantErr {
+			if err := f.CreateIssueComment(context.Background(), tt.args.comment.IssueNum, tt.args.comment); (err != nil) != tt.wantErr {
 				t.Errorf("FakeProvider.CreateIssueComment() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -195,7 +196,7 @@ func TestFakeProvider_CreateIssueComment(t *testing.T) {
 // 			f := &FakeProvider{
 // 				Repositories: tt.fields.Repositories,
 // 			}
-// 			got, err := f.CreateIssue(tt.input)
+// 			got, err := f.CreateIssue(context.Background(), tt.input)
 // 			if (err != nil) != tt.wantErr {
 // 				t.Errorf("FakeProvider.CreateIssue() error = %v, wantErr %v", err, tt.wantErr)
 // 				return
@@ -228,7 +229,7 @@ func TestFakeProvider_CreateIssue(t *testing.T) {
 			f := &FakeProvider{
 				Repositories: tt.fields.Repositories,
 			}
-			got, err := f.CreateIssue(tt.args.issue)
+			got, err := f.CreateIssue(context.Background(), tt.args.issue)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("FakeProvider.CreateIssue() error = %v, wantErr %v", err, tt.wantErr)
 				return
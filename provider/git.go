@@ -23,58 +23,144 @@
 package provider
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net"
 	"os"
 	"strings"
 
-	"github.com/artur-sak13/gitmv/auth"
-
 	"golang.org/x/crypto/ssh"
 
 	"gopkg.in/src-d/go-billy.v4/memfs"
 	git "gopkg.in/src-d/go-git.v4"
 	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
 	"gopkg.in/src-d/go-git.v4/plumbing/transport"
 	gitssh "gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
 	"gopkg.in/src-d/go-git.v4/storage/memory"
 )
 
-func MigrateWiki(repo *GitRepository, id *auth.ID) error {
-	fs := memfs.New()
-	storer := memory.NewStorage()
+// ImportComplete is the status of a repository whose import finished
+const ImportComplete = "complete"
 
-	s := fmt.Sprintf("%s/.ssh/id_rsa", os.Getenv("HOME"))
-	key, err := ioutil.ReadFile(s)
+// MigrateRepo starts migrating a source repository into its destination repository
+// Hosted providers cannot import from the local filesystem, so local sources are pushed instead.
+func MigrateRepo(ctx context.Context, src, dest GitProvider, repo, destRepo *GitRepository) (string, error) {
+	dest, err := routed(ctx, dest, destRepo.Name)
 	if err != nil {
-		return fmt.Errorf("error reading private key: %v", err)
+		return "", err
+	}
+	if local, ok := unwrap(src).(*LocalProvider); ok {
+		if err := local.PushMirror(ctx, repo, destRepo.CloneURL, dest.GetAuth().Token); err != nil {
+			return "", err
+		}
+		return ImportComplete, nil
 	}
+	return dest.MigrateRepo(ctx, repo, src.GetAuth().Token)
+}
 
-	signer, err := ssh.ParsePrivateKey([]byte(key))
+// SyncRefs copies the branches and tags a destination repository lacks or has at another commit than its source
+// Refs are compared first so that an unchanged repository is not transferred, it reports whether any were copied.
+func SyncRefs(ctx context.Context, src, dest GitProvider, repo, destRepo *GitRepository) (bool, error) {
+	srcRefs, err := src.GetRefs(ctx, repo.PID, repo.Name)
 	if err != nil {
-		return fmt.Errorf("error parsing private key: %v", err)
+		return false, fmt.Errorf("failed to list refs of %s due to: %v", repo.Name, err)
 	}
-	hostKeyCallback := func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	destRefs, err := dest.GetRefs(ctx, destRepo.PID, destRepo.Name)
+	if err != nil {
+		return false, fmt.Errorf("failed to list refs of %s due to: %v", destRepo.Name, err)
+	}
+
+	synced := make(map[string]string, len(destRefs))
+	for _, ref := range destRefs {
+		synced[ref.Name] = ref.SHA
+	}
+	changed := false
+	for _, ref := range srcRefs {
+		if synced[ref.Name] != ref.SHA {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return false, nil
+	}
+
+	if dest, err = routed(ctx, dest, destRepo.Name); err != nil {
+		return false, err
+	}
+	if _, ok := unwrap(dest).(*LocalProvider); ok {
+		// a local destination fetches from its source
+		_, err = dest.MigrateRepo(ctx, repo, src.GetAuth().Token)
+	} else if local, ok := unwrap(src).(*LocalProvider); ok {
+		err = local.PushMirror(ctx, repo, destRepo.CloneURL, dest.GetAuth().Token)
+	} else {
+		err = mirrorRefs(ctx, repo.CloneURL, src.GetAuth().Token, destRepo.CloneURL, dest.GetAuth().Token)
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// mirrorRefs fetches the branches and tags of one remote into memory and force pushes them to another
+func mirrorRefs(ctx context.Context, srcURL, srcToken, destURL, destToken string) error {
+	r, err := git.Init(memory.NewStorage(), nil)
+	if err != nil {
+		return fmt.Errorf("error creating repository %v", err)
+	}
+
+	remote, err := r.CreateRemote(&config.RemoteConfig{Name: git.DefaultRemoteName, URLs: []string{srcURL}})
+	if err != nil {
+		return fmt.Errorf("error creating remote repo %v", err)
+	}
+	err = remote.FetchContext(ctx, &git.FetchOptions{
+		RefSpecs: mirrorRefSpecs,
+		Auth:     transportAuth(srcURL, srcToken),
+		Tags:     git.NoTags,
+	})
+	if err == transport.ErrEmptyRemoteRepository {
 		return nil
 	}
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return fmt.Errorf("failed to fetch %s due to: %v", srcURL, err)
+	}
 
-	auth := &gitssh.PublicKeys{
-		User:   "git",
-		Signer: signer,
-		HostKeyCallbackHelper: gitssh.HostKeyCallbackHelper{
-			HostKeyCallback: hostKeyCallback,
-		},
+	mirror, err := r.CreateRemote(&config.RemoteConfig{Name: mirrorRemoteName, URLs: []string{destURL}})
+	if err != nil {
+		return fmt.Errorf("error creating remote repo %v", err)
 	}
-	wikiURL := strings.TrimSuffix(repo.SSHURL, ".git") + ".wiki.git"
+	err = mirror.PushContext(ctx, &git.PushOptions{
+		RemoteName: mirrorRemoteName,
+		RefSpecs:   mirrorRefSpecs,
+		Auth:       transportAuth(destURL, destToken),
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return fmt.Errorf("failed to push %s to %s due to: %v", srcURL, destURL, err)
+	}
+	return nil
+}
 
-	r, err := git.Clone(storer, fs, &git.CloneOptions{
+// MigrateWiki mirrors the wiki of a source repository into the wiki of its destination repository
+func MigrateWiki(ctx context.Context, src, dest *GitRepository) error {
+	fs := memfs.New()
+	storer := memory.NewStorage()
+
+	auth, err := wikiAuth()
+	if err != nil {
+		return err
+	}
+	wikiURL := toWikiURL(src.SSHURL)
+
+	r, err := git.CloneContext(ctx, storer, fs, &git.CloneOptions{
 		URL:      wikiURL,
 		Auth:     auth,
 		Progress: os.Stdout,
 	})
 
-	if err == transport.ErrEmptyRemoteRepository {
+	// a repository without a wiki has nothing to mirror
+	if err == transport.ErrEmptyRemoteRepository || err == transport.ErrRepositoryNotFound {
 		return nil
 	}
 
@@ -87,7 +173,7 @@ func MigrateWiki(repo *GitRepository, id *auth.ID) error {
 		return fmt.Errorf("error removing git remote %v", err)
 	}
 
-	newWikiURL := fmt.Sprintf("git@github.com:%s/%s.wiki.git", id.Owner, repo.Name)
+	newWikiURL := toWikiURL(dest.SSHURL)
 	_, err = r.CreateRemote(&config.RemoteConfig{
 		Name: "origin",
 		URLs: []string{newWikiURL},
@@ -98,7 +184,7 @@ func MigrateWiki(repo *GitRepository, id *auth.ID) error {
 	}
 
 	refspec := config.RefSpec("+" + config.DefaultPushRefSpec)
-	err = r.Push(&git.PushOptions{
+	err = r.PushContext(ctx, &git.PushOptions{
 		Auth: auth,
 		RefSpecs: []config.RefSpec{
 			refspec,
@@ -114,3 +200,75 @@ func MigrateWiki(repo *GitRepository, id *auth.ID) error {
 
 	return nil
 }
+
+// GetWikiPages lists the files of a repository's wiki, a repository without a wiki has no pages
+func GetWikiPages(ctx context.Context, repo *GitRepository) ([]string, error) {
+	auth, err := wikiAuth()
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := git.CloneContext(ctx, memory.NewStorage(), nil, &git.CloneOptions{
+		URL:   toWikiURL(repo.SSHURL),
+		Auth:  auth,
+		Depth: 1,
+	})
+	if err == transport.ErrEmptyRemoteRepository || err == transport.ErrRepositoryNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error cloning wiki of %s: %v", repo.Name, err)
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return nil, fmt.Errorf("error reading wiki of %s: %v", repo.Name, err)
+	}
+	commit, err := r.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("error reading wiki of %s: %v", repo.Name, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("error reading wiki of %s: %v", repo.Name, err)
+	}
+
+	var pages []string
+	err = tree.Files().ForEach(func(f *object.File) error {
+		pages = append(pages, f.Name)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading wiki of %s: %v", repo.Name, err)
+	}
+	return pages, nil
+}
+
+// wikiAuth authenticates wiki transfers with the user's SSH key
+func wikiAuth() (transport.AuthMethod, error) {
+	s := fmt.Sprintf("%s/.ssh/id_rsa", os.Getenv("HOME"))
+	key, err := ioutil.ReadFile(s)
+	if err != nil {
+		return nil, fmt.Errorf("error reading private key: %v", err)
+	}
+
+	signer, err := ssh.ParsePrivateKey([]byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing private key: %v", err)
+	}
+	hostKeyCallback := func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		return nil
+	}
+
+	return &gitssh.PublicKeys{
+		User:   "git",
+		Signer: signer,
+		HostKeyCallbackHelper: gitssh.HostKeyCallbackHelper{
+			HostKeyCallback: hostKeyCallback,
+		},
+	}, nil
+}
+
+func toWikiURL(repoURL string) string {
+	return strings.TrimSuffix(repoURL, ".git") + ".wiki.git"
+}
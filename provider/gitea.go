@@ -0,0 +1,736 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 Artur Sak
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/artur-sak13/gitmv/auth"
+)
+
+const giteaHostedURL = "https://gitea.com"
+
+// GiteaProvider implements the provider interface for Gitea and Forgejo
+type GiteaProvider struct {
+	Client *restClient
+	ID     *auth.ID
+
+	usersMu sync.Mutex
+	users   map[string]bool
+
+	loginOnce sync.Once
+	login     string
+}
+
+type (
+	giteaUser struct {
+		ID       int64  `json:"id"`
+		Login    string `json:"login"`
+		FullName string `json:"full_name"`
+		Email    string `json:"email"`
+	}
+
+	giteaRepository struct {
+		ID          int64      `json:"id"`
+		Name        string     `json:"name"`
+		Description string     `json:"description"`
+		CloneURL    string     `json:"clone_url"`
+		SSHURL      string     `json:"ssh_url"`
+		Owner       *giteaUser `json:"owner"`
+		Archived    bool       `json:"archived"`
+		Fork        bool       `json:"fork"`
+		Empty       bool       `json:"empty"`
+		Private     bool       `json:"private"`
+		UpdatedAt   time.Time  `json:"updated_at"`
+	}
+
+	giteaLabel struct {
+		ID          int64  `json:"id"`
+		Name        string `json:"name"`
+		Color       string `json:"color"`
+		Description string `json:"description"`
+	}
+
+	giteaIssue struct {
+		Number      int          `json:"number"`
+		Title       string       `json:"title"`
+		Body        string       `json:"body"`
+		State       string       `json:"state"`
+		User        *giteaUser   `json:"user"`
+		Labels      []giteaLabel `json:"labels"`
+		Assignees   []*giteaUser `json:"assignees"`
+		PullRequest *struct{}    `json:"pull_request"`
+	}
+
+	giteaComment struct {
+		ID        int64      `json:"id"`
+		User      *giteaUser `json:"user"`
+		Body      string     `json:"body"`
+		CreatedAt time.Time  `json:"created_at"`
+		UpdatedAt time.Time  `json:"updated_at"`
+	}
+
+	giteaBranch struct {
+		Name   string `json:"name"`
+		Commit struct {
+			ID string `json:"id"`
+		} `json:"commit"`
+	}
+
+	giteaTag struct {
+		Name   string `json:"name"`
+		Commit struct {
+			SHA string `json:"sha"`
+		} `json:"commit"`
+	}
+)
+
+// NewGiteaProvider creates a new Gitea client which implements the provider interface
+func NewGiteaProvider(id *auth.ID) (GitProvider, error) {
+	return WithGiteaClient(newHTTPClient(), id)
+}
+
+// WithGiteaClient creates a new GitProvider with an HTTP client
+// This function is exported to create mock clients in tests
+func WithGiteaClient(client *http.Client, id *auth.ID) (GitProvider, error) {
+	baseURL := id.URL
+	if baseURL == "" {
+		baseURL = giteaHostedURL
+	}
+
+	header := make(http.Header)
+	if id.Token != "" {
+		header.Set("Authorization", "token "+id.Token)
+	}
+
+	rest, err := newRESTClient(client, strings.TrimSuffix(baseURL, "/")+"/api/v1", header)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GiteaProvider{
+		Client: rest,
+		ID:     id,
+		users:  make(map[string]bool),
+	}, nil
+}
+
+// CreateRepository creates a new private Gitea repository for the organization/owner
+func (g *GiteaProvider) CreateRepository(ctx context.Context, srcRepo *GitRepository) (*GitRepository, error) {
+	repoOpts := map[string]interface{}{
+		"name":        strings.TrimSpace(srcRepo.Name),
+		"description": strings.TrimSpace(srcRepo.Description),
+		"private":     true,
+	}
+
+	path := "user/repos"
+	if g.ID.Owner != "" {
+		path = fmt.Sprintf("orgs/%s/repos", url.PathEscape(g.ID.Owner))
+	}
+
+	var repo giteaRepository
+	if _, err := g.Client.do(ctx, http.MethodPost, path, nil, repoOpts, &repo); err != nil {
+		return nil, fmt.Errorf("failed to create repository %s/%s due to: %v", g.ID.Owner, srcRepo.Name, err)
+	}
+
+	if srcRepo.Archived {
+		if _, err := g.Client.do(ctx, http.MethodPatch, g.repoPath(ctx, srcRepo.Name), nil, map[string]bool{"archived": true}, &repo); err != nil {
+			return nil, fmt.Errorf("failed to archive repository %s/%s due to: %v", g.ID.Owner, srcRepo.Name, err)
+		}
+	}
+
+	return fromGiteaRepo(&repo), nil
+}
+
+func fromGiteaRepo(repo *giteaRepository) *GitRepository {
+	owner := ""
+	if repo.Owner != nil {
+		owner = repo.Owner.Login
+	}
+	visibility := "public"
+	if repo.Private {
+		visibility = "private"
+	}
+	return &GitRepository{
+		Name:        repo.Name,
+		Description: repo.Description,
+		CloneURL:    repo.CloneURL,
+		SSHURL:      repo.SSHURL,
+		Owner:       owner,
+		Archived:    repo.Archived,
+		Fork:        repo.Fork,
+		Empty:       repo.Empty,
+		PID:         int(repo.ID),
+
+		Visibility:   visibility,
+		LastActivity: repo.UpdatedAt,
+	}
+}
+
+// MigrateRepo migrates a repo from an existing provider into Gitea
+// Gitea can only migrate into a new repository, so the refs are pushed into an
+// empty repository left by CreateRepository instead
+func (g *GiteaProvider) MigrateRepo(ctx context.Context, repo *GitRepository, token string) (string, error) {
+	var existing giteaRepository
+	_, err := g.Client.do(ctx, http.MethodGet, g.repoPath(ctx, repo.Name), nil, nil, &existing)
+	switch {
+	case err == nil && !existing.Empty:
+		return "", fmt.Errorf("failed to migrate repository %s/%s due to: the repository already has content", g.ID.Owner, repo.Name)
+	case err == nil:
+		if err := mirrorRefs(ctx, repo.CloneURL, token, existing.CloneURL, g.ID.Token); err != nil {
+			return "", fmt.Errorf("failed to migrate repository %s/%s due to: %v", g.ID.Owner, repo.Name, err)
+		}
+		return ImportComplete, nil
+	case !isNotFound(err):
+		return "", err
+	}
+
+	owner, err := g.getOwner(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	migrateOpts := map[string]interface{}{
+		"clone_addr":    repo.CloneURL,
+		"auth_username": repo.Owner,
+		"auth_password": token,
+		"uid":           owner.ID,
+		"repo_owner":    owner.Login,
+		"repo_name":     repo.Name,
+		"description":   repo.Description,
+		"private":       true,
+		"mirror":        false,
+	}
+	if repo.Owner == "" {
+		migrateOpts["auth_username"] = "oauth2"
+	}
+
+	var migrated giteaRepository
+	if _, err := g.Client.do(ctx, http.MethodPost, "repos/migrate", nil, migrateOpts, &migrated); err != nil {
+		return "", fmt.Errorf("failed to migrate repository %s/%s due to: %v", g.ID.Owner, repo.Name, err)
+	}
+
+	return ImportComplete, nil
+}
+
+// GetImportProgress checks whether a previously started Gitea migration has finished
+func (g *GiteaProvider) GetImportProgress(ctx context.Context, repoName string) (string, error) {
+	var repo giteaRepository
+	if _, err := g.Client.do(ctx, http.MethodGet, g.repoPath(ctx, repoName), nil, nil, &repo); err != nil {
+		return "", err
+	}
+	if repo.Empty {
+		return "", fmt.Errorf("no import found for %s/%s", g.ID.Owner, repoName)
+	}
+	return ImportComplete, nil
+}
+
+func (g *GiteaProvider) getOwner(ctx context.Context) (*giteaUser, error) {
+	path := "user"
+	if g.ID.Owner != "" {
+		path = fmt.Sprintf("users/%s", url.PathEscape(g.ID.Owner))
+	}
+
+	var owner giteaUser
+	if _, err := g.Client.do(ctx, http.MethodGet, path, nil, nil, &owner); err != nil {
+		return nil, fmt.Errorf("failed to find owner %s: %v", g.ID.Owner, err)
+	}
+	return &owner, nil
+}
+
+// CreateIssue creates a new Gitea issue
+func (g *GiteaProvider) CreateIssue(ctx context.Context, issue *GitIssue) (*GitIssue, error) {
+	labelIDs, err := g.getLabelIDs(ctx, issue.Repo, issue.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	issueOpts := map[string]interface{}{
+		"title":     strings.TrimSpace(issue.Title),
+		"body":      strings.TrimSpace(issue.Body),
+		"labels":    labelIDs,
+		"assignees": g.getAssignees(ctx, issue.Assignees),
+	}
+
+	var result giteaIssue
+	if _, err := g.Client.do(ctx, http.MethodPost, g.repoPath(ctx, issue.Repo)+"/issues", nil, issueOpts, &result); err != nil {
+		return nil, fmt.Errorf("failed to create issue in %s/%s due to: %v", g.ID.Owner, issue.Repo, err)
+	}
+
+	if issue.State == "closed" && result.State != "closed" {
+		path := fmt.Sprintf("%s/issues/%d", g.repoPath(ctx, issue.Repo), result.Number)
+		if _, err := g.Client.do(ctx, http.MethodPatch, path, nil, map[string]string{"state": "closed"}, &result); err != nil {
+			return nil, fmt.Errorf("failed to close issue %d in %s/%s due to: %v", result.Number, g.ID.Owner, issue.Repo, err)
+		}
+	}
+
+	gitissue := fromGiteaIssue(&result)
+	gitissue.Repo = issue.Repo
+	gitissue.PID = issue.PID
+	return gitissue, nil
+}
+
+// UpdateIssue replaces the title, body, state and labels of a Gitea issue
+func (g *GiteaProvider) UpdateIssue(ctx context.Context, issue *GitIssue) error {
+	labelIDs, err := g.getLabelIDs(ctx, issue.Repo, issue.Labels)
+	if err != nil {
+		return err
+	}
+
+	state := "open"
+	if issue.State == "closed" {
+		state = "closed"
+	}
+	issueOpts := map[string]string{
+		"title": strings.TrimSpace(issue.Title),
+		"body":  strings.TrimSpace(issue.Body),
+		"state": state,
+	}
+	path := fmt.Sprintf("%s/issues/%d", g.repoPath(ctx, issue.Repo), issue.Number)
+	if _, err := g.Client.do(ctx, http.MethodPatch, path, nil, issueOpts, nil); err != nil {
+		return fmt.Errorf("failed to update issue %d in %s/%s due to: %v", issue.Number, g.ID.Owner, issue.Repo, err)
+	}
+	// the issue's labels are replaced through their own endpoint
+	if _, err := g.Client.do(ctx, http.MethodPut, path+"/labels", nil, map[string][]int64{"labels": labelIDs}, nil); err != nil {
+		return fmt.Errorf("failed to update labels of issue %d in %s/%s due to: %v", issue.Number, g.ID.Owner, issue.Repo, err)
+	}
+	return nil
+}
+
+// getAssignees drops assignees without a matching Gitea account, which would fail the whole request
+func (g *GiteaProvider) getAssignees(ctx context.Context, users []GitUser) []string {
+	g.usersMu.Lock()
+	defer g.usersMu.Unlock()
+
+	logins := []string{}
+	for _, user := range users {
+		if user.Login == "" {
+			continue
+		}
+		exists, ok := g.users[user.Login]
+		if !ok {
+			_, err := g.Client.do(ctx, http.MethodGet, "users/"+url.PathEscape(user.Login), nil, nil, nil)
+			exists = err == nil
+			g.users[user.Login] = exists
+		}
+		if exists {
+			logins = append(logins, user.Login)
+		}
+	}
+	return logins
+}
+
+func (g *GiteaProvider) getLabelIDs(ctx context.Context, repo string, labels []GitLabel) ([]int64, error) {
+	ids := []int64{}
+	if len(labels) == 0 {
+		return ids, nil
+	}
+
+	existing, err := g.listLabels(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]int64)
+	for _, label := range existing {
+		byName[label.Name] = label.ID
+	}
+
+	for _, label := range labels {
+		if id, ok := byName[label.Name]; ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func fromGiteaIssue(issue *giteaIssue) *GitIssue {
+	labels := []GitLabel{}
+	for _, label := range issue.Labels {
+		labels = append(labels, GitLabel{
+			Name:        label.Name,
+			Color:       label.Color,
+			Description: label.Description,
+		})
+	}
+
+	assignees := []GitUser{}
+	for _, assignee := range issue.Assignees {
+		assignees = append(assignees, *fromGiteaUser(assignee))
+	}
+
+	return &GitIssue{
+		Number:    issue.Number,
+		Title:     issue.Title,
+		Body:      issue.Body,
+		State:     issue.State,
+		Labels:    labels,
+		User:      fromGiteaUser(issue.User),
+		Assignees: assignees,
+	}
+}
+
+func fromGiteaUser(user *giteaUser) *GitUser {
+	if user == nil {
+		return &GitUser{}
+	}
+	return &GitUser{
+		Login: user.Login,
+		Name:  user.FullName,
+		Email: user.Email,
+	}
+}
+
+// CreateIssueComment creates a new Gitea issue comment
+func (g *GiteaProvider) CreateIssueComment(ctx context.Context, issueNum int, comment *GitIssueComment) error {
+	path := fmt.Sprintf("%s/issues/%d/comments", g.repoPath(ctx, comment.Repo), issueNum)
+	_, err := g.Client.do(ctx, http.MethodPost, path, nil, map[string]string{"body": strings.TrimSpace(comment.Body)}, nil)
+	return err
+}
+
+// CreatePullRequest records a pull request as a Gitea issue
+func (g *GiteaProvider) CreatePullRequest(ctx context.Context, pr *GitPullRequest) (*GitPullRequest, error) {
+	issue, err := g.CreateIssue(ctx, PullRequestAsIssue(pr))
+	if err != nil {
+		return nil, err
+	}
+
+	created := *pr
+	created.Number = issue.Number
+	created.IsIssue = true
+	return &created, nil
+}
+
+// CreateReviewComment records a pull request comment on the issue created in its place
+func (g *GiteaProvider) CreateReviewComment(ctx context.Context, pr *GitPullRequest, comment *GitReviewComment) error {
+	return g.CreateIssueComment(ctx, pr.Number, ReviewCommentAsIssueComment(pr.Number, comment))
+}
+
+// CreateLabel creates a new Gitea issue label
+func (g *GiteaProvider) CreateLabel(ctx context.Context, srcLabel *GitLabel) (*GitLabel, error) {
+	labelOpts := map[string]string{
+		"name":        strings.TrimSpace(srcLabel.Name),
+		"color":       "#" + strings.Trim(srcLabel.Color, "#\r\n\t"),
+		"description": strings.TrimSpace(srcLabel.Description),
+	}
+
+	var result giteaLabel
+	if _, err := g.Client.do(ctx, http.MethodPost, g.repoPath(ctx, srcLabel.Repo)+"/labels", nil, labelOpts, &result); err != nil {
+		return nil, err
+	}
+
+	return fromGiteaLabel(srcLabel.Repo, &result), nil
+}
+
+// DeleteRepository deletes a Gitea repository
+func (g *GiteaProvider) DeleteRepository(ctx context.Context, repo *GitRepository) error {
+	if _, err := g.Client.do(ctx, http.MethodDelete, g.repoPath(ctx, repo.Name), nil, nil, nil); err != nil {
+		return fmt.Errorf("failed to delete repository %s/%s due to: %v", g.ID.Owner, repo.Name, err)
+	}
+	return nil
+}
+
+// ArchiveRepository makes a Gitea repository read-only
+func (g *GiteaProvider) ArchiveRepository(ctx context.Context, repo *GitRepository) error {
+	if _, err := g.Client.do(ctx, http.MethodPatch, g.repoPath(ctx, repo.Name), nil, map[string]bool{"archived": true}, nil); err != nil {
+		return fmt.Errorf("failed to archive repository %s/%s due to: %v", g.ID.Owner, repo.Name, err)
+	}
+	return nil
+}
+
+// DeleteLabel deletes a Gitea issue label, which the API addresses by ID
+func (g *GiteaProvider) DeleteLabel(ctx context.Context, label *GitLabel) error {
+	labels, err := g.listLabels(ctx, label.Repo)
+	if err != nil {
+		return fmt.Errorf("failed to list labels of %s/%s due to: %v", g.ID.Owner, label.Repo, err)
+	}
+	for _, existing := range labels {
+		if existing.Name != label.Name {
+			continue
+		}
+		path := fmt.Sprintf("%s/labels/%d", g.repoPath(ctx, label.Repo), existing.ID)
+		if _, err := g.Client.do(ctx, http.MethodDelete, path, nil, nil, nil); err != nil {
+			return fmt.Errorf("failed to delete label %s from %s/%s due to: %v", label.Name, g.ID.Owner, label.Repo, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("label %s does not exist in %s/%s", label.Name, g.ID.Owner, label.Repo)
+}
+
+// UpdateLabel replaces the color and description of a Gitea issue label
+func (g *GiteaProvider) UpdateLabel(ctx context.Context, label *GitLabel) error {
+	labels, err := g.listLabels(ctx, label.Repo)
+	if err != nil {
+		return fmt.Errorf("failed to list labels of %s/%s due to: %v", g.ID.Owner, label.Repo, err)
+	}
+	for _, existing := range labels {
+		if existing.Name != label.Name {
+			continue
+		}
+		labelOpts := map[string]string{
+			"color":       "#" + strings.Trim(label.Color, "#\r\n\t"),
+			"description": strings.TrimSpace(label.Description),
+		}
+		path := fmt.Sprintf("%s/labels/%d", g.repoPath(ctx, label.Repo), existing.ID)
+		if _, err := g.Client.do(ctx, http.MethodPatch, path, nil, labelOpts, nil); err != nil {
+			return fmt.Errorf("failed to update label %s in %s/%s due to: %v", label.Name, g.ID.Owner, label.Repo, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("label %s does not exist in %s/%s", label.Name, g.ID.Owner, label.Repo)
+}
+
+// DeleteIssue deletes a Gitea issue
+func (g *GiteaProvider) DeleteIssue(ctx context.Context, issue *GitIssue) error {
+	path := fmt.Sprintf("%s/issues/%d", g.repoPath(ctx, issue.Repo), issue.Number)
+	if _, err := g.Client.do(ctx, http.MethodDelete, path, nil, nil, nil); err != nil {
+		return fmt.Errorf("failed to delete issue %d in %s/%s due to: %v", issue.Number, g.ID.Owner, issue.Repo, err)
+	}
+	return nil
+}
+
+func fromGiteaLabel(repo string, label *giteaLabel) *GitLabel {
+	return &GitLabel{
+		Repo:        repo,
+		Name:        label.Name,
+		Color:       label.Color,
+		Description: label.Description,
+	}
+}
+
+// GetAuth returns a string with a user's api authentication token
+func (g *GiteaProvider) GetAuth() *auth.ID {
+	return g.ID
+}
+
+// GetRepositories retrieves a list of Gitea repositories for the organization/owner
+func (g *GiteaProvider) GetRepositories(ctx context.Context) ([]*GitRepository, error) {
+	path := "user/repos"
+	if g.ID.Owner != "" {
+		path = fmt.Sprintf("orgs/%s/repos", url.PathEscape(g.ID.Owner))
+	}
+
+	var result []*giteaRepository
+	err := g.depaginate(func(opts url.Values) (int, error) {
+		var repos []*giteaRepository
+		_, err := g.Client.do(ctx, http.MethodGet, path, opts, nil, &repos)
+
+		result = append(result, repos...)
+		return len(repos), err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var repos []*GitRepository
+	for _, repo := range result {
+		repos = append(repos, fromGiteaRepo(repo))
+	}
+	return repos, nil
+}
+
+// GetIssues retrieves a list of issues associated with a Gitea repository
+func (g *GiteaProvider) GetIssues(ctx context.Context, pid int, repo string) ([]*GitIssue, error) {
+	return g.listIssues(ctx, pid, repo, time.Time{})
+}
+
+// GetIssuesUpdatedAfter retrieves the issues of a Gitea repository updated after since
+func (g *GiteaProvider) GetIssuesUpdatedAfter(ctx context.Context, pid int, repo string, since time.Time) ([]*GitIssue, error) {
+	return g.listIssues(ctx, pid, repo, since)
+}
+
+// listIssues retrieves the issues of a Gitea repository, only those updated after since unless it is zero
+func (g *GiteaProvider) listIssues(ctx context.Context, pid int, repo string, since time.Time) ([]*GitIssue, error) {
+	var result []*giteaIssue
+	err := g.depaginate(func(opts url.Values) (int, error) {
+		opts.Set("state", "all")
+		opts.Set("type", "issues")
+		if !since.IsZero() {
+			opts.Set("since", since.Format(time.RFC3339))
+		}
+
+		var issues []*giteaIssue
+		_, err := g.Client.do(ctx, http.MethodGet, g.repoPath(ctx, repo)+"/issues", opts, nil, &issues)
+
+		result = append(result, issues...)
+		return len(issues), err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []*GitIssue
+	for _, issue := range result {
+		// Older Gitea versions ignore the type filter
+		if issue.PullRequest != nil {
+			continue
+		}
+		gitissue := fromGiteaIssue(issue)
+		gitissue.Repo = repo
+		gitissue.PID = pid
+		issues = append(issues, gitissue)
+	}
+	return issues, nil
+}
+
+// GetComments retrieves a list of issue comments associated with a Gitea issue
+func (g *GiteaProvider) GetComments(ctx context.Context, pid, issueNum int, repo string) ([]*GitIssueComment, error) {
+	var list []*giteaComment
+	path := fmt.Sprintf("%s/issues/%d/comments", g.repoPath(ctx, repo), issueNum)
+	if _, err := g.Client.do(ctx, http.MethodGet, path, nil, nil, &list); err != nil {
+		return nil, err
+	}
+
+	var comments []*GitIssueComment
+	for _, comment := range list {
+		comments = append(comments, &GitIssueComment{
+			ID:        int(comment.ID),
+			Repo:      repo,
+			IssueNum:  issueNum,
+			User:      *fromGiteaUser(comment.User),
+			Body:      comment.Body,
+			CreatedAt: comment.CreatedAt,
+			UpdatedAt: comment.UpdatedAt,
+		})
+	}
+	return comments, nil
+}
+
+// GetLabels retrieves a list of labels associated with a Gitea repository
+func (g *GiteaProvider) GetLabels(ctx context.Context, pid int, repo string) ([]*GitLabel, error) {
+	list, err := g.listLabels(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var labels []*GitLabel
+	for _, label := range list {
+		labels = append(labels, fromGiteaLabel(repo, label))
+	}
+	return labels, nil
+}
+
+// GrantAccess is not supported since Gitea teams are not migrated yet
+func (g *GiteaProvider) GrantAccess(ctx context.Context, repo *GitRepository, members []*GitMember) error {
+	return fmt.Errorf("gitea GrantAccess not supported")
+}
+
+// GetMembers is not supported since Gitea teams are not migrated yet
+func (g *GiteaProvider) GetMembers(ctx context.Context, repo *GitRepository) ([]*GitMember, error) {
+	return nil, fmt.Errorf("gitea GetMembers not supported")
+}
+
+// GetRefs retrieves the branches and tags of a Gitea repository along with the commits they point at
+func (g *GiteaProvider) GetRefs(ctx context.Context, pid int, repo string) ([]*GitRef, error) {
+	var refs []*GitRef
+
+	err := g.depaginate(func(opts url.Values) (int, error) {
+		var branches []*giteaBranch
+		_, err := g.Client.do(ctx, http.MethodGet, g.repoPath(ctx, repo)+"/branches", opts, nil, &branches)
+		for _, branch := range branches {
+			refs = append(refs, &GitRef{Name: branch.Name, SHA: branch.Commit.ID})
+		}
+		return len(branches), err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches of %s due to: %v", repo, err)
+	}
+
+	err = g.depaginate(func(opts url.Values) (int, error) {
+		var tags []*giteaTag
+		_, err := g.Client.do(ctx, http.MethodGet, g.repoPath(ctx, repo)+"/tags", opts, nil, &tags)
+		for _, tag := range tags {
+			refs = append(refs, &GitRef{Name: tag.Name, Tag: true, SHA: tag.Commit.SHA})
+		}
+		return len(tags), err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags of %s due to: %v", repo, err)
+	}
+
+	return refs, nil
+}
+
+// GetPullRequests returns no pull requests since reading them from Gitea is not supported
+func (g *GiteaProvider) GetPullRequests(ctx context.Context, pid int, repo string) ([]*GitPullRequest, error) {
+	return []*GitPullRequest{}, nil
+}
+
+// GetReviewComments returns no comments since reading pull requests from Gitea is not supported
+func (g *GiteaProvider) GetReviewComments(ctx context.Context, pid, pullNum int, repo string) ([]*GitReviewComment, error) {
+	return []*GitReviewComment{}, nil
+}
+
+func (g *GiteaProvider) listLabels(ctx context.Context, repo string) ([]*giteaLabel, error) {
+	var list []*giteaLabel
+	err := g.depaginate(func(opts url.Values) (int, error) {
+		var labels []*giteaLabel
+		_, err := g.Client.do(ctx, http.MethodGet, g.repoPath(ctx, repo)+"/labels", opts, nil, &labels)
+
+		list = append(list, labels...)
+		return len(labels), err
+	})
+	return list, err
+}
+
+// repoPath returns the API path of a repository owned by the organization/owner,
+// or by the authenticated user when no owner is set
+func (g *GiteaProvider) repoPath(ctx context.Context, repo string) string {
+	owner := g.ID.Owner
+	if owner == "" {
+		g.loginOnce.Do(func() {
+			if user, err := g.getOwner(ctx); err == nil {
+				g.login = user.Login
+			}
+		})
+		owner = g.login
+	}
+	return fmt.Sprintf("repos/%s/%s", url.PathEscape(owner), url.PathEscape(repo))
+}
+
+// depaginate requests pages until one comes back with fewer items than the page limit
+func (g *GiteaProvider) depaginate(closure func(opts url.Values) (int, error)) error {
+	limit := 50
+	for page := 1; ; page++ {
+		opts := url.Values{}
+		opts.Set("page", strconv.Itoa(page))
+		opts.Set("limit", strconv.Itoa(limit))
+
+		n, err := closure(opts)
+		if err != nil {
+			return err
+		}
+		if n < limit {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,445 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/artur-sak13/gitmv/auth"
+)
+
+const giteaOrgName = "o"
+
+func setupGitea(t *testing.T) (*GiteaProvider, *http.ServeMux, func()) {
+	mux := http.NewServeMux()
+
+	apiHandler := http.NewServeMux()
+	apiHandler.Handle("/api/v1/", http.StripPrefix("/api/v1", mux))
+	apiHandler.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		t.Errorf("Request URL %s is missing the /api/v1 prefix", req.URL)
+		http.Error(w, "missing API prefix", http.StatusInternalServerError)
+	})
+
+	server := httptest.NewServer(apiHandler)
+
+	id := auth.NewAuthID(server.URL, "p", giteaOrgName)
+	prov, err := WithGiteaClient(server.Client(), id)
+	if err != nil {
+		t.Fatalf("WithGiteaClient returned error: %v", err)
+	}
+
+	return prov.(*GiteaProvider), mux, server.Close
+}
+
+func TestGitea_CreateRepository(t *testing.T) {
+	prov, mux, teardown := setupGitea(t)
+	defer teardown()
+
+	mux.HandleFunc("/orgs/o/repos", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testHeader(t, r, "Authorization", "token p")
+
+		v := make(map[string]interface{})
+		json.NewDecoder(r.Body).Decode(&v)
+		want := map[string]interface{}{"name": "r", "description": "d", "private": true}
+		if !reflect.DeepEqual(v, want) {
+			t.Errorf("Request body = %+v, want %+v", v, want)
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id":1,"name":"r","description":"d","clone_url":"https://gitea.example.com/o/r.git","ssh_url":"git@gitea.example.com:o/r.git","owner":{"login":"o"},"empty":true}`)
+	})
+
+	got, err := prov.CreateRepository(context.Background(), &GitRepository{Name: "r", Description: "d"})
+	if err != nil {
+		t.Errorf("CreateRepository returned error: %v", err)
+	}
+	want := &GitRepository{
+		Name:        "r",
+		Description: "d",
+		CloneURL:    "https://gitea.example.com/o/r.git",
+		SSHURL:      "git@gitea.example.com:o/r.git",
+		Owner:       "o",
+		Empty:       true,
+		PID:         1,
+		Visibility:  "public",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CreateRepository = %+v, want %+v", got, want)
+	}
+}
+
+func TestGitea_MigrateRepo(t *testing.T) {
+	prov, mux, teardown := setupGitea(t)
+	defer teardown()
+
+	mux.HandleFunc("/repos/o/r", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+	})
+	mux.HandleFunc("/users/o", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `{"id":7,"login":"o"}`)
+	})
+	mux.HandleFunc("/repos/migrate", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+
+		v := make(map[string]interface{})
+		json.NewDecoder(r.Body).Decode(&v)
+		want := map[string]interface{}{
+			"clone_addr":    "https://gitlab.example.com/u/r.git",
+			"auth_username": "u",
+			"auth_password": "secret",
+			"uid":           float64(7),
+			"repo_owner":    "o",
+			"repo_name":     "r",
+			"description":   "",
+			"private":       true,
+			"mirror":        false,
+		}
+		if !reflect.DeepEqual(v, want) {
+			t.Errorf("Request body = %+v, want %+v", v, want)
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id":2,"name":"r"}`)
+	})
+
+	got, err := prov.MigrateRepo(context.Background(), &GitRepository{Name: "r", Owner: "u", CloneURL: "https://gitlab.example.com/u/r.git"}, "secret")
+	if err != nil {
+		t.Errorf("MigrateRepo returned error: %v", err)
+	}
+	if want := "complete"; got != want {
+		t.Errorf("MigrateRepo = %+v, want %+v", got, want)
+	}
+}
+
+func TestGitea_MigrateRepoExisting(t *testing.T) {
+	prov, mux, teardown := setupGitea(t)
+	defer teardown()
+
+	mux.HandleFunc("/repos/o/r", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `{"id":1,"name":"r","empty":false}`)
+	})
+	mux.HandleFunc("/repos/migrate", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("MigrateRepo migrated over an existing repository")
+	})
+
+	if _, err := prov.MigrateRepo(context.Background(), &GitRepository{Name: "r", Owner: "u"}, "secret"); err == nil {
+		t.Errorf("MigrateRepo returned no error for a repository with content")
+	}
+}
+
+func TestGitea_GetImportProgress(t *testing.T) {
+	prov, mux, teardown := setupGitea(t)
+	defer teardown()
+
+	mux.HandleFunc("/repos/o/r", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `{"id":1,"name":"r","empty":false}`)
+	})
+	mux.HandleFunc("/repos/o/empty", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `{"id":2,"name":"empty","empty":true}`)
+	})
+
+	got, err := prov.GetImportProgress(context.Background(), "r")
+	if err != nil {
+		t.Errorf("GetImportProgress returned error: %v", err)
+	}
+	if want := "complete"; got != want {
+		t.Errorf("GetImportProgress = %+v, want %+v", got, want)
+	}
+
+	if _, err := prov.GetImportProgress(context.Background(), "empty"); err == nil {
+		t.Errorf("GetImportProgress returned no error for an empty repository")
+	}
+}
+
+func TestGitea_CreateIssue(t *testing.T) {
+	prov, mux, teardown := setupGitea(t)
+	defer teardown()
+
+	mux.HandleFunc("/repos/o/r/labels", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `[{"id":3,"name":"bug","color":"d73a4a"},{"id":4,"name":"docs","color":"0075ca"}]`)
+	})
+	mux.HandleFunc("/users/u", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":7,"login":"u"}`)
+	})
+	mux.HandleFunc("/users/ghost", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/repos/o/r/issues", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+
+		v := make(map[string]interface{})
+		json.NewDecoder(r.Body).Decode(&v)
+		want := map[string]interface{}{
+			"title":     "t",
+			"body":      "b",
+			"labels":    []interface{}{float64(3)},
+			"assignees": []interface{}{"u"},
+		}
+		if !reflect.DeepEqual(v, want) {
+			t.Errorf("Request body = %+v, want %+v", v, want)
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"number":5,"title":"t","body":"b","state":"open","labels":[{"id":3,"name":"bug","color":"d73a4a"}],"assignees":[{"login":"u"}]}`)
+	})
+	mux.HandleFunc("/repos/o/r/issues/5", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "PATCH")
+		fmt.Fprint(w, `{"number":5,"title":"t","body":"b","state":"closed","labels":[{"id":3,"name":"bug","color":"d73a4a"}],"assignees":[{"login":"u"}]}`)
+	})
+
+	got, err := prov.CreateIssue(context.Background(), &GitIssue{
+		Repo:      "r",
+		Title:     "t",
+		Body:      "b",
+		State:     "closed",
+		Labels:    []GitLabel{{Name: "bug"}},
+		Assignees: []GitUser{{Login: "u"}, {Login: "ghost"}},
+	})
+	if err != nil {
+		t.Errorf("CreateIssue returned error: %v", err)
+	}
+	want := &GitIssue{
+		Repo:      "r",
+		Number:    5,
+		Title:     "t",
+		Body:      "b",
+		State:     "closed",
+		Labels:    []GitLabel{{Name: "bug", Color: "d73a4a"}},
+		User:      &GitUser{},
+		Assignees: []GitUser{{Login: "u"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CreateIssue = %+v, want %+v", got, want)
+	}
+}
+
+func TestGitea_CreateIssueComment(t *testing.T) {
+	prov, mux, teardown := setupGitea(t)
+	defer teardown()
+
+	mux.HandleFunc("/repos/o/r/issues/5/comments", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+
+		v := make(map[string]string)
+		json.NewDecoder(r.Body).Decode(&v)
+		if want := map[string]string{"body": "c"}; !reflect.DeepEqual(v, want) {
+			t.Errorf("Request body = %+v, want %+v", v, want)
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id":1,"body":"c"}`)
+	})
+
+	if err := prov.CreateIssueComment(context.Background(), 5, &GitIssueComment{Repo: "r", Body: "c"}); err != nil {
+		t.Errorf("CreateIssueComment returned error: %v", err)
+	}
+}
+
+func TestGitea_CreateLabel(t *testing.T) {
+	prov, mux, teardown := setupGitea(t)
+	defer teardown()
+
+	mux.HandleFunc("/repos/o/r/labels", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+
+		v := make(map[string]string)
+		json.NewDecoder(r.Body).Decode(&v)
+		want := map[string]string{"name": "bug", "color": "#d73a4a", "description": "d"}
+		if !reflect.DeepEqual(v, want) {
+			t.Errorf("Request body = %+v, want %+v", v, want)
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id":3,"name":"bug","color":"d73a4a","description":"d"}`)
+	})
+
+	got, err := prov.CreateLabel(context.Background(), &GitLabel{Repo: "r", Name: "bug", Color: "d73a4a", Description: "d"})
+	if err != nil {
+		t.Errorf("CreateLabel returned error: %v", err)
+	}
+	want := &GitLabel{Repo: "r", Name: "bug", Color: "d73a4a", Description: "d"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CreateLabel = %+v, want %+v", got, want)
+	}
+}
+
+func TestGitea_GetRepositories(t *testing.T) {
+	prov, mux, teardown := setupGitea(t)
+	defer teardown()
+
+	mux.HandleFunc("/orgs/o/repos", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		if r.URL.Query().Get("page") != "1" {
+			fmt.Fprint(w, `[]`)
+			return
+		}
+		fmt.Fprint(w, `[{"id":1,"name":"r","owner":{"login":"o"}},{"id":2,"name":"f","fork":true,"owner":{"login":"o"}}]`)
+	})
+
+	got, err := prov.GetRepositories(context.Background())
+	if err != nil {
+		t.Errorf("GetRepositories returned error: %v", err)
+	}
+	want := []*GitRepository{
+		{Name: "r", Owner: "o", PID: 1, Visibility: "public"},
+		{Name: "f", Owner: "o", Fork: true, PID: 2, Visibility: "public"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetRepositories = %+v, want %+v", got, want)
+	}
+}
+
+func TestGitea_GetIssues(t *testing.T) {
+	prov, mux, teardown := setupGitea(t)
+	defer teardown()
+
+	mux.HandleFunc("/repos/o/r/issues", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		if got := r.URL.Query().Get("state"); got != "all" {
+			t.Errorf("state = %q, want all", got)
+		}
+		fmt.Fprint(w, `[{"number":1,"title":"t","state":"open","user":{"login":"u","full_name":"U"}},{"number":2,"title":"pr","pull_request":{}}]`)
+	})
+
+	got, err := prov.GetIssues(context.Background(), 1, "r")
+	if err != nil {
+		t.Errorf("GetIssues returned error: %v", err)
+	}
+	want := []*GitIssue{{
+		Repo:      "r",
+		PID:       1,
+		Number:    1,
+		Title:     "t",
+		State:     "open",
+		Labels:    []GitLabel{},
+		User:      &GitUser{Login: "u", Name: "U"},
+		Assignees: []GitUser{},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetIssues = %+v, want %+v", got, want)
+	}
+}
+
+func TestGitea_GetComments(t *testing.T) {
+	prov, mux, teardown := setupGitea(t)
+	defer teardown()
+
+	mux.HandleFunc("/repos/o/r/issues/1/comments", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `[{"id":1,"body":"c","user":{"login":"u"},"created_at":"2019-03-01T10:00:00Z","updated_at":"2019-03-02T10:00:00Z"}]`)
+	})
+
+	got, err := prov.GetComments(context.Background(), 1, 1, "r")
+	if err != nil {
+		t.Errorf("GetComments returned error: %v", err)
+	}
+	want := []*GitIssueComment{{
+		ID:        1,
+		Repo:      "r",
+		IssueNum:  1,
+		User:      GitUser{Login: "u"},
+		Body:      "c",
+		CreatedAt: time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2019, 3, 2, 10, 0, 0, 0, time.UTC),
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetComments = %+v, want %+v", got, want)
+	}
+}
+
+func TestGitea_GetRefs(t *testing.T) {
+	prov, mux, teardown := setupGitea(t)
+	defer teardown()
+
+	mux.HandleFunc("/repos/o/r/branches", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `[{"name":"master","commit":{"id":"abc"}}]`)
+	})
+	mux.HandleFunc("/repos/o/r/tags", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `[{"name":"v1","commit":{"sha":"def"}}]`)
+	})
+
+	got, err := prov.GetRefs(context.Background(), 1, "r")
+	if err != nil {
+		t.Errorf("GetRefs returned error: %v", err)
+	}
+	want := []*GitRef{
+		{Name: "master", SHA: "abc"},
+		{Name: "v1", Tag: true, SHA: "def"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetRefs = %+v, want %+v", got, want)
+	}
+}
+
+func TestGitea_DeleteLabel(t *testing.T) {
+	prov, mux, teardown := setupGitea(t)
+	defer teardown()
+
+	mux.HandleFunc("/repos/o/r/labels", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `[{"id":3,"name":"bug"},{"id":4,"name":"docs"}]`)
+	})
+	deleted := false
+	mux.HandleFunc("/repos/o/r/labels/4", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "DELETE")
+		deleted = true
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := prov.DeleteLabel(context.Background(), &GitLabel{Repo: "r", Name: "docs"}); err != nil {
+		t.Errorf("DeleteLabel returned error: %v", err)
+	}
+	if !deleted {
+		t.Errorf("DeleteLabel did not delete label 4")
+	}
+	if err := prov.DeleteLabel(context.Background(), &GitLabel{Repo: "r", Name: "missing"}); err == nil {
+		t.Errorf("DeleteLabel expected error for a missing label")
+	}
+}
+
+func TestGitea_UpdateIssue(t *testing.T) {
+	prov, mux, teardown := setupGitea(t)
+	defer teardown()
+
+	mux.HandleFunc("/repos/o/r/labels", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `[{"id":3,"name":"bug"}]`)
+	})
+	mux.HandleFunc("/repos/o/r/issues/4", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "PATCH")
+		var v map[string]string
+		json.NewDecoder(r.Body).Decode(&v)
+		if want := map[string]string{"title": "t", "body": "b", "state": "closed"}; !reflect.DeepEqual(v, want) {
+			t.Errorf("Request = %+v, want %+v", v, want)
+		}
+		fmt.Fprint(w, `{"number":4,"state":"closed"}`)
+	})
+	mux.HandleFunc("/repos/o/r/issues/4/labels", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "PUT")
+		var v map[string][]int64
+		json.NewDecoder(r.Body).Decode(&v)
+		if want := []int64{3}; !reflect.DeepEqual(v["labels"], want) {
+			t.Errorf("Request labels = %v, want %v", v["labels"], want)
+		}
+		fmt.Fprint(w, `[]`)
+	})
+
+	update := &GitIssue{Repo: "r", Number: 4, Title: "t", Body: "b", State: "closed", Labels: []GitLabel{{Name: "bug"}}}
+	if err := prov.UpdateIssue(context.Background(), update); err != nil {
+		t.Errorf("UpdateIssue returned error: %v", err)
+	}
+}
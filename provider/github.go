@@ -25,6 +25,9 @@ package provider
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -32,43 +35,83 @@ import (
 	"github.com/artur-sak13/gitmv/auth"
 
 	"github.com/google/go-github/v21/github"
+	"github.com/sirupsen/logrus"
 	"golang.org/x/oauth2"
 )
 
 // GitHubProvider implements the provider interface for GitHub
 type GithubProvider struct {
-	Client  *github.Client
-	Context context.Context
-	ID      *auth.ID
+	Client *github.Client
+	ID     *auth.ID
 
-	retries   int
-	Repocache map[string]*CachedRepo
+	Repocache RepoCache
 	Members   map[string]*github.User
+	membersMu sync.Mutex
+
+	patches   map[string]map[string]string
+	patchesMu sync.Mutex
 }
 
 // NewGithubProvider creates a new GitHub clients which implements the provider interface
+// A URL other than github.com is treated as a GitHub Enterprise Server host.
 func NewGithubProvider(ctx context.Context, id *auth.ID) (GitProvider, error) {
 	ts := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: id.Token},
 	)
-	tc := oauth2.NewClient(ctx, ts)
-	client := github.NewClient(tc)
-	return WithGithubClient(ctx, client, id), nil
+	tc := oauth2.NewClient(context.WithValue(ctx, oauth2.HTTPClient, newHTTPClient()), ts)
+
+	if isGithubDotCom(id.URL) {
+		return WithGithubClient(github.NewClient(tc), id), nil
+	}
+
+	baseURL, uploadURL, err := githubEnterpriseURLs(id.URL)
+	if err != nil {
+		return nil, err
+	}
+	client, err := github.NewEnterpriseClient(baseURL, uploadURL, tc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitHub Enterprise client for %s due to: %v", id.URL, err)
+	}
+	return WithGithubClient(client, id), nil
+}
+
+func isGithubDotCom(rawURL string) bool {
+	if rawURL == "" {
+		return true
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	return u.Host == "github.com" || u.Host == "api.github.com"
+}
+
+// githubEnterpriseURLs derives the API and uploads endpoints of a GitHub Enterprise Server host
+// The host may be given with or without its /api/v3 suffix.
+func githubEnterpriseURLs(rawURL string) (string, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", "", fmt.Errorf("invalid GitHub Enterprise URL %q", rawURL)
+	}
+	root := strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), "/api/v3")
+
+	baseURL, uploadURL := *u, *u
+	baseURL.Path = root + "/api/v3/"
+	uploadURL.Path = root + "/api/uploads/"
+	return baseURL.String(), uploadURL.String(), nil
 }
 
 // WithGithubClient creates a new GitProvider with a GitHub client
-func WithGithubClient(ctx context.Context, client *github.Client, id *auth.ID) GitProvider {
+func WithGithubClient(client *github.Client, id *auth.ID) GitProvider {
 	return &GithubProvider{
 		Client:    client,
-		Context:   ctx,
 		ID:        id,
-		retries:   5,
-		Repocache: make(map[string]*CachedRepo),
+		Repocache: make(RepoCache),
 	}
 }
 
 // CreateRepository creates a new GitHub repository
-func (g *GithubProvider) CreateRepository(srcRepo *GitRepository) (*GitRepository, error) {
+func (g *GithubProvider) CreateRepository(ctx context.Context, srcRepo *GitRepository) (*GitRepository, error) {
 	cachedrepo, ok := g.Repocache[srcRepo.Name]
 	if ok {
 		return cachedrepo.Repo, nil
@@ -81,62 +124,75 @@ func (g *GithubProvider) CreateRepository(srcRepo *GitRepository) (*GitRepositor
 		Archived:    github.Bool(srcRepo.Archived),
 	}
 
-	r, _, err := g.Client.Repositories.Create(g.Context, g.ID.Owner, repo)
+	r, _, err := g.Client.Repositories.Create(ctx, g.ID.Owner, repo)
 	if err == nil {
 		newrepo := fromGithubRepo(r)
 		g.NewCachedRepo(newrepo)
 		return newrepo, nil
 	}
 
-	abuseRateLimitError, ok := err.(*github.AbuseRateLimitError)
-	if ok {
-		time.Sleep(abuseRateLimitError.GetRetryAfter())
-		return g.CreateRepository(srcRepo)
-	}
-
 	return nil, fmt.Errorf("failed to create repository %s/%s due to: %s", g.ID.Owner, srcRepo.Name, err)
 }
 
 func fromGithubRepo(repo *github.Repository) *GitRepository {
+	visibility := "public"
+	if repo.GetPrivate() {
+		visibility = "private"
+	}
 	return &GitRepository{
 		Name:        repo.GetName(),
 		Description: repo.GetDescription(),
 		CloneURL:    repo.GetCloneURL(),
 		SSHURL:      repo.GetSSHURL(),
+		Owner:       repo.GetOwner().GetLogin(),
 		Archived:    repo.GetArchived(),
 		Fork:        repo.GetFork(),
 		Empty:       repo.GetSize() == 0,
 		PID:         int(repo.GetID()),
+
+		Topics:       repo.Topics,
+		Visibility:   visibility,
+		LastActivity: repo.GetPushedAt().Time,
 	}
 }
 
 // RepositoryExists checks if a given repostory already exists in GitHub
-func (g *GithubProvider) RepositoryExists(name string) bool {
-	_, _, err := g.Client.Repositories.Get(g.Context, g.ID.Owner, name)
+func (g *GithubProvider) RepositoryExists(ctx context.Context, name string) bool {
+	_, _, err := g.Client.Repositories.Get(ctx, g.ID.Owner, name)
 	return err == nil
 }
 
 // CreateIssue creates a new GitHub issue
-func (g *GithubProvider) CreateIssue(issue *GitIssue) (*GitIssue, error) {
+func (g *GithubProvider) CreateIssue(ctx context.Context, issue *GitIssue) (*GitIssue, error) {
 	issueRequest := &github.IssueRequest{
 		Title:  github.String(strings.TrimSpace(issue.Title)),
 		Body:   github.String(strings.TrimSpace(issue.Body)),
-		State:  github.String(strings.TrimSpace(issue.State)),
 		Labels: ToGitLabelStringSlice(issue.Labels),
 	}
 	if issue.Assignees != nil && len(issue.Assignees) > 0 {
-		var assignees []string
-		for _, assignee := range issue.Assignees {
-			assignees = append(assignees, g.Members[assignee.Email].GetLogin())
+		assignees, err := g.getAssigneeLogins(ctx, issue.Assignees)
+		if err != nil {
+			return nil, err
 		}
-		issueRequest.Assignees = &assignees
+		issueRequest.Assignees = assignees
 	}
 
-	result, _, err := g.Client.Issues.Create(g.Context, g.ID.Owner, issue.Repo, issueRequest)
+	result, _, err := g.Client.Issues.Create(ctx, g.ID.Owner, issue.Repo, issueRequest)
 	if err != nil {
 		return nil, err
 	}
 
+	// issues are always created open, closing them takes a second request
+	if issue.State == "closed" && result.GetState() != "closed" {
+		number := result.GetNumber()
+		result, _, err = g.Client.Issues.Edit(ctx, g.ID.Owner, issue.Repo, number, &github.IssueRequest{
+			State: github.String("closed"),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to close issue %d in %s/%s due to: %v", number, g.ID.Owner, issue.Repo, err)
+		}
+	}
+
 	number := 0
 	if result.Number != nil {
 		number = result.GetNumber()
@@ -144,6 +200,208 @@ func (g *GithubProvider) CreateIssue(issue *GitIssue) (*GitIssue, error) {
 	return fromGithubIssue(number, result), nil
 }
 
+// UpdateIssue replaces the title, body, state and labels of a GitHub issue
+func (g *GithubProvider) UpdateIssue(ctx context.Context, issue *GitIssue) error {
+	state := "open"
+	if issue.State == "closed" {
+		state = "closed"
+	}
+	issueRequest := &github.IssueRequest{
+		Title:  github.String(strings.TrimSpace(issue.Title)),
+		Body:   github.String(strings.TrimSpace(issue.Body)),
+		State:  github.String(state),
+		Labels: ToGitLabelStringSlice(issue.Labels),
+	}
+	if _, _, err := g.Client.Issues.Edit(ctx, g.ID.Owner, issue.Repo, issue.Number, issueRequest); err != nil {
+		return fmt.Errorf("failed to update issue %d in %s/%s due to: %v", issue.Number, g.ID.Owner, issue.Repo, err)
+	}
+	return nil
+}
+
+// getAssigneeLogins maps users to the logins of the organization members sharing their email
+func (g *GithubProvider) getAssigneeLogins(ctx context.Context, users []GitUser) (*[]string, error) {
+	members, err := g.getMemberMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var assignees []string
+	for _, assignee := range users {
+		if login := members[assignee.Email].GetLogin(); login != "" {
+			assignees = append(assignees, login)
+		}
+	}
+	return &assignees, nil
+}
+
+// CreatePullRequest recreates a pull request when both of its branches exist and otherwise falls back to an issue
+func (g *GithubProvider) CreatePullRequest(ctx context.Context, pr *GitPullRequest) (*GitPullRequest, error) {
+	if g.branchExists(ctx, pr.Repo, pr.SourceBranch) && g.branchExists(ctx, pr.Repo, pr.TargetBranch) {
+		created, err := g.createPullRequest(ctx, pr)
+		if err == nil {
+			return created, nil
+		}
+		logrus.WithFields(logrus.Fields{
+			"repo":   pr.Repo,
+			"number": pr.Number,
+		}).Warnf("failed to create pull request, creating an issue instead: %v", err)
+	}
+
+	issue, err := g.CreateIssue(ctx, PullRequestAsIssue(pr))
+	if err != nil {
+		return nil, err
+	}
+
+	created := *pr
+	created.Number = issue.Number
+	created.IsIssue = true
+	return &created, nil
+}
+
+func (g *GithubProvider) branchExists(ctx context.Context, repo, branch string) bool {
+	if branch == "" {
+		return false
+	}
+	_, _, err := g.Client.Repositories.GetBranch(ctx, g.ID.Owner, repo, branch)
+	return err == nil
+}
+
+func (g *GithubProvider) createPullRequest(ctx context.Context, pr *GitPullRequest) (*GitPullRequest, error) {
+	result, _, err := g.Client.PullRequests.Create(ctx, g.ID.Owner, pr.Repo, &github.NewPullRequest{
+		Title: github.String(strings.TrimSpace(pr.Title)),
+		Head:  github.String(pr.SourceBranch),
+		Base:  github.String(pr.TargetBranch),
+		Body:  github.String(strings.TrimSpace(pr.Body)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	number := result.GetNumber()
+
+	// labels and assignees of pull requests are managed through the issues API
+	issueRequest := &github.IssueRequest{
+		Labels: ToGitLabelStringSlice(pr.Labels),
+	}
+	if len(pr.Assignees) > 0 {
+		assignees, err := g.getAssigneeLogins(ctx, pr.Assignees)
+		if err != nil {
+			return nil, err
+		}
+		issueRequest.Assignees = assignees
+	}
+	if _, _, err := g.Client.Issues.Edit(ctx, g.ID.Owner, pr.Repo, number, issueRequest); err != nil {
+		return nil, fmt.Errorf("failed to label pull request %s#%d due to: %v", pr.Repo, number, err)
+	}
+
+	if pr.State != "open" {
+		_, _, err := g.Client.PullRequests.Edit(ctx, g.ID.Owner, pr.Repo, number, &github.PullRequest{
+			State: github.String("closed"),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to close pull request %s#%d due to: %v", pr.Repo, number, err)
+		}
+	}
+
+	created := *pr
+	created.Number = number
+	created.HeadSHA = result.GetHead().GetSHA()
+	return &created, nil
+}
+
+// CreateReviewComment creates a pull request comment, positioned on the diff when the commented line is part of it
+func (g *GithubProvider) CreateReviewComment(ctx context.Context, pr *GitPullRequest, comment *GitReviewComment) error {
+	if !pr.IsIssue && comment.Path != "" {
+		patch, err := g.getPatch(ctx, pr, comment.Path)
+		if err != nil {
+			return err
+		}
+		if position, ok := diffPosition(patch, comment.Line, comment.OldLine); ok {
+			_, _, err := g.Client.PullRequests.CreateComment(ctx, g.ID.Owner, pr.Repo, pr.Number, &github.PullRequestComment{
+				Body:     github.String(strings.TrimSpace(comment.Body)),
+				CommitID: github.String(pr.HeadSHA),
+				Path:     github.String(comment.Path),
+				Position: github.Int(position),
+			})
+			return err
+		}
+	}
+
+	return g.CreateIssueComment(ctx, pr.Number, ReviewCommentAsIssueComment(pr.Number, comment))
+}
+
+// getPatch loads the patch of one file of a pull request, caching every file of the pull request
+func (g *GithubProvider) getPatch(ctx context.Context, pr *GitPullRequest, path string) (string, error) {
+	g.patchesMu.Lock()
+	defer g.patchesMu.Unlock()
+
+	key := fmt.Sprintf("%s#%d", pr.Repo, pr.Number)
+	if patches, ok := g.patches[key]; ok {
+		return patches[path], nil
+	}
+
+	patches := make(map[string]string)
+	_, err := g.depaginate(func(opts github.ListOptions) (*github.Response, error) {
+		files, resp, err := g.Client.PullRequests.ListFiles(ctx, g.ID.Owner, pr.Repo, pr.Number, &opts)
+
+		for _, file := range files {
+			patches[file.GetFilename()] = file.GetPatch()
+		}
+		return resp, err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list files of pull request %s due to: %v", key, err)
+	}
+
+	if g.patches == nil {
+		g.patches = make(map[string]map[string]string)
+	}
+	g.patches[key] = patches
+	return patches[path], nil
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
+// diffPosition finds the position GitHub expects for a comment on a new line, or on an old line when line is 0
+// Positions count the lines below the first hunk header of a file's patch.
+func diffPosition(patch string, line, oldLine int) (int, bool) {
+	if line == 0 && oldLine == 0 {
+		return 0, false
+	}
+
+	var oldNum, newNum int
+	for position, text := range strings.Split(patch, "\n") {
+		if m := hunkHeader.FindStringSubmatch(text); m != nil {
+			oldNum, _ = strconv.Atoi(m[1])
+			newNum, _ = strconv.Atoi(m[2])
+			continue
+		}
+		if position == 0 {
+			return 0, false
+		}
+
+		switch {
+		case strings.HasPrefix(text, "\\"):
+			// "\ No newline at end of file" markers take a position without being a line
+		case strings.HasPrefix(text, "-"):
+			if line == 0 && oldNum == oldLine {
+				return position, true
+			}
+			oldNum++
+		case strings.HasPrefix(text, "+"):
+			if newNum == line {
+				return position, true
+			}
+			newNum++
+		default:
+			if line != 0 && newNum == line || line == 0 && oldNum == oldLine {
+				return position, true
+			}
+			oldNum++
+			newNum++
+		}
+	}
+	return 0, false
+}
+
 func fromGithubIssue(number int, issue *github.Issue) *GitIssue {
 	labels := []GitLabel{}
 	if issue.Labels != nil && len(issue.Labels) > 0 {
@@ -180,47 +438,35 @@ func fromGithubUser(user *github.User) *GitUser {
 }
 
 // CreateIssueComment creates a new GitHub issue comment
-func (g *GithubProvider) CreateIssueComment(issueNum int, comment *GitIssueComment) error {
+func (g *GithubProvider) CreateIssueComment(ctx context.Context, issueNum int, comment *GitIssueComment) error {
 	issueComment := &github.IssueComment{
 		User:      g.Members[comment.User.Email],
 		Body:      github.String(strings.TrimSpace(comment.Body)),
 		CreatedAt: &comment.CreatedAt,
 		UpdatedAt: &comment.UpdatedAt,
 	}
-	_, _, err := g.Client.Issues.CreateComment(g.Context, g.ID.Owner, comment.Repo, issueNum, issueComment)
+	_, _, err := g.Client.Issues.CreateComment(ctx, g.ID.Owner, comment.Repo, issueNum, issueComment)
 
 	if err == nil {
 		return nil
 	}
 
-	abuseRateLimitError, ok := err.(*github.AbuseRateLimitError)
-	if ok {
-		time.Sleep(abuseRateLimitError.GetRetryAfter())
-		return g.CreateIssueComment(issueNum, comment)
-	}
-
 	return err
 }
 
 // CreateLabel creates a new GitHub issue label
-func (g *GithubProvider) CreateLabel(srcLabel *GitLabel) (*GitLabel, error) {
+func (g *GithubProvider) CreateLabel(ctx context.Context, srcLabel *GitLabel) (*GitLabel, error) {
 	label := &github.Label{
 		Name:        github.String(strings.TrimSpace(srcLabel.Name)),
 		Color:       github.String(strings.Trim(srcLabel.Color, "#\r\n\t")),
 		Description: github.String(strings.TrimSpace(srcLabel.Description)),
 	}
 
-	result, _, err := g.Client.Issues.CreateLabel(g.Context, g.ID.Owner, srcLabel.Repo, label)
+	result, _, err := g.Client.Issues.CreateLabel(ctx, g.ID.Owner, srcLabel.Repo, label)
 	if err == nil {
 		return fromGithubLabel(result), nil
 	}
 
-	abuseRateLimitError, ok := err.(*github.AbuseRateLimitError)
-	if ok {
-		time.Sleep(abuseRateLimitError.GetRetryAfter())
-		return g.CreateLabel(srcLabel)
-	}
-
 	return nil, err
 
 }
@@ -233,8 +479,58 @@ func fromGithubLabel(label *github.Label) *GitLabel {
 	}
 }
 
+// DeleteRepository deletes a GitHub repository and drops it from the cache
+func (g *GithubProvider) DeleteRepository(ctx context.Context, repo *GitRepository) error {
+	if _, err := g.Client.Repositories.Delete(ctx, g.ID.Owner, repo.Name); err != nil {
+		return fmt.Errorf("failed to delete repository %s/%s due to: %v", g.ID.Owner, repo.Name, err)
+	}
+	delete(g.Repocache, repo.Name)
+	return nil
+}
+
+// ArchiveRepository makes a GitHub repository read-only
+func (g *GithubProvider) ArchiveRepository(ctx context.Context, repo *GitRepository) error {
+	_, _, err := g.Client.Repositories.Edit(ctx, g.ID.Owner, repo.Name, &github.Repository{Archived: github.Bool(true)})
+	if err != nil {
+		return fmt.Errorf("failed to archive repository %s/%s due to: %v", g.ID.Owner, repo.Name, err)
+	}
+	return nil
+}
+
+// UpdateLabel replaces the color and description of a GitHub issue label
+func (g *GithubProvider) UpdateLabel(ctx context.Context, label *GitLabel) error {
+	edit := &github.Label{
+		Color:       github.String(strings.Trim(label.Color, "#\r\n\t")),
+		Description: github.String(strings.TrimSpace(label.Description)),
+	}
+	if _, _, err := g.Client.Issues.EditLabel(ctx, g.ID.Owner, label.Repo, label.Name, edit); err != nil {
+		return fmt.Errorf("failed to update label %s in %s/%s due to: %v", label.Name, g.ID.Owner, label.Repo, err)
+	}
+	return nil
+}
+
+// DeleteLabel deletes a GitHub issue label
+func (g *GithubProvider) DeleteLabel(ctx context.Context, label *GitLabel) error {
+	if _, err := g.Client.Issues.DeleteLabel(ctx, g.ID.Owner, label.Repo, label.Name); err != nil {
+		return fmt.Errorf("failed to delete label %s from %s/%s due to: %v", label.Name, g.ID.Owner, label.Repo, err)
+	}
+	return nil
+}
+
+// DeleteIssue closes and locks a GitHub issue since the REST API cannot delete issues
+func (g *GithubProvider) DeleteIssue(ctx context.Context, issue *GitIssue) error {
+	_, _, err := g.Client.Issues.Edit(ctx, g.ID.Owner, issue.Repo, issue.Number, &github.IssueRequest{State: github.String("closed")})
+	if err != nil {
+		return fmt.Errorf("failed to close issue %d in %s/%s due to: %v", issue.Number, g.ID.Owner, issue.Repo, err)
+	}
+	if _, err := g.Client.Issues.Lock(ctx, g.ID.Owner, issue.Repo, issue.Number, nil); err != nil {
+		return fmt.Errorf("failed to lock issue %d in %s/%s due to: %v", issue.Number, g.ID.Owner, issue.Repo, err)
+	}
+	return nil
+}
+
 // MigrateRepo migrates a repo from an existing provider into GitHub
-func (g *GithubProvider) MigrateRepo(repo *GitRepository, token string) (string, error) {
+func (g *GithubProvider) MigrateRepo(ctx context.Context, repo *GitRepository, token string) (string, error) {
 	// Must create repository before running import
 	repoImport := &github.Import{
 		VCS:         github.String("git"),
@@ -242,85 +538,37 @@ func (g *GithubProvider) MigrateRepo(repo *GitRepository, token string) (string,
 		VCSUsername: github.String(repo.Owner),
 		VCSPassword: github.String(token),
 	}
-	result, _, err := g.Client.Migrations.StartImport(g.Context, g.ID.Owner, repo.Name, repoImport)
+	result, _, err := g.Client.Migrations.StartImport(ctx, g.ID.Owner, repo.Name, repoImport)
 	if err == nil {
 		return result.GetStatus(), nil
 	}
 
-	abuseRateLimitError, ok := err.(*github.AbuseRateLimitError)
-	if ok {
-		time.Sleep(abuseRateLimitError.GetRetryAfter())
-		return g.MigrateRepo(repo, token)
-	}
-
 	return "", err
 }
 
 // GetImportProgress checks the progress of a previously started GitHub import
-func (g *GithubProvider) GetImportProgress(repoName string) (string, error) {
-	migration, _, err := g.Client.Migrations.ImportProgress(g.Context, g.ID.Owner, repoName)
+func (g *GithubProvider) GetImportProgress(ctx context.Context, repoName string) (string, error) {
+	migration, _, err := g.Client.Migrations.ImportProgress(ctx, g.ID.Owner, repoName)
 	if err != nil {
 		return "", err
 	}
 	return migration.GetStatus(), nil
 }
 
-// type retryAbort struct{ error }
-
-// func (r *retryAbort) Error() string {
-// 	return fmt.Sprintf("aborting retry loop: %v", r.error)
-// }
-
-// func (g *GithubProvider) sleepForAttempt(retryCount int) {
-// 	maxDelay := 20 * time.Second
-// 	delay := time.Second * time.Duration(math.Exp2(float64(retryCount)))
-// 	if delay > maxDelay {
-// 		delay = maxDelay
-// 	}
-// 	time.Sleep(delay)
-// }
-
-// func (g *GithubProvider) retry(action string, call func() (*github.Response, error)) (*github.Response, error) {
-// 	var err error
-// 	var resp *github.Response
-
-// 	for retryCount := 0; retryCount <= g.retries; retryCount++ {
-// 		if resp, err = call(); err == nil {
-// 			return resp, nil
-// 		}
-// 		switch err := err.(type) {
-// 		case *github.RateLimitError:
-// 			return resp, err
-// 		case *github.TwoFactorAuthError:
-// 			return resp, err
-// 		case *retryAbort:
-// 			return resp, err
-// 		}
-
-// 		if retryCount == g.retries {
-// 			return resp, err
-// 		}
-// 		logrus.Errorf("error %s: %v. Retrying...\n", action, err)
-
-// 		g.sleepForAttempt(retryCount)
-// 	}
-// 	return resp, err
-// }
-
 // GetAuth returns a string with a user's api authentication token
 func (g *GithubProvider) GetAuth() *auth.ID {
 	return g.ID
 }
 
 // GetRepositories retrieves a list of GitHub repositories for the organization/owner
-func (g *GithubProvider) GetRepositories() ([]*GitRepository, error) {
+func (g *GithubProvider) GetRepositories(ctx context.Context) ([]*GitRepository, error) {
 	repoOpts := github.RepositoryListByOrgOptions{}
 	var result []*github.Repository
 
 	_, err := g.depaginate(func(opts github.ListOptions) (*github.Response, error) {
 		repoOpts.ListOptions = opts
 
-		repos, resp, err := g.Client.Repositories.ListByOrg(g.Context, g.ID.Owner, &repoOpts)
+		repos, resp, err := g.Client.Repositories.ListByOrg(ctx, g.ID.Owner, &repoOpts)
 
 		result = append(result, repos...)
 		return resp, err
@@ -333,7 +581,7 @@ func (g *GithubProvider) GetRepositories() ([]*GitRepository, error) {
 	// repoListOpts := github.RepositoryListOptions{}
 	// _, err = g.depaginate(func(opts github.ListOptions) (*github.Response, error) {
 	// 	repoListOpts.ListOptions = opts
-	// 	repos, resp, err := g.Client.Repositories.List(g.Context, "", &repoListOpts)
+	// 	repos, resp, err := g.Client.Repositories.List(ctx, "", &repoListOpts)
 
 	// 	result = append(result, repos...)
 	// 	return resp, err
@@ -351,16 +599,17 @@ func (g *GithubProvider) GetRepositories() ([]*GitRepository, error) {
 }
 
 // GetIssues retrieves a list of issues associated with a GitHub repository
-func (g *GithubProvider) GetIssues(pid int, repo string) ([]*GitIssue, error) {
-	issueOpts := github.IssueListByRepoOptions{
-		State: "all",
-	}
+func (g *GithubProvider) GetIssues(ctx context.Context, pid int, repo string) ([]*GitIssue, error) {
+	return g.listIssues(ctx, pid, repo, github.IssueListByRepoOptions{State: "all"})
+}
 
+// listIssues retrieves the issues of a GitHub repository matching issueOpts
+func (g *GithubProvider) listIssues(ctx context.Context, pid int, repo string, issueOpts github.IssueListByRepoOptions) ([]*GitIssue, error) {
 	var result []*github.Issue
 	_, err := g.depaginate(func(opts github.ListOptions) (*github.Response, error) {
 		issueOpts.ListOptions = opts
 
-		issues, resp, err := g.Client.Issues.ListByRepo(g.Context, g.ID.Owner, repo, &issueOpts)
+		issues, resp, err := g.Client.Issues.ListByRepo(ctx, g.ID.Owner, repo, &issueOpts)
 
 		result = append(result, issues...)
 		return resp, err
@@ -373,19 +622,27 @@ func (g *GithubProvider) GetIssues(pid int, repo string) ([]*GitIssue, error) {
 	var issues []*GitIssue
 
 	for _, issue := range result {
-		issues = append(issues, fromGithubIssue(issue.GetNumber(), issue))
+		gitissue := fromGithubIssue(issue.GetNumber(), issue)
+		gitissue.Repo = repo
+		gitissue.PID = pid
+		issues = append(issues, gitissue)
 	}
 
 	return issues, nil
 
 }
 
+// GetIssuesUpdatedAfter retrieves the issues of a GitHub repository updated after since
+func (g *GithubProvider) GetIssuesUpdatedAfter(ctx context.Context, pid int, repo string, since time.Time) ([]*GitIssue, error) {
+	return g.listIssues(ctx, pid, repo, github.IssueListByRepoOptions{State: "all", Since: since})
+}
+
 // GetComments retrieves a list of issue comments associated with a GitHub issue
-func (g *GithubProvider) GetComments(pid, issueNum int, repo string) ([]*GitIssueComment, error) {
+func (g *GithubProvider) GetComments(ctx context.Context, pid, issueNum int, repo string) ([]*GitIssueComment, error) {
 	var list []*github.IssueComment
 	_, err := g.depaginate(func(opts github.ListOptions) (*github.Response, error) {
 		comments, resp, err := g.Client.Issues.ListComments(
-			g.Context,
+			ctx,
 			g.ID.Owner,
 			repo,
 			issueNum,
@@ -410,6 +667,7 @@ func (g *GithubProvider) GetComments(pid, issueNum int, repo string) ([]*GitIssu
 
 func fromGithubComment(repo string, issueNum int, comment *github.IssueComment) *GitIssueComment {
 	return &GitIssueComment{
+		ID:        int(comment.GetID()),
 		Repo:      repo,
 		IssueNum:  issueNum,
 		User:      *fromGithubUser(comment.User),
@@ -420,11 +678,11 @@ func fromGithubComment(repo string, issueNum int, comment *github.IssueComment)
 }
 
 // GetLabels retrieves a list of labels associated with a GitHub repository
-func (g *GithubProvider) GetLabels(pid int, repo string) ([]*GitLabel, error) {
+func (g *GithubProvider) GetLabels(ctx context.Context, pid int, repo string) ([]*GitLabel, error) {
 	var list []*github.Label
 
 	_, err := g.depaginate(func(opts github.ListOptions) (*github.Response, error) {
-		labels, resp, err := g.Client.Issues.ListLabels(g.Context, g.ID.Owner, repo, &opts)
+		labels, resp, err := g.Client.Issues.ListLabels(ctx, g.ID.Owner, repo, &opts)
 
 		list = append(list, labels...)
 		return resp, err
@@ -436,148 +694,182 @@ func (g *GithubProvider) GetLabels(pid int, repo string) ([]*GitLabel, error) {
 
 	var labels []*GitLabel
 	for _, label := range list {
-		labels = append(labels, fromGithubLabel(label))
+		gitlabel := fromGithubLabel(label)
+		gitlabel.Repo = repo
+		labels = append(labels, gitlabel)
 	}
 
 	return labels, nil
 }
 
-func (g *GithubProvider) getMembers() ([]*github.User, error) {
-	memberOpts := github.ListMembersOptions{}
-	var users []*github.User
+// GetRefs retrieves the branches and tags of a GitHub repository along with the commits they point at
+func (g *GithubProvider) GetRefs(ctx context.Context, pid int, repo string) ([]*GitRef, error) {
+	var refs []*GitRef
 
 	_, err := g.depaginate(func(opts github.ListOptions) (*github.Response, error) {
-		memberOpts.ListOptions = opts
-		members, resp, err := g.Client.Organizations.ListMembers(g.Context, g.ID.Owner, &memberOpts)
-
-		users = append(users, members...)
-
+		branches, resp, err := g.Client.Repositories.ListBranches(ctx, g.ID.Owner, repo, &opts)
+		for _, branch := range branches {
+			refs = append(refs, &GitRef{Name: branch.GetName(), SHA: branch.GetCommit().GetSHA()})
+		}
 		return resp, err
 	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches of %s/%s due to: %v", g.ID.Owner, repo, err)
+	}
 
+	_, err = g.depaginate(func(opts github.ListOptions) (*github.Response, error) {
+		tags, resp, err := g.Client.Repositories.ListTags(ctx, g.ID.Owner, repo, &opts)
+		for _, tag := range tags {
+			refs = append(refs, &GitRef{Name: tag.GetName(), Tag: true, SHA: tag.GetCommit().GetSHA()})
+		}
+		return resp, err
+	})
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to list tags of %s/%s due to: %v", g.ID.Owner, repo, err)
 	}
 
-	return users, nil
+	return refs, nil
 }
 
-func (g *GithubProvider) setMemberMap(members []*github.User) {
-	g.Members = make(map[string]*github.User)
-	for _, member := range members {
-		if email := member.GetEmail(); email != "" {
-			g.Members[email] = member
-		}
-	}
+// GetPullRequests returns no pull requests since reading them from GitHub is not supported
+func (g *GithubProvider) GetPullRequests(ctx context.Context, pid int, repo string) ([]*GitPullRequest, error) {
+	return []*GitPullRequest{}, nil
 }
 
-func (g *GithubProvider) LoadCache() error {
-	repos, err := g.GetRepositories()
-	if err != nil {
-		return err
-	}
-	members, err := g.getMembers()
-	if err != nil {
-		return err
-	}
+// GetReviewComments returns no comments since reading pull requests from GitHub is not supported
+func (g *GithubProvider) GetReviewComments(ctx context.Context, pid, pullNum int, repo string) ([]*GitReviewComment, error) {
+	return []*GitReviewComment{}, nil
+}
 
-	g.setMemberMap(members)
+// GetMembers is not supported since members are only migrated into GitHub
+func (g *GithubProvider) GetMembers(ctx context.Context, repo *GitRepository) ([]*GitMember, error) {
+	return nil, fmt.Errorf("github GetMembers not supported")
+}
 
-	wg := sync.WaitGroup{}
-	for _, repo := range repos {
-		cachedrepo := &CachedRepo{
-			Repo:    repo,
-			issueMu: sync.RWMutex{},
-			Issues:  make(map[string]*CachedIssue),
-			labelMu: sync.RWMutex{},
-			Labels:  make(map[string]*GitLabel),
+// GrantAccess gives the members of a source repository access to its GitHub repository
+// Every member becomes a collaborator with the strongest permission any of their groups or their direct
+// membership grants, like GitLab does. Teams are not used since GitHub gives the members of a child team
+// access to every repository of its parent, which subgroup members do not have in GitLab.
+// Adding a collaborator who is not an org member sends them an invitation.
+func (g *GithubProvider) GrantAccess(ctx context.Context, repo *GitRepository, members []*GitMember) error {
+	strongest := make(map[string]*GitMember)
+	var logins []string
+	for _, member := range members {
+		existing, ok := strongest[member.User.Login]
+		if !ok {
+			logins = append(logins, member.User.Login)
 		}
+		if !ok || !existing.Permission.Includes(member.Permission) {
+			strongest[member.User.Login] = member
+		}
+	}
 
-		wg.Add(1)
-
-		go func(cachedrepo *CachedRepo) {
-			defer wg.Done()
-			g.fillIssues(cachedrepo)
-			g.fillLabels(cachedrepo)
-		}(cachedrepo)
+	for _, login := range logins {
+		if err := g.addCollaborator(ctx, repo.Name, strongest[login]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-		g.Repocache[repo.Name] = cachedrepo
+// addCollaborator grants a single member access to a repository
+func (g *GithubProvider) addCollaborator(ctx context.Context, repo string, member *GitMember) error {
+	opts := &github.RepositoryAddCollaboratorOptions{Permission: toGithubPermission(member.Permission)}
+	if _, err := g.Client.Repositories.AddCollaborator(ctx, g.ID.Owner, repo, member.User.Login, opts); err != nil {
+		return fmt.Errorf("failed to add %s as collaborator of %s due to: %v", member.User.Login, repo, err)
 	}
-	wg.Wait()
 	return nil
 }
 
-func (g *GithubProvider) NewCachedRepo(repo *GitRepository) {
-	g.Repocache[repo.Name] = &CachedRepo{
-		Repo:    repo,
-		issueMu: sync.RWMutex{},
-		Issues:  make(map[string]*CachedIssue),
-		labelMu: sync.RWMutex{},
-		Labels:  make(map[string]*GitLabel),
+// toGithubPermission converts a permission into the name GitHub's API uses for it
+func toGithubPermission(permission Permission) string {
+	switch permission {
+	case PermissionRead:
+		return "pull"
+	case PermissionWrite:
+		return "push"
 	}
+	return string(permission)
 }
 
-func (g *GithubProvider) fillIssues(cachedrepo *CachedRepo) error {
-	issues, err := g.GetIssues(cachedrepo.Repo.PID, cachedrepo.Repo.Name)
+func (g *GithubProvider) getMembers(ctx context.Context) ([]*github.User, error) {
+	memberOpts := github.ListMembersOptions{}
+	var users []*github.User
+
+	_, err := g.depaginate(func(opts github.ListOptions) (*github.Response, error) {
+		memberOpts.ListOptions = opts
+		members, resp, err := g.Client.Organizations.ListMembers(ctx, g.ID.Owner, &memberOpts)
+
+		users = append(users, members...)
+
+		return resp, err
+	})
+
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	for _, issue := range issues {
-		cacheissue := &CachedIssue{Issue: issue, Comments: make(map[time.Time]*GitIssueComment)}
-		comments, err := g.GetComments(cachedrepo.Repo.PID, issue.Number, cachedrepo.Repo.Name)
-		if err != nil {
-			return err
-		}
-
-		wg := sync.WaitGroup{}
+	return users, nil
+}
 
-		for _, comment := range comments {
-			wg.Add(1)
-			go func(comment *GitIssueComment) {
-				defer wg.Done()
+// getMemberMap lazily loads the organization members so that any source
+// provider can map assignees without loading the full cache first
+func (g *GithubProvider) getMemberMap(ctx context.Context) (map[string]*github.User, error) {
+	g.membersMu.Lock()
+	defer g.membersMu.Unlock()
 
-				cacheissue.commentMu.Lock()
-				cacheissue.Comments[comment.CreatedAt] = comment
-				cacheissue.commentMu.Unlock()
-			}(comment)
+	if g.Members == nil {
+		members, err := g.getMembers(ctx)
+		if err != nil {
+			return nil, err
 		}
-		wg.Wait()
-		cachedrepo.Issues[issue.Title] = cacheissue
+		g.setMemberMap(members)
 	}
-
-	return nil
+	return g.Members, nil
 }
 
-func (g *GithubProvider) NewCachedIssue(issue *GitIssue) *CachedIssue {
-	return &CachedIssue{
-		Issue:    issue,
-		Comments: make(map[time.Time]*GitIssueComment),
+func (g *GithubProvider) setMemberMap(members []*github.User) {
+	g.Members = make(map[string]*github.User)
+	for _, member := range members {
+		if email := member.GetEmail(); email != "" {
+			g.Members[email] = member
+		}
 	}
 }
 
-func (g *GithubProvider) fillLabels(cachedrepo *CachedRepo) error {
-	labels, err := g.GetLabels(cachedrepo.Repo.PID, cachedrepo.Repo.Name)
+// LoadCache fills the repository cache and the organization member map
+func (g *GithubProvider) LoadCache(ctx context.Context) error {
+	cache, err := LoadCache(ctx, g, defaultCacheWorkers)
 	if err != nil {
 		return err
 	}
+	g.Repocache = cache
 
-	wg := sync.WaitGroup{}
-
-	for _, label := range labels {
-		wg.Add(1)
-		go func(label *GitLabel) {
-			defer wg.Done()
+	return g.loadMembers(ctx)
+}
 
-			cachedrepo.labelMu.Lock()
-			cachedrepo.Labels[label.Name] = label
-			cachedrepo.labelMu.Unlock()
-		}(label)
+func (g *GithubProvider) loadMembers(ctx context.Context) error {
+	members, err := g.getMembers(ctx)
+	if err != nil {
+		return err
 	}
-	wg.Wait()
+
+	g.membersMu.Lock()
+	g.setMemberMap(members)
+	g.membersMu.Unlock()
 	return nil
 }
 
+// NewCachedRepo adds an empty repository to the cache
+func (g *GithubProvider) NewCachedRepo(repo *GitRepository) {
+	g.Repocache[repo.Name] = NewCachedRepo(repo)
+}
+
+// NewCachedIssue creates an empty cache entry for an issue
+func (g *GithubProvider) NewCachedIssue(issue *GitIssue) *CachedIssue {
+	return NewCachedIssue(issue)
+}
+
 func (g *GithubProvider) PrintCache() {
 	for k, v := range g.Repocache {
 		fmt.Printf("Print Repo - Key: %v, Value: %v\n", k, v.Repo.CloneURL)
@@ -9,7 +9,9 @@ import (
 	"net/url"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/artur-sak13/gitmv/auth"
 
@@ -45,7 +47,7 @@ func setup() (*GithubProvider, *http.ServeMux, string, func()) {
 	client.UploadURL = u
 
 	id := auth.NewAuthID(server.URL, "p", githubOrgName)
-	prov := WithGithubClient(context.Background(), client, id)
+	prov := WithGithubClient(client, id)
 
 	return prov.(*GithubProvider), mux, server.URL, server.Close
 }
@@ -80,7 +82,7 @@ func TestMigrateRepo(t *testing.T) {
 		fmt.Fprint(w, `{"status":"importing"}`)
 	})
 
-	got, err := prov.MigrateRepo(repo, "p")
+	got, err := prov.MigrateRepo(context.Background(), repo, "p")
 	if err != nil {
 		t.Errorf("StartImport returned error: %v", err)
 	}
@@ -100,7 +102,7 @@ func TestImportProgress(t *testing.T) {
 		fmt.Fprint(w, `{"status":"complete"}`)
 	})
 
-	got, err := prov.GetImportProgress("r")
+	got, err := prov.GetImportProgress(context.Background(), "r")
 	if err != nil {
 		t.Errorf("ImportProgress returned error: %v", err)
 	}
@@ -110,6 +112,305 @@ func TestImportProgress(t *testing.T) {
 	}
 }
 
+func TestNewGithubProvider_Enterprise(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	mux.HandleFunc("/api/v3/orgs/o/repos", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		testHeader(t, r, "Authorization", "Bearer p")
+		fmt.Fprintf(w, `[{"id":1,"name":"r","owner":{"login":"o"},"size":1,"clone_url":"%s/o/r.git","ssh_url":"git@%s:o/r.git"}]`, server.URL, host)
+	})
+
+	prov, err := NewGithubProvider(context.Background(), auth.NewAuthID(server.URL, "p", githubOrgName))
+	if err != nil {
+		t.Fatalf("NewGithubProvider returned error: %v", err)
+	}
+	if got, want := prov.(*GithubProvider).Client.UploadURL.String(), server.URL+"/api/uploads/"; got != want {
+		t.Errorf("UploadURL = %q, want %q", got, want)
+	}
+
+	got, err := prov.GetRepositories(context.Background())
+	if err != nil {
+		t.Fatalf("GetRepositories returned error: %v", err)
+	}
+	want := []*GitRepository{
+		{
+			Name:     "r",
+			CloneURL: server.URL + "/o/r.git",
+			SSHURL:   "git@" + host + ":o/r.git",
+			Owner:    "o",
+			PID:      1,
+
+			Visibility: "public",
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetRepositories = %+v, want %+v", got, want)
+	}
+	if got, want := toWikiURL(got[0].SSHURL), "git@"+host+":o/r.wiki.git"; got != want {
+		t.Errorf("toWikiURL = %q, want %q", got, want)
+	}
+}
+
+func TestGithubEnterpriseURLs(t *testing.T) {
+	tests := []struct {
+		url        string
+		wantBase   string
+		wantUpload string
+		wantErr    bool
+	}{
+		{
+			url:        "https://ghe.example.com",
+			wantBase:   "https://ghe.example.com/api/v3/",
+			wantUpload: "https://ghe.example.com/api/uploads/",
+		},
+		{
+			url:        "https://ghe.example.com/api/v3/",
+			wantBase:   "https://ghe.example.com/api/v3/",
+			wantUpload: "https://ghe.example.com/api/uploads/",
+		},
+		{
+			url:     "ghe.example.com",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		base, upload, err := githubEnterpriseURLs(tt.url)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("githubEnterpriseURLs(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+			continue
+		}
+		if base != tt.wantBase || upload != tt.wantUpload {
+			t.Errorf("githubEnterpriseURLs(%q) = %q, %q, want %q, %q", tt.url, base, upload, tt.wantBase, tt.wantUpload)
+		}
+	}
+
+	for _, u := range []string{"", "https://github.com", "https://api.github.com/"} {
+		if !isGithubDotCom(u) {
+			t.Errorf("isGithubDotCom(%q) = false, want true", u)
+		}
+	}
+}
+
+func TestCreateIssue_Closed(t *testing.T) {
+	prov, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/repos/o/r/issues", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		fmt.Fprint(w, `{"number":5,"state":"open","title":"t"}`)
+	})
+	closed := false
+	mux.HandleFunc("/repos/o/r/issues/5", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "PATCH")
+		v := new(github.IssueRequest)
+		json.NewDecoder(r.Body).Decode(v)
+		closed = v.GetState() == "closed"
+		fmt.Fprint(w, `{"number":5,"state":"closed","title":"t"}`)
+	})
+
+	got, err := prov.CreateIssue(context.Background(), &GitIssue{Repo: "r", Title: "t", State: "closed"})
+	if err != nil {
+		t.Fatalf("CreateIssue returned error: %v", err)
+	}
+	if !closed {
+		t.Errorf("CreateIssue did not close the issue")
+	}
+	if got.Number != 5 || got.State != "closed" {
+		t.Errorf("CreateIssue = %+v, want closed issue 5", got)
+	}
+}
+
+func TestCreatePullRequest(t *testing.T) {
+	prov, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/repos/o/r/branches/feature", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"name":"feature"}`)
+	})
+	mux.HandleFunc("/repos/o/r/branches/master", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"name":"master"}`)
+	})
+	mux.HandleFunc("/repos/o/r/pulls", func(w http.ResponseWriter, r *http.Request) {
+		v := new(github.NewPullRequest)
+		json.NewDecoder(r.Body).Decode(v)
+
+		testMethod(t, r, "POST")
+		want := &github.NewPullRequest{
+			Title: github.String("t"),
+			Head:  github.String("feature"),
+			Base:  github.String("master"),
+			Body:  github.String("b"),
+		}
+		if !reflect.DeepEqual(v, want) {
+			t.Errorf("Request body = %+v, want %+v", v, want)
+		}
+		fmt.Fprint(w, `{"number":3,"head":{"sha":"abc"}}`)
+	})
+	mux.HandleFunc("/repos/o/r/issues/3", func(w http.ResponseWriter, r *http.Request) {
+		v := new(github.IssueRequest)
+		json.NewDecoder(r.Body).Decode(v)
+
+		testMethod(t, r, "PATCH")
+		if want := []string{"bug"}; !reflect.DeepEqual(v.GetLabels(), want) {
+			t.Errorf("Labels = %+v, want %+v", v.GetLabels(), want)
+		}
+		fmt.Fprint(w, `{"number":3}`)
+	})
+	closed := false
+	mux.HandleFunc("/repos/o/r/pulls/3", func(w http.ResponseWriter, r *http.Request) {
+		v := new(github.PullRequest)
+		json.NewDecoder(r.Body).Decode(v)
+
+		testMethod(t, r, "PATCH")
+		closed = v.GetState() == "closed"
+		fmt.Fprint(w, `{"number":3,"state":"closed"}`)
+	})
+
+	got, err := prov.CreatePullRequest(context.Background(), &GitPullRequest{
+		Repo:         "r",
+		Number:       1,
+		Title:        "t",
+		Body:         "b",
+		State:        "closed",
+		SourceBranch: "feature",
+		TargetBranch: "master",
+		Labels:       []GitLabel{{Name: "bug"}},
+	})
+	if err != nil {
+		t.Fatalf("CreatePullRequest returned error: %v", err)
+	}
+	if got.Number != 3 || got.HeadSHA != "abc" || got.IsIssue {
+		t.Errorf("CreatePullRequest = %+v, want pull request 3 at abc", got)
+	}
+	if !closed {
+		t.Errorf("CreatePullRequest did not close the pull request")
+	}
+}
+
+func TestCreatePullRequest_Fallback(t *testing.T) {
+	prov, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/repos/o/r/branches/master", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"name":"master"}`)
+	})
+	mux.HandleFunc("/repos/o/r/branches/deleted", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"Branch not found"}`, http.StatusNotFound)
+	})
+	mux.HandleFunc("/repos/o/r/issues", func(w http.ResponseWriter, r *http.Request) {
+		v := new(github.IssueRequest)
+		json.NewDecoder(r.Body).Decode(v)
+
+		testMethod(t, r, "POST")
+		for _, want := range []string{"`deleted` into `master` (merged)", "| `a.go` | +2 | -1 |"} {
+			if !strings.Contains(v.GetBody(), want) {
+				t.Errorf("Body = %q, want it to contain %q", v.GetBody(), want)
+			}
+		}
+		fmt.Fprint(w, `{"number":9}`)
+	})
+	// GitHub ignores the state of new issues, so a merged pull request is closed afterwards
+	closed := false
+	mux.HandleFunc("/repos/o/r/issues/9", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "PATCH")
+		v := new(github.IssueRequest)
+		json.NewDecoder(r.Body).Decode(v)
+		closed = v.GetState() == "closed"
+		fmt.Fprint(w, `{"number":9,"state":"closed"}`)
+	})
+
+	got, err := prov.CreatePullRequest(context.Background(), &GitPullRequest{
+		Repo:         "r",
+		Number:       1,
+		Title:        "t",
+		State:        "merged",
+		SourceBranch: "deleted",
+		TargetBranch: "master",
+		Changes:      []GitFileChange{{OldPath: "a.go", NewPath: "a.go", Additions: 2, Deletions: 1}},
+	})
+	if err != nil {
+		t.Fatalf("CreatePullRequest returned error: %v", err)
+	}
+	if got.Number != 9 || !got.IsIssue {
+		t.Errorf("CreatePullRequest = %+v, want issue 9", got)
+	}
+	if !closed {
+		t.Errorf("CreatePullRequest did not close the issue")
+	}
+}
+
+func TestCreateReviewComment(t *testing.T) {
+	prov, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/repos/o/r/pulls/3/files", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `[{"filename":"a.go","patch":"@@ -1,2 +1,3 @@\n package a\n+\n+var x int"}]`)
+	})
+	mux.HandleFunc("/repos/o/r/pulls/3/comments", func(w http.ResponseWriter, r *http.Request) {
+		v := new(github.PullRequestComment)
+		json.NewDecoder(r.Body).Decode(v)
+
+		testMethod(t, r, "POST")
+		want := &github.PullRequestComment{
+			Body:     github.String("why?"),
+			CommitID: github.String("abc"),
+			Path:     github.String("a.go"),
+			Position: github.Int(3),
+		}
+		if !reflect.DeepEqual(v, want) {
+			t.Errorf("Request body = %+v, want %+v", v, want)
+		}
+		fmt.Fprint(w, `{"id":1}`)
+	})
+	mux.HandleFunc("/repos/o/r/issues/3/comments", func(w http.ResponseWriter, r *http.Request) {
+		v := new(github.IssueComment)
+		json.NewDecoder(r.Body).Decode(v)
+
+		testMethod(t, r, "POST")
+		if want := "> `b.go` line 7\n\nelsewhere"; v.GetBody() != want {
+			t.Errorf("Body = %q, want %q", v.GetBody(), want)
+		}
+		fmt.Fprint(w, `{"id":2}`)
+	})
+
+	pr := &GitPullRequest{Repo: "r", Number: 3, HeadSHA: "abc"}
+	if err := prov.CreateReviewComment(context.Background(), pr, &GitReviewComment{Repo: "r", Body: "why?", Path: "a.go", Line: 3}); err != nil {
+		t.Errorf("CreateReviewComment returned error: %v", err)
+	}
+	if err := prov.CreateReviewComment(context.Background(), pr, &GitReviewComment{Repo: "r", Body: "elsewhere", Path: "b.go", Line: 7}); err != nil {
+		t.Errorf("CreateReviewComment returned error: %v", err)
+	}
+}
+
+func TestDiffPosition(t *testing.T) {
+	patch := "@@ -1,3 +1,3 @@\n package a\n-var x int\n+var y int\n \n@@ -10,2 +10,3 @@ func f() {\n \treturn\n+\t// unreachable\n }"
+	tests := []struct {
+		name    string
+		line    int
+		oldLine int
+		want    int
+		wantOK  bool
+	}{
+		{name: "context line", line: 1, want: 1, wantOK: true},
+		{name: "added line", line: 2, want: 3, wantOK: true},
+		{name: "removed line", oldLine: 2, want: 2, wantOK: true},
+		{name: "second hunk", line: 11, want: 7, wantOK: true},
+		{name: "outside the diff", line: 5},
+		{name: "no position"},
+	}
+	for _, tt := range tests {
+		got, ok := diffPosition(patch, tt.line, tt.oldLine)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("%s: diffPosition = %d, %v, want %d, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
 func testMethod(t *testing.T, r *http.Request, want string) {
 	if got := r.Method; got != want {
 		t.Errorf("Request method: %v, want %v", got, want)
@@ -121,3 +422,162 @@ func testHeader(t *testing.T, r *http.Request, header string, want string) {
 		t.Errorf("Header.Get(%q) returned %q, want %q", header, got, want)
 	}
 }
+
+func TestGetRefs(t *testing.T) {
+	prov, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/repos/o/r/branches", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `[{"name":"master","commit":{"sha":"abc"}}]`)
+	})
+	mux.HandleFunc("/repos/o/r/tags", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `[{"name":"v1","commit":{"sha":"def"}}]`)
+	})
+
+	got, err := prov.GetRefs(context.Background(), 1, "r")
+	if err != nil {
+		t.Errorf("GetRefs returned error: %v", err)
+	}
+	want := []*GitRef{
+		{Name: "master", SHA: "abc"},
+		{Name: "v1", Tag: true, SHA: "def"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetRefs = %+v, want %+v", got, want)
+	}
+}
+
+func TestDeleteIssue(t *testing.T) {
+	prov, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/repos/o/r/issues/4", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "PATCH")
+		v := new(github.IssueRequest)
+		json.NewDecoder(r.Body).Decode(v)
+		if v.GetState() != "closed" {
+			t.Errorf("Request state = %q, want closed", v.GetState())
+		}
+		fmt.Fprint(w, `{"number":4,"state":"closed"}`)
+	})
+	locked := false
+	mux.HandleFunc("/repos/o/r/issues/4/lock", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "PUT")
+		locked = true
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := prov.DeleteIssue(context.Background(), &GitIssue{Repo: "r", Number: 4}); err != nil {
+		t.Errorf("DeleteIssue returned error: %v", err)
+	}
+	if !locked {
+		t.Errorf("DeleteIssue did not lock the issue")
+	}
+}
+
+func TestUpdateIssue(t *testing.T) {
+	prov, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/repos/o/r/issues", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		if got := r.URL.Query().Get("since"); got != "2019-01-01T00:00:00Z" {
+			t.Errorf("Request since = %q, want 2019-01-01T00:00:00Z", got)
+		}
+		fmt.Fprint(w, `[{"number":4,"state":"open","title":"t"}]`)
+	})
+	mux.HandleFunc("/repos/o/r/issues/4", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "PATCH")
+		v := new(github.IssueRequest)
+		json.NewDecoder(r.Body).Decode(v)
+		if v.GetState() != "closed" || v.GetTitle() != "t" || !reflect.DeepEqual(v.GetLabels(), []string{"bug"}) {
+			t.Errorf("Request = %+v, want a closed issue titled t labeled bug", v)
+		}
+		fmt.Fprint(w, `{"number":4,"state":"closed"}`)
+	})
+
+	issues, err := prov.GetIssuesUpdatedAfter(context.Background(), 0, "r", time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Errorf("GetIssuesUpdatedAfter returned error: %v", err)
+	}
+	if len(issues) != 1 || issues[0].Number != 4 {
+		t.Errorf("GetIssuesUpdatedAfter = %+v, want issue 4", issues)
+	}
+
+	update := &GitIssue{Repo: "r", Number: 4, Title: "t", State: "closed", Labels: []GitLabel{{Name: "bug"}}}
+	if err := prov.UpdateIssue(context.Background(), update); err != nil {
+		t.Errorf("UpdateIssue returned error: %v", err)
+	}
+}
+
+func TestUpdateLabel(t *testing.T) {
+	prov, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/repos/o/r/labels/bug", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "PATCH")
+		v := new(github.Label)
+		json.NewDecoder(r.Body).Decode(v)
+		if v.GetColor() != "ff0000" || v.GetDescription() != "broken" {
+			t.Errorf("Request = %+v, want color ff0000 and description broken", v)
+		}
+		fmt.Fprint(w, `{"name":"bug","color":"ff0000","description":"broken"}`)
+	})
+
+	if err := prov.UpdateLabel(context.Background(), &GitLabel{Repo: "r", Name: "bug", Color: "#ff0000", Description: "broken"}); err != nil {
+		t.Errorf("UpdateLabel returned error: %v", err)
+	}
+}
+
+func TestGrantAccess(t *testing.T) {
+	prov, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/orgs/o/teams", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("GrantAccess used teams, which leak the repositories of parent teams to child teams")
+	})
+	collaborators := make(map[string]map[string]string)
+	mux.HandleFunc("/repos/o/", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "PUT")
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/repos/o/"), "/")
+		if len(parts) != 3 || parts[1] != "collaborators" {
+			t.Errorf("Request path = %v, want a collaborator", r.URL.Path)
+			return
+		}
+		v := new(github.RepositoryAddCollaboratorOptions)
+		json.NewDecoder(r.Body).Decode(v)
+		if collaborators[parts[0]] == nil {
+			collaborators[parts[0]] = make(map[string]string)
+		}
+		collaborators[parts[0]][parts[2]] = v.Permission
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	// a project in g/sub lists its own members and those of both groups
+	members := []*GitMember{
+		{User: GitUser{Login: "jane"}, Permission: PermissionAdmin},
+		{User: GitUser{Login: "john"}, Permission: PermissionTriage, Group: "g/sub"},
+		{User: GitUser{Login: "joan"}, Permission: PermissionWrite, Group: "g/sub"},
+		{User: GitUser{Login: "joan"}, Permission: PermissionRead, Group: "g"},
+	}
+	if err := prov.GrantAccess(context.Background(), &GitRepository{Name: "sub"}, members); err != nil {
+		t.Fatalf("GrantAccess returned error: %v", err)
+	}
+	// a project in g only lists the members of g, john who is only in g/sub has no access
+	members = []*GitMember{
+		{User: GitUser{Login: "joan"}, Permission: PermissionRead, Group: "g"},
+	}
+	if err := prov.GrantAccess(context.Background(), &GitRepository{Name: "top"}, members); err != nil {
+		t.Fatalf("GrantAccess returned error: %v", err)
+	}
+
+	want := map[string]map[string]string{
+		"sub": {"jane": "admin", "john": "triage", "joan": "push"},
+		"top": {"joan": "pull"},
+	}
+	if !reflect.DeepEqual(collaborators, want) {
+		t.Errorf("collaborators = %v, want %v", collaborators, want)
+	}
+}
@@ -25,7 +25,11 @@ package provider
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/url"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/artur-sak13/gitmv/auth"
 
@@ -34,16 +38,28 @@ import (
 
 // GitlabProvider implements the provider interface for GitLab
 type GitlabProvider struct {
-	Client  *gitlab.Client
-	Context context.Context
-	ID      *auth.ID
+	Client *gitlab.Client
+	ID     *auth.ID
+
+	// Naming derives the name of listed repositories from their namespace and path, only the path is used when it is nil
+	Naming NameFunc
+
+	// groupMembers caches the direct members of groups by their full path
+	groupMembersMu sync.Mutex
+	groupMembers   map[string][]*GitMember
+
+	loginMu sync.Mutex
+	login   string
 }
 
+// NameFunc derives a repository name from its namespace and path
+type NameFunc func(namespace, path string) (string, error)
+
 type getterFn func(opts gitlab.ListOptions) (*gitlab.Response, error)
 
 // NewGitlabProvider creates a new GitLab client which implements the provider interface
 func NewGitlabProvider(id *auth.ID) (GitProvider, error) {
-	client := gitlab.NewClient(nil, id.Token)
+	client := gitlab.NewClient(newHTTPClient(), id.Token)
 	if !IsHosted(id.URL) {
 		if err := client.SetBaseURL(id.URL); err != nil {
 			return nil, err
@@ -69,14 +85,14 @@ func WithGitlabClient(client *gitlab.Client, id *auth.ID) GitProvider {
 
 // GetRepositories gets a list of all repositories in the target Gitlab instance
 // For >100 repositories this _depaginates_ the responses and appends them to one slice
-func (g *GitlabProvider) GetRepositories() ([]*GitRepository, error) {
+func (g *GitlabProvider) GetRepositories(ctx context.Context) ([]*GitRepository, error) {
 	var result []*gitlab.Project
 	projectOpts := gitlab.ListProjectsOptions{Statistics: gitlab.Bool(true)}
 
 	_, err := depaginate(func(opts gitlab.ListOptions) (*gitlab.Response, error) {
 		projectOpts.ListOptions = opts
 
-		projects, resp, err := g.Client.Projects.ListProjects(&projectOpts)
+		projects, resp, err := g.Client.Projects.ListProjects(&projectOpts, gitlab.WithContext(ctx))
 
 		result = append(result, projects...)
 		return resp, err
@@ -88,7 +104,15 @@ func (g *GitlabProvider) GetRepositories() ([]*GitRepository, error) {
 
 	var repos []*GitRepository
 	for _, project := range result {
-		repos = append(repos, fromGitlabProject(project))
+		repo := fromGitlabProject(project)
+		if g.Naming != nil {
+			name, err := g.Naming(repo.Namespace, project.Path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to name %s due to: %v", project.PathWithNamespace, err)
+			}
+			repo.Name, repo.Path = name, project.Path
+		}
+		repos = append(repos, repo)
 	}
 	return repos, nil
 }
@@ -98,6 +122,18 @@ func fromGitlabProject(project *gitlab.Project) *GitRepository {
 	if project.Owner != nil {
 		owner = project.Owner.Username
 	}
+	empty := true
+	if project.Statistics != nil {
+		empty = project.Statistics.CommitCount == 0
+	}
+	namespace := ""
+	if project.Namespace != nil {
+		namespace = project.Namespace.FullPath
+	}
+	var activity time.Time
+	if project.LastActivityAt != nil {
+		activity = *project.LastActivityAt
+	}
 	return &GitRepository{
 		Name:        project.Path,
 		Description: project.Description,
@@ -106,21 +142,34 @@ func fromGitlabProject(project *gitlab.Project) *GitRepository {
 		Archived:    project.Archived,
 		CloneURL:    project.HTTPURLToRepo,
 		Fork:        project.ForkedFromProject != nil,
-		Empty:       project.Statistics.CommitCount == 0,
+		Empty:       empty,
 		PID:         project.ID,
+
+		Namespace:    namespace,
+		Topics:       project.TagList,
+		Visibility:   string(project.Visibility),
+		LastActivity: activity,
 	}
 }
 
 // GetIssues retrieves a full list of Issues for a project
 // For >100 issues this _depaginates_ the responses and appends them to one slice
-func (g *GitlabProvider) GetIssues(pid int, repo string) ([]*GitIssue, error) {
-	var result []*gitlab.Issue
-	issueOpts := gitlab.ListProjectIssuesOptions{}
+func (g *GitlabProvider) GetIssues(ctx context.Context, pid int, repo string) ([]*GitIssue, error) {
+	return g.listIssues(ctx, pid, repo, gitlab.ListProjectIssuesOptions{})
+}
+
+// GetIssuesUpdatedAfter retrieves the issues of a project updated after since
+func (g *GitlabProvider) GetIssuesUpdatedAfter(ctx context.Context, pid int, repo string, since time.Time) ([]*GitIssue, error) {
+	return g.listIssues(ctx, pid, repo, gitlab.ListProjectIssuesOptions{UpdatedAfter: &since})
+}
 
+// listIssues retrieves the issues of a project matching issueOpts
+func (g *GitlabProvider) listIssues(ctx context.Context, pid int, repo string, issueOpts gitlab.ListProjectIssuesOptions) ([]*GitIssue, error) {
+	var result []*gitlab.Issue
 	_, err := depaginate(func(opts gitlab.ListOptions) (*gitlab.Response, error) {
 		issueOpts.ListOptions = opts
 
-		issues, resp, err := g.Client.Issues.ListProjectIssues(pid, &issueOpts)
+		issues, resp, err := g.Client.Issues.ListProjectIssues(pid, &issueOpts, gitlab.WithContext(ctx))
 
 		result = append(result, issues...)
 		return resp, err
@@ -136,8 +185,8 @@ func (g *GitlabProvider) GetIssues(pid int, repo string) ([]*GitIssue, error) {
 		gitissue := fromGitlabIssue(issue)
 		gitissue.Repo = repo
 		gitissue.PID = pid
-		gitissue.User = g.GetUserByID(issue.Author.ID)
-		gitissue.Assignees = g.getAssignees(issue.Assignees)
+		gitissue.User = g.GetUserByID(ctx, issue.Author.ID)
+		gitissue.Assignees = g.getAssignees(ctx, issue.Assignees)
 
 		issues = append(issues, gitissue)
 	}
@@ -155,10 +204,10 @@ func fromGitlabIssue(issue *gitlab.Issue) *GitIssue {
 	}
 }
 
-func (g *GitlabProvider) getAssignees(assignees []*gitlab.IssueAssignee) []GitUser {
+func (g *GitlabProvider) getAssignees(ctx context.Context, assignees []*gitlab.IssueAssignee) []GitUser {
 	users := []GitUser{}
 	for _, assignee := range assignees {
-		user := g.GetUserByID(assignee.ID)
+		user := g.GetUserByID(ctx, assignee.ID)
 		if user != nil {
 			users = append(users, *user)
 		}
@@ -168,8 +217,8 @@ func (g *GitlabProvider) getAssignees(assignees []*gitlab.IssueAssignee) []GitUs
 }
 
 // GetUserByID looks up a user by ID and lifts them to the GitUser type
-func (g *GitlabProvider) GetUserByID(uid int) *GitUser {
-	user, _, err := g.Client.Users.GetUser(uid, gitlab.WithSudo(2))
+func (g *GitlabProvider) GetUserByID(ctx context.Context, uid int) *GitUser {
+	user, _, err := g.Client.Users.GetUser(uid, gitlab.WithSudo(2), gitlab.WithContext(ctx))
 	if err != nil {
 		return nil
 	}
@@ -189,14 +238,14 @@ func fromGitlabUser(user *gitlab.User) *GitUser {
 
 // GetComments retrieves a full list of comments for a project issue
 // For >100 comments this _depaginates_ the responses and appends them to one slice
-func (g *GitlabProvider) GetComments(pid, issueNum int, repo string) ([]*GitIssueComment, error) {
+func (g *GitlabProvider) GetComments(ctx context.Context, pid, issueNum int, repo string) ([]*GitIssueComment, error) {
 	var list []*gitlab.Note
 	noteOpts := gitlab.ListIssueNotesOptions{}
 
 	_, err := depaginate(func(opts gitlab.ListOptions) (*gitlab.Response, error) {
 		noteOpts.ListOptions = opts
 
-		notes, resp, err := g.Client.Notes.ListIssueNotes(pid, issueNum, &noteOpts, gitlab.WithSudo(2))
+		notes, resp, err := g.Client.Notes.ListIssueNotes(pid, issueNum, &noteOpts, gitlab.WithSudo(2), gitlab.WithContext(ctx))
 
 		list = append(list, notes...)
 		return resp, err
@@ -221,6 +270,7 @@ func fromGitlabComments(repo string, issueNum int, notes []*gitlab.Note) []*GitI
 
 func fromGitlabComment(repo string, issueNum int, note *gitlab.Note) *GitIssueComment {
 	return &GitIssueComment{
+		ID:       note.ID,
 		Repo:     repo,
 		IssueNum: issueNum,
 		User: GitUser{
@@ -235,14 +285,14 @@ func fromGitlabComment(repo string, issueNum int, note *gitlab.Note) *GitIssueCo
 }
 
 // GetLabels retrieves a full list of labels associated with a project
-func (g *GitlabProvider) GetLabels(pid int, repo string) ([]*GitLabel, error) {
+func (g *GitlabProvider) GetLabels(ctx context.Context, pid int, repo string) ([]*GitLabel, error) {
 	var list []*gitlab.Label
 	var labelOpts gitlab.ListLabelsOptions
 
 	_, err := depaginate(func(opts gitlab.ListOptions) (*gitlab.Response, error) {
 		labelOpts = gitlab.ListLabelsOptions(opts)
 
-		issues, resp, err := g.Client.Labels.ListLabels(pid, &labelOpts)
+		issues, resp, err := g.Client.Labels.ListLabels(pid, &labelOpts, gitlab.WithContext(ctx))
 
 		list = append(list, issues...)
 		return resp, err
@@ -255,6 +305,291 @@ func (g *GitlabProvider) GetLabels(pid int, repo string) ([]*GitLabel, error) {
 	return fromGitlabLabels(repo, list), nil
 }
 
+// GetMembers retrieves the direct members of a project and of every group above it
+// Members of a group have the full path of that group, personal projects have no groups.
+func (g *GitlabProvider) GetMembers(ctx context.Context, repo *GitRepository) ([]*GitMember, error) {
+	var list []*gitlab.ProjectMember
+	_, err := depaginate(func(opts gitlab.ListOptions) (*gitlab.Response, error) {
+		memberOpts := gitlab.ListProjectMembersOptions{ListOptions: opts}
+
+		members, resp, err := g.Client.ProjectMembers.ListProjectMembers(repo.PID, &memberOpts, gitlab.WithContext(ctx))
+
+		list = append(list, members...)
+		return resp, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list members of %s due to: %v", repo.FullPath(), err)
+	}
+
+	var members []*GitMember
+	for _, member := range list {
+		if member.State == "active" {
+			members = append(members, &GitMember{
+				User:       GitUser{Login: member.Username, Name: member.Name, Email: member.Email},
+				Permission: fromGitlabAccessLevel(member.AccessLevel),
+			})
+		}
+	}
+
+	// projects owned by a user live in the user's namespace rather than a group
+	if repo.Owner != "" || repo.Namespace == "" {
+		return members, nil
+	}
+	parts := strings.Split(repo.Namespace, "/")
+	for i := range parts {
+		groupMembers, err := g.getGroupMembers(ctx, strings.Join(parts[:i+1], "/"))
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, groupMembers...)
+	}
+	return members, nil
+}
+
+// getGroupMembers retrieves the active direct members of a group once
+func (g *GitlabProvider) getGroupMembers(ctx context.Context, group string) ([]*GitMember, error) {
+	g.groupMembersMu.Lock()
+	defer g.groupMembersMu.Unlock()
+
+	if members, ok := g.groupMembers[group]; ok {
+		return members, nil
+	}
+
+	var list []*gitlab.GroupMember
+	_, err := depaginate(func(opts gitlab.ListOptions) (*gitlab.Response, error) {
+		memberOpts := gitlab.ListGroupMembersOptions{ListOptions: opts}
+
+		members, resp, err := g.Client.Groups.ListGroupMembers(group, &memberOpts, gitlab.WithContext(ctx))
+
+		list = append(list, members...)
+		return resp, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list members of group %s due to: %v", group, err)
+	}
+
+	var members []*GitMember
+	for _, member := range list {
+		if member.State == "active" {
+			members = append(members, &GitMember{
+				User:       GitUser{Login: member.Username, Name: member.Name},
+				Permission: fromGitlabAccessLevel(member.AccessLevel),
+				Group:      group,
+			})
+		}
+	}
+	if g.groupMembers == nil {
+		g.groupMembers = make(map[string][]*GitMember)
+	}
+	g.groupMembers[group] = members
+	return members, nil
+}
+
+// fromGitlabAccessLevel converts a GitLab access level into the permission granting the same access
+func fromGitlabAccessLevel(level gitlab.AccessLevelValue) Permission {
+	switch {
+	case level >= gitlab.OwnerPermissions:
+		return PermissionAdmin
+	case level >= gitlab.MaintainerPermissions:
+		return PermissionMaintain
+	case level >= gitlab.DeveloperPermissions:
+		return PermissionWrite
+	case level >= gitlab.ReporterPermissions:
+		return PermissionTriage
+	}
+	return PermissionRead
+}
+
+// GetRefs retrieves the branches and tags of a GitLab project along with the commits they point at
+func (g *GitlabProvider) GetRefs(ctx context.Context, pid int, repo string) ([]*GitRef, error) {
+	var refs []*GitRef
+
+	_, err := depaginate(func(opts gitlab.ListOptions) (*gitlab.Response, error) {
+		branchOpts := gitlab.ListBranchesOptions(opts)
+		branches, resp, err := g.Client.Branches.ListBranches(pid, &branchOpts, gitlab.WithContext(ctx))
+		for _, branch := range branches {
+			refs = append(refs, &GitRef{Name: branch.Name, SHA: gitlabCommitID(branch.Commit)})
+		}
+		return resp, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches of %s due to: %v", repo, err)
+	}
+
+	_, err = depaginate(func(opts gitlab.ListOptions) (*gitlab.Response, error) {
+		tags, resp, err := g.Client.Tags.ListTags(pid, &gitlab.ListTagsOptions{ListOptions: opts}, gitlab.WithContext(ctx))
+		for _, tag := range tags {
+			refs = append(refs, &GitRef{Name: tag.Name, Tag: true, SHA: gitlabCommitID(tag.Commit)})
+		}
+		return resp, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags of %s due to: %v", repo, err)
+	}
+
+	return refs, nil
+}
+
+func gitlabCommitID(commit *gitlab.Commit) string {
+	if commit == nil {
+		return ""
+	}
+	return commit.ID
+}
+
+// GetPullRequests retrieves a full list of merge requests for a project along with their diff summaries
+func (g *GitlabProvider) GetPullRequests(ctx context.Context, pid int, repo string) ([]*GitPullRequest, error) {
+	var result []*gitlab.MergeRequest
+	mrOpts := gitlab.ListProjectMergeRequestsOptions{
+		State:   gitlab.String("all"),
+		OrderBy: gitlab.String("created_at"),
+		Sort:    gitlab.String("asc"),
+	}
+
+	_, err := depaginate(func(opts gitlab.ListOptions) (*gitlab.Response, error) {
+		mrOpts.ListOptions = opts
+
+		mrs, resp, err := g.Client.MergeRequests.ListProjectMergeRequests(pid, &mrOpts, gitlab.WithContext(ctx))
+
+		result = append(result, mrs...)
+		return resp, err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var prs []*GitPullRequest
+
+	for _, mr := range result {
+		changes, _, err := g.Client.MergeRequests.GetMergeRequestChanges(pid, mr.IID, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get changes of merge request %s!%d due to: %v", repo, mr.IID, err)
+		}
+
+		pr := fromGitlabMergeRequest(changes)
+		pr.Repo = repo
+		pr.PID = pid
+		if user := g.GetUserByID(ctx, mr.Author.ID); user != nil {
+			pr.User = user
+		}
+		if mr.Assignee.ID != 0 {
+			if user := g.GetUserByID(ctx, mr.Assignee.ID); user != nil {
+				pr.Assignees = append(pr.Assignees, *user)
+			}
+		}
+
+		prs = append(prs, pr)
+	}
+
+	return prs, nil
+}
+
+func fromGitlabMergeRequest(mr *gitlab.MergeRequest) *GitPullRequest {
+	pr := &GitPullRequest{
+		Number:       mr.IID,
+		Title:        mr.Title,
+		Body:         mr.Description,
+		State:        fromGitlabMergeRequestState(mr.State),
+		SourceBranch: mr.SourceBranch,
+		TargetBranch: mr.TargetBranch,
+		HeadSHA:      mr.SHA,
+		Labels:       ToGitLabels(mr.Labels),
+		User: &GitUser{
+			Login: mr.Author.Username,
+			Name:  mr.Author.Name,
+		},
+		Assignees: []GitUser{},
+		Changes:   []GitFileChange{},
+	}
+
+	for _, change := range mr.Changes {
+		additions, deletions := countDiffLines(change.Diff)
+		pr.Changes = append(pr.Changes, GitFileChange{
+			OldPath:     change.OldPath,
+			NewPath:     change.NewPath,
+			Additions:   additions,
+			Deletions:   deletions,
+			NewFile:     change.NewFile,
+			RenamedFile: change.RenamedFile,
+			DeletedFile: change.DeletedFile,
+		})
+	}
+
+	return pr
+}
+
+func fromGitlabMergeRequestState(state string) string {
+	switch state {
+	case "opened":
+		return "open"
+	case "merged":
+		return "merged"
+	default:
+		return "closed"
+	}
+}
+
+// GetReviewComments retrieves the discussions of a merge request, including notes positioned on the diff
+// For >100 discussions this _depaginates_ the responses and appends them to one slice
+func (g *GitlabProvider) GetReviewComments(ctx context.Context, pid, pullNum int, repo string) ([]*GitReviewComment, error) {
+	var list []*gitlab.Discussion
+	var discussionOpts gitlab.ListMergeRequestDiscussionsOptions
+
+	_, err := depaginate(func(opts gitlab.ListOptions) (*gitlab.Response, error) {
+		discussionOpts = gitlab.ListMergeRequestDiscussionsOptions(opts)
+
+		discussions, resp, err := g.Client.Discussions.ListMergeRequestDiscussions(pid, pullNum, &discussionOpts, gitlab.WithSudo(2), gitlab.WithContext(ctx))
+
+		list = append(list, discussions...)
+		return resp, err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*GitReviewComment
+	for _, discussion := range list {
+		for _, note := range discussion.Notes {
+			if note.System {
+				continue
+			}
+			result = append(result, fromGitlabReviewComment(repo, pullNum, note))
+		}
+	}
+
+	return result, nil
+}
+
+func fromGitlabReviewComment(repo string, pullNum int, note *gitlab.Note) *GitReviewComment {
+	comment := &GitReviewComment{
+		ID:      note.ID,
+		Repo:    repo,
+		PullNum: pullNum,
+		User: GitUser{
+			Login: note.Author.Username,
+			Name:  note.Author.Name,
+			Email: note.Author.Email,
+		},
+		Body:      note.Body,
+		CreatedAt: *note.CreatedAt,
+		UpdatedAt: *note.UpdatedAt,
+	}
+
+	if position := note.Position; position != nil && position.PositionType == "text" {
+		comment.Path = position.NewPath
+		if position.NewLine == 0 {
+			comment.Path = position.OldPath
+		}
+		comment.Line = position.NewLine
+		comment.OldLine = position.OldLine
+		comment.CommitID = position.HeadSHA
+	}
+
+	return comment
+}
+
 // GetAuthToken returns a string with a user's api authentication token
 func (g *GitlabProvider) GetAuth() *auth.ID {
 	return g.ID
@@ -300,35 +635,357 @@ func lastPage(resp *gitlab.Response) bool {
 	return resp == nil || resp.CurrentPage >= resp.TotalPages || resp.NextPage == 0
 }
 
-// CreateRepository creates a new GitLab repository
-func (g *GitlabProvider) CreateRepository(repo *GitRepository) (*GitRepository, error) {
-	// TODO: Implement
-	return nil, fmt.Errorf("gitlab CreateRepository not implemented")
+// CreateRepository creates a new private GitLab project in the owner's namespace
+func (g *GitlabProvider) CreateRepository(ctx context.Context, repo *GitRepository) (*GitRepository, error) {
+	projectOpts, err := g.newProjectOptions(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	project, _, err := g.Client.Projects.CreateProject(projectOpts, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create repository %s due to: %v", repo.Name, err)
+	}
+
+	if repo.Archived {
+		project, _, err = g.Client.Projects.ArchiveProject(project.ID, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("failed to archive repository %s due to: %v", repo.Name, err)
+		}
+	}
+
+	return fromGitlabProject(project), nil
 }
 
-// MigrateRepo migrates a git repo from an existing provider
-func (g *GitlabProvider) MigrateRepo(repo *GitRepository, token string) (string, error) {
-	return "", fmt.Errorf("gitlab MigrateRepo not implemented")
+// MigrateRepo imports a git repo from an existing provider into GitLab
+// GitLab only imports from a URL while creating a project, so the project is
+// created with the import URL if it doesn't exist yet. An existing project
+// without commits has the source's branches and tags pushed into it instead.
+func (g *GitlabProvider) MigrateRepo(ctx context.Context, repo *GitRepository, token string) (string, error) {
+	importURL, err := authenticatedURL(repo.CloneURL, repo.Owner, token)
+	if err != nil {
+		return "", err
+	}
+
+	pid, err := g.projectPath(ctx, repo.Name)
+	if err != nil {
+		return "", err
+	}
+	project, resp, err := g.Client.Projects.GetProject(pid, nil, gitlab.WithContext(ctx))
+	if err != nil && (resp == nil || resp.StatusCode != http.StatusNotFound) {
+		return "", err
+	}
+
+	if project == nil {
+		projectOpts, err := g.newProjectOptions(ctx, repo)
+		if err != nil {
+			return "", err
+		}
+		projectOpts.ImportURL = gitlab.String(importURL)
+
+		project, _, err = g.Client.Projects.CreateProject(projectOpts, gitlab.WithContext(ctx))
+		if err != nil {
+			return "", fmt.Errorf("failed to import repository %s due to: %v", pid, err)
+		}
+		return fromGitlabImportStatus(project.ImportStatus), nil
+	}
+
+	// GitLab only reports a default branch once a project has commits
+	if project.DefaultBranch != "" {
+		return "", fmt.Errorf("failed to import repository %s due to: the project already has commits", pid)
+	}
+	if err := mirrorRefs(ctx, repo.CloneURL, token, project.HTTPURLToRepo, g.ID.Token); err != nil {
+		return "", fmt.Errorf("failed to import repository %s due to: %v", pid, err)
+	}
+	return ImportComplete, nil
 }
 
-func (g *GitlabProvider) GetImportProgress(repo string) (string, error) {
-	return "", fmt.Errorf("gitlab GetImportProgress not implemented")
+// GetImportProgress checks the progress of a previously started GitLab import
+func (g *GitlabProvider) GetImportProgress(ctx context.Context, repo string) (string, error) {
+	pid, err := g.projectPath(ctx, repo)
+	if err != nil {
+		return "", err
+	}
+	project, _, err := g.Client.Projects.GetProject(pid, nil, gitlab.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+
+	switch project.ImportStatus {
+	case "", "none":
+		// repositories pushed into an existing project were not imported
+		if project.DefaultBranch != "" {
+			return ImportComplete, nil
+		}
+		return "", fmt.Errorf("no import found for %s", pid)
+	case "failed":
+		return "", fmt.Errorf("import failed for %s: %s", pid, project.ImportError)
+	}
+
+	return fromGitlabImportStatus(project.ImportStatus), nil
+}
+
+// fromGitlabImportStatus maps GitLab's import states onto the status strings
+// reported by GitHub's source import API
+func fromGitlabImportStatus(status string) string {
+	switch status {
+	case "scheduled", "started":
+		return "importing"
+	case "finished":
+		return ImportComplete
+	default:
+		return status
+	}
+}
+
+func (g *GitlabProvider) newProjectOptions(ctx context.Context, repo *GitRepository) (*gitlab.CreateProjectOptions, error) {
+	projectOpts := &gitlab.CreateProjectOptions{
+		Name:        gitlab.String(strings.TrimSpace(repo.Name)),
+		Path:        gitlab.String(strings.TrimSpace(repo.Name)),
+		Description: gitlab.String(strings.TrimSpace(repo.Description)),
+		Visibility:  gitlab.Visibility(gitlab.PrivateVisibility),
+	}
+
+	if g.ID.Owner != "" {
+		namespace, _, err := g.Client.Namespaces.GetNamespace(g.ID.Owner, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("failed to find namespace %s: %v", g.ID.Owner, err)
+		}
+		projectOpts.NamespaceID = gitlab.Int(namespace.ID)
+	}
+
+	return projectOpts, nil
+}
+
+func authenticatedURL(cloneURL, user, token string) (string, error) {
+	u, err := url.Parse(cloneURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse clone url %s: %v", cloneURL, err)
+	}
+	if token == "" {
+		return u.String(), nil
+	}
+	if user == "" {
+		user = "oauth2"
+	}
+	u.User = url.UserPassword(user, token)
+	return u.String(), nil
 }
 
 // CreateIssue creates a new GitLab issue
-func (g *GitlabProvider) CreateIssue(issue *GitIssue) (*GitIssue, error) {
-	// TODO: Implement
-	return nil, fmt.Errorf("gitlab CreateIssue not implemented")
+func (g *GitlabProvider) CreateIssue(ctx context.Context, issue *GitIssue) (*GitIssue, error) {
+	issueOpts := &gitlab.CreateIssueOptions{
+		Title:       gitlab.String(strings.TrimSpace(issue.Title)),
+		Description: gitlab.String(strings.TrimSpace(issue.Body)),
+		Labels:      gitlab.Labels(*ToGitLabelStringSlice(issue.Labels)),
+		AssigneeIDs: g.getUserIDs(ctx, issue.Assignees),
+	}
+
+	pid, err := g.projectPath(ctx, issue.Repo)
+	if err != nil {
+		return nil, err
+	}
+
+	result, _, err := g.Client.Issues.CreateIssue(pid, issueOpts, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create issue in %s due to: %v", pid, err)
+	}
+
+	if issue.State == "closed" && result.State != "closed" {
+		iid := result.IID
+		result, _, err = g.Client.Issues.UpdateIssue(pid, iid, &gitlab.UpdateIssueOptions{
+			StateEvent: gitlab.String("close"),
+		}, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("failed to close issue %d in %s due to: %v", iid, pid, err)
+		}
+	}
+
+	gitissue := fromGitlabIssue(result)
+	gitissue.Repo = issue.Repo
+	gitissue.PID = result.ProjectID
+	gitissue.User = issue.User
+	gitissue.Assignees = issue.Assignees
+
+	return gitissue, nil
+}
+
+// CreatePullRequest records a merge request as a GitLab issue
+func (g *GitlabProvider) CreatePullRequest(ctx context.Context, pr *GitPullRequest) (*GitPullRequest, error) {
+	issue, err := g.CreateIssue(ctx, PullRequestAsIssue(pr))
+	if err != nil {
+		return nil, err
+	}
+
+	created := *pr
+	created.Number = issue.Number
+	created.IsIssue = true
+	return &created, nil
+}
+
+// CreateReviewComment records a merge request comment on the issue created in its place
+func (g *GitlabProvider) CreateReviewComment(ctx context.Context, pr *GitPullRequest, comment *GitReviewComment) error {
+	return g.CreateIssueComment(ctx, pr.Number, ReviewCommentAsIssueComment(pr.Number, comment))
+}
+
+func (g *GitlabProvider) getUserIDs(ctx context.Context, users []GitUser) []int {
+	var ids []int
+	for _, user := range users {
+		if user.Login == "" {
+			continue
+		}
+		found, _, err := g.Client.Users.ListUsers(&gitlab.ListUsersOptions{Username: gitlab.String(user.Login)}, gitlab.WithContext(ctx))
+		if err != nil || len(found) == 0 {
+			continue
+		}
+		ids = append(ids, found[0].ID)
+	}
+	return ids
 }
 
 // CreateIssueComment creates a new GitLab issue note/comment
-func (g *GitlabProvider) CreateIssueComment(issueNum int, comment *GitIssueComment) error {
-	// TODO: Implement
-	return fmt.Errorf("gitlab CreateIssueComment not implemented")
+func (g *GitlabProvider) CreateIssueComment(ctx context.Context, issueNum int, comment *GitIssueComment) error {
+	noteOpts := &gitlab.CreateIssueNoteOptions{
+		Body: gitlab.String(strings.TrimSpace(comment.Body)),
+	}
+
+	pid, err := g.projectPath(ctx, comment.Repo)
+	if err != nil {
+		return err
+	}
+	_, _, err = g.Client.Notes.CreateIssueNote(pid, issueNum, noteOpts, gitlab.WithContext(ctx))
+	return err
 }
 
 // CreateLabel creates a new GitLab issue label
-func (g *GitlabProvider) CreateLabel(label *GitLabel) (*GitLabel, error) {
-	// TODO: Implement
-	return nil, fmt.Errorf("gitlab CreateLabel not implemented")
+func (g *GitlabProvider) CreateLabel(ctx context.Context, srcLabel *GitLabel) (*GitLabel, error) {
+	labelOpts := &gitlab.CreateLabelOptions{
+		Name:        gitlab.String(strings.TrimSpace(srcLabel.Name)),
+		Color:       gitlab.String("#" + strings.Trim(srcLabel.Color, "#\r\n\t")),
+		Description: gitlab.String(strings.TrimSpace(srcLabel.Description)),
+	}
+
+	pid, err := g.projectPath(ctx, srcLabel.Repo)
+	if err != nil {
+		return nil, err
+	}
+	result, _, err := g.Client.Labels.CreateLabel(pid, labelOpts, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return fromGitlabLabel(srcLabel.Repo, result), nil
+}
+
+// UpdateIssue replaces the title, description, state and labels of a GitLab issue
+func (g *GitlabProvider) UpdateIssue(ctx context.Context, issue *GitIssue) error {
+	stateEvent := "reopen"
+	if issue.State == "closed" {
+		stateEvent = "close"
+	}
+	issueOpts := &gitlab.UpdateIssueOptions{
+		Title:       gitlab.String(strings.TrimSpace(issue.Title)),
+		Description: gitlab.String(strings.TrimSpace(issue.Body)),
+		Labels:      gitlab.Labels(*ToGitLabelStringSlice(issue.Labels)),
+		StateEvent:  gitlab.String(stateEvent),
+	}
+	pid, err := g.projectPath(ctx, issue.Repo)
+	if err != nil {
+		return err
+	}
+	if _, _, err := g.Client.Issues.UpdateIssue(pid, issue.Number, issueOpts, gitlab.WithContext(ctx)); err != nil {
+		return fmt.Errorf("failed to update issue %d in %s due to: %v", issue.Number, pid, err)
+	}
+	return nil
+}
+
+// UpdateLabel replaces the color and description of a GitLab issue label
+func (g *GitlabProvider) UpdateLabel(ctx context.Context, label *GitLabel) error {
+	labelOpts := &gitlab.UpdateLabelOptions{
+		Name:        gitlab.String(label.Name),
+		Color:       gitlab.String("#" + strings.Trim(label.Color, "#\r\n\t")),
+		Description: gitlab.String(strings.TrimSpace(label.Description)),
+	}
+	pid, err := g.projectPath(ctx, label.Repo)
+	if err != nil {
+		return err
+	}
+	if _, _, err := g.Client.Labels.UpdateLabel(pid, labelOpts, gitlab.WithContext(ctx)); err != nil {
+		return fmt.Errorf("failed to update label %s in %s due to: %v", label.Name, pid, err)
+	}
+	return nil
+}
+
+// DeleteRepository deletes a GitLab project
+func (g *GitlabProvider) DeleteRepository(ctx context.Context, repo *GitRepository) error {
+	pid, err := g.projectPath(ctx, repo.Name)
+	if err != nil {
+		return err
+	}
+	if _, err := g.Client.Projects.DeleteProject(pid, gitlab.WithContext(ctx)); err != nil {
+		return fmt.Errorf("failed to delete repository %s due to: %v", pid, err)
+	}
+	return nil
+}
+
+// ArchiveRepository makes a GitLab project read-only
+func (g *GitlabProvider) ArchiveRepository(ctx context.Context, repo *GitRepository) error {
+	pid, err := g.projectPath(ctx, repo.Name)
+	if err != nil {
+		return err
+	}
+	if _, _, err := g.Client.Projects.ArchiveProject(pid, gitlab.WithContext(ctx)); err != nil {
+		return fmt.Errorf("failed to archive repository %s due to: %v", pid, err)
+	}
+	return nil
+}
+
+// DeleteLabel deletes a GitLab issue label
+func (g *GitlabProvider) DeleteLabel(ctx context.Context, label *GitLabel) error {
+	labelOpts := &gitlab.DeleteLabelOptions{Name: gitlab.String(label.Name)}
+	pid, err := g.projectPath(ctx, label.Repo)
+	if err != nil {
+		return err
+	}
+	if _, err := g.Client.Labels.DeleteLabel(pid, labelOpts, gitlab.WithContext(ctx)); err != nil {
+		return fmt.Errorf("failed to delete label %s from %s due to: %v", label.Name, pid, err)
+	}
+	return nil
+}
+
+// DeleteIssue deletes a GitLab issue, which requires owner access to the project
+func (g *GitlabProvider) DeleteIssue(ctx context.Context, issue *GitIssue) error {
+	pid, err := g.projectPath(ctx, issue.Repo)
+	if err != nil {
+		return err
+	}
+	if _, err := g.Client.Issues.DeleteIssue(pid, issue.Number, gitlab.WithContext(ctx)); err != nil {
+		return fmt.Errorf("failed to delete issue %d in %s due to: %v", issue.Number, pid, err)
+	}
+	return nil
+}
+
+// GrantAccess is not supported since members are only migrated into GitHub
+func (g *GitlabProvider) GrantAccess(ctx context.Context, repo *GitRepository, members []*GitMember) error {
+	return fmt.Errorf("gitlab GrantAccess not supported")
+}
+
+// projectPath returns the namespaced path GitLab accepts in place of a project ID,
+// projects are in the authenticated user's namespace when no owner is set
+func (g *GitlabProvider) projectPath(ctx context.Context, repo string) (string, error) {
+	if g.ID.Owner != "" {
+		return g.ID.Owner + "/" + repo, nil
+	}
+
+	g.loginMu.Lock()
+	defer g.loginMu.Unlock()
+	// a failed lookup is not cached so that the next call retries it
+	if g.login == "" {
+		user, _, err := g.Client.Users.CurrentUser(gitlab.WithContext(ctx))
+		if err != nil {
+			return "", fmt.Errorf("failed to find the authenticated user due to: %v", err)
+		}
+		g.login = user.Username
+	}
+	return g.login + "/" + repo, nil
 }
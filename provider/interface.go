@@ -22,33 +22,69 @@
 
 package provider
 
-import "github.com/artur-sak13/gitmv/auth"
+import (
+	"context"
+	"time"
 
-// GitProvider
+	"github.com/artur-sak13/gitmv/auth"
+)
+
+// GitProvider is implemented by every supported git hosting service
+// Every call takes a context so that it can be cancelled or bounded by a deadline.
 type GitProvider interface {
 	// Create methods
-	CreateRepository(*GitRepository) (*GitRepository, error)
+	CreateRepository(context.Context, *GitRepository) (*GitRepository, error)
+
+	CreateIssue(context.Context, *GitIssue) (*GitIssue, error)
+
+	CreateIssueComment(context.Context, int, *GitIssueComment) error
+
+	CreateLabel(context.Context, *GitLabel) (*GitLabel, error)
+
+	MigrateRepo(context.Context, *GitRepository, string) (string, error)
+
+	CreatePullRequest(context.Context, *GitPullRequest) (*GitPullRequest, error)
+
+	CreateReviewComment(context.Context, *GitPullRequest, *GitReviewComment) error
+
+	GrantAccess(context.Context, *GitRepository, []*GitMember) error
 
-	CreateIssue(*GitIssue) (*GitIssue, error)
+	// Update methods
+	UpdateIssue(context.Context, *GitIssue) error
 
-	CreateIssueComment(int, *GitIssueComment) error
+	UpdateLabel(context.Context, *GitLabel) error
 
-	CreateLabel(*GitLabel) (*GitLabel, error)
+	// Delete methods
+	DeleteRepository(context.Context, *GitRepository) error
 
-	MigrateRepo(*GitRepository, string) (string, error)
+	ArchiveRepository(context.Context, *GitRepository) error
+
+	DeleteLabel(context.Context, *GitLabel) error
+
+	DeleteIssue(context.Context, *GitIssue) error
 
 	// Read methods
-	GetRepositories() ([]*GitRepository, error)
+	GetRepositories(context.Context) ([]*GitRepository, error)
+
+	GetIssues(context.Context, int, string) ([]*GitIssue, error)
+
+	GetIssuesUpdatedAfter(context.Context, int, string, time.Time) ([]*GitIssue, error)
+
+	GetComments(context.Context, int, int, string) ([]*GitIssueComment, error)
+
+	GetLabels(context.Context, int, string) ([]*GitLabel, error)
+
+	GetPullRequests(context.Context, int, string) ([]*GitPullRequest, error)
 
-	GetIssues(int, string) ([]*GitIssue, error)
+	GetReviewComments(context.Context, int, int, string) ([]*GitReviewComment, error)
 
-	GetComments(int, int, string) ([]*GitIssueComment, error)
+	GetRefs(context.Context, int, string) ([]*GitRef, error)
 
-	GetLabels(int, string) ([]*GitLabel, error)
+	GetMembers(context.Context, *GitRepository) ([]*GitMember, error)
 
 	GetAuth() *auth.ID
 
-	GetImportProgress(string) (string, error)
+	GetImportProgress(context.Context, string) (string, error)
 
 	// ValidateRepositoryName(org string, name string) error
 }
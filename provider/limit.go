@@ -0,0 +1,351 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 Artur Sak
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package provider
+
+import (
+	"context"
+	"time"
+
+	"github.com/artur-sak13/gitmv/auth"
+)
+
+// LimitedProvider wraps a GitProvider so that at most a fixed number of its API calls run at once
+// and each call is bounded by an optional timeout.
+type LimitedProvider struct {
+	GitProvider
+	sem     chan struct{}
+	timeout time.Duration
+}
+
+// Limit bounds the concurrent calls made to a GitProvider, a limit below one leaves it unbounded
+func Limit(p GitProvider, n int) GitProvider {
+	l := limited(p)
+	l.sem = nil
+	if n > 0 {
+		l.sem = make(chan struct{}, n)
+	}
+	return l
+}
+
+// Timeout bounds the duration of every call made to a GitProvider, a zero timeout leaves it unbounded
+func Timeout(p GitProvider, d time.Duration) GitProvider {
+	l := limited(p)
+	l.timeout = d
+	return l
+}
+
+// limited returns a copy of p's limits, wrapping p if it has none yet
+func limited(p GitProvider) *LimitedProvider {
+	if l, ok := p.(*LimitedProvider); ok {
+		copied := *l
+		return &copied
+	}
+	return &LimitedProvider{GitProvider: p}
+}
+
+// Unwrap returns the underlying GitProvider
+func (l *LimitedProvider) Unwrap() GitProvider {
+	return l.GitProvider
+}
+
+// unwrap strips any limits from a GitProvider
+func unwrap(p GitProvider) GitProvider {
+	for {
+		l, ok := p.(*LimitedProvider)
+		if !ok {
+			return p
+		}
+		p = l.GitProvider
+	}
+}
+
+// acquire waits for a call slot and applies the call timeout
+// The returned release func must be called once the call is done.
+func (l *LimitedProvider) acquire(ctx context.Context) (context.Context, func(), error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	if l.sem != nil {
+		select {
+		case l.sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+
+	cancel := func() {}
+	if l.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, l.timeout)
+	}
+	return ctx, func() {
+		cancel()
+		if l.sem != nil {
+			<-l.sem
+		}
+	}, nil
+}
+
+// CreateRepository creates a repository once a call slot is free
+func (l *LimitedProvider) CreateRepository(ctx context.Context, repo *GitRepository) (*GitRepository, error) {
+	ctx, release, err := l.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return l.GitProvider.CreateRepository(ctx, repo)
+}
+
+// CreateIssue creates an issue once a call slot is free
+func (l *LimitedProvider) CreateIssue(ctx context.Context, issue *GitIssue) (*GitIssue, error) {
+	ctx, release, err := l.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return l.GitProvider.CreateIssue(ctx, issue)
+}
+
+// CreateIssueComment creates an issue comment once a call slot is free
+func (l *LimitedProvider) CreateIssueComment(ctx context.Context, issueNum int, comment *GitIssueComment) error {
+	ctx, release, err := l.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return l.GitProvider.CreateIssueComment(ctx, issueNum, comment)
+}
+
+// CreateLabel creates a label once a call slot is free
+func (l *LimitedProvider) CreateLabel(ctx context.Context, label *GitLabel) (*GitLabel, error) {
+	ctx, release, err := l.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return l.GitProvider.CreateLabel(ctx, label)
+}
+
+// DeleteRepository deletes a repository once a call slot is free
+func (l *LimitedProvider) DeleteRepository(ctx context.Context, repo *GitRepository) error {
+	ctx, release, err := l.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return l.GitProvider.DeleteRepository(ctx, repo)
+}
+
+// ArchiveRepository archives a repository once a call slot is free
+func (l *LimitedProvider) ArchiveRepository(ctx context.Context, repo *GitRepository) error {
+	ctx, release, err := l.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return l.GitProvider.ArchiveRepository(ctx, repo)
+}
+
+// GrantAccess grants members access to a repository once a call slot is free
+func (l *LimitedProvider) GrantAccess(ctx context.Context, repo *GitRepository, members []*GitMember) error {
+	ctx, release, err := l.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return l.GitProvider.GrantAccess(ctx, repo, members)
+}
+
+// GetMembers lists the members of a repository once a call slot is free
+func (l *LimitedProvider) GetMembers(ctx context.Context, repo *GitRepository) ([]*GitMember, error) {
+	ctx, release, err := l.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return l.GitProvider.GetMembers(ctx, repo)
+}
+
+// UpdateIssue edits an issue once a call slot is free
+func (l *LimitedProvider) UpdateIssue(ctx context.Context, issue *GitIssue) error {
+	ctx, release, err := l.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return l.GitProvider.UpdateIssue(ctx, issue)
+}
+
+// UpdateLabel updates a label once a call slot is free
+func (l *LimitedProvider) UpdateLabel(ctx context.Context, label *GitLabel) error {
+	ctx, release, err := l.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return l.GitProvider.UpdateLabel(ctx, label)
+}
+
+// DeleteLabel deletes a label once a call slot is free
+func (l *LimitedProvider) DeleteLabel(ctx context.Context, label *GitLabel) error {
+	ctx, release, err := l.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return l.GitProvider.DeleteLabel(ctx, label)
+}
+
+// DeleteIssue deletes an issue once a call slot is free
+func (l *LimitedProvider) DeleteIssue(ctx context.Context, issue *GitIssue) error {
+	ctx, release, err := l.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return l.GitProvider.DeleteIssue(ctx, issue)
+}
+
+// MigrateRepo starts a repository import once a call slot is free
+func (l *LimitedProvider) MigrateRepo(ctx context.Context, repo *GitRepository, token string) (string, error) {
+	ctx, release, err := l.acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	return l.GitProvider.MigrateRepo(ctx, repo, token)
+}
+
+// CreatePullRequest creates a pull request once a call slot is free
+func (l *LimitedProvider) CreatePullRequest(ctx context.Context, pr *GitPullRequest) (*GitPullRequest, error) {
+	ctx, release, err := l.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return l.GitProvider.CreatePullRequest(ctx, pr)
+}
+
+// CreateReviewComment creates a pull request comment once a call slot is free
+func (l *LimitedProvider) CreateReviewComment(ctx context.Context, pr *GitPullRequest, comment *GitReviewComment) error {
+	ctx, release, err := l.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return l.GitProvider.CreateReviewComment(ctx, pr, comment)
+}
+
+// GetRepositories lists repositories once a call slot is free
+func (l *LimitedProvider) GetRepositories(ctx context.Context) ([]*GitRepository, error) {
+	ctx, release, err := l.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return l.GitProvider.GetRepositories(ctx)
+}
+
+// GetIssues lists issues once a call slot is free
+func (l *LimitedProvider) GetIssues(ctx context.Context, pid int, repo string) ([]*GitIssue, error) {
+	ctx, release, err := l.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return l.GitProvider.GetIssues(ctx, pid, repo)
+}
+
+// GetIssuesUpdatedAfter lists recently updated issues once a call slot is free
+func (l *LimitedProvider) GetIssuesUpdatedAfter(ctx context.Context, pid int, repo string, since time.Time) ([]*GitIssue, error) {
+	ctx, release, err := l.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return l.GitProvider.GetIssuesUpdatedAfter(ctx, pid, repo, since)
+}
+
+// GetComments lists issue comments once a call slot is free
+func (l *LimitedProvider) GetComments(ctx context.Context, pid, issueNum int, repo string) ([]*GitIssueComment, error) {
+	ctx, release, err := l.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return l.GitProvider.GetComments(ctx, pid, issueNum, repo)
+}
+
+// GetLabels lists labels once a call slot is free
+func (l *LimitedProvider) GetLabels(ctx context.Context, pid int, repo string) ([]*GitLabel, error) {
+	ctx, release, err := l.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return l.GitProvider.GetLabels(ctx, pid, repo)
+}
+
+// GetPullRequests lists pull requests once a call slot is free
+func (l *LimitedProvider) GetPullRequests(ctx context.Context, pid int, repo string) ([]*GitPullRequest, error) {
+	ctx, release, err := l.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return l.GitProvider.GetPullRequests(ctx, pid, repo)
+}
+
+// GetReviewComments lists pull request comments once a call slot is free
+func (l *LimitedProvider) GetReviewComments(ctx context.Context, pid, pullNum int, repo string) ([]*GitReviewComment, error) {
+	ctx, release, err := l.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return l.GitProvider.GetReviewComments(ctx, pid, pullNum, repo)
+}
+
+// GetRefs lists branches and tags once a call slot is free
+func (l *LimitedProvider) GetRefs(ctx context.Context, pid int, repo string) ([]*GitRef, error) {
+	ctx, release, err := l.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return l.GitProvider.GetRefs(ctx, pid, repo)
+}
+
+// GetAuth returns the underlying provider's authentication data
+func (l *LimitedProvider) GetAuth() *auth.ID {
+	return l.GitProvider.GetAuth()
+}
+
+// GetImportProgress checks an import's status once a call slot is free
+func (l *LimitedProvider) GetImportProgress(ctx context.Context, repo string) (string, error) {
+	ctx, release, err := l.acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	return l.GitProvider.GetImportProgress(ctx, repo)
+}
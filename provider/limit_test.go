@@ -0,0 +1,76 @@
+package provider
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowProvider records how many GetIssues calls run at once
+type slowProvider struct {
+	GitProvider
+	running, peak int32
+}
+
+func (s *slowProvider) GetIssues(ctx context.Context, pid int, repo string) ([]*GitIssue, error) {
+	n := atomic.AddInt32(&s.running, 1)
+	for {
+		max := atomic.LoadInt32(&s.peak)
+		if n <= max || atomic.CompareAndSwapInt32(&s.peak, max, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	atomic.AddInt32(&s.running, -1)
+	return nil, nil
+}
+
+func TestLimit(t *testing.T) {
+	slow := &slowProvider{GitProvider: NewFakeProvider()}
+	limited := Limit(slow, 2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limited.GetIssues(context.Background(), 1, "r")
+		}()
+	}
+	wg.Wait()
+
+	if slow.peak > 2 {
+		t.Errorf("Limit ran %d calls at once, want at most 2", slow.peak)
+	}
+	if unwrap(limited) != GitProvider(slow) {
+		t.Errorf("unwrap did not return the limited provider")
+	}
+	if unwrap(Timeout(limited, time.Second)) != GitProvider(slow) {
+		t.Errorf("Timeout did not reuse the existing limits")
+	}
+}
+
+// blockingProvider waits until its context is done
+type blockingProvider struct {
+	GitProvider
+}
+
+func (b *blockingProvider) GetLabels(ctx context.Context, pid int, repo string) ([]*GitLabel, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestTimeout(t *testing.T) {
+	limited := Timeout(&blockingProvider{GitProvider: NewFakeProvider()}, 10*time.Millisecond)
+	if _, err := limited.GetLabels(context.Background(), 1, "r"); err != context.DeadlineExceeded {
+		t.Errorf("GetLabels returned %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Limit(NewFakeProvider(), 1).GetLabels(ctx, 1, "r"); err != context.Canceled {
+		t.Errorf("GetLabels returned %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,1012 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 Artur Sak
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/storer"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	githttp "gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+
+	"github.com/artur-sak13/gitmv/auth"
+)
+
+// mirrorRemoteName is the remote local repositories are pushed through
+const mirrorRemoteName = "mirror"
+
+// mirrorRefSpecs are the refs copied by mirror fetches and pushes
+var mirrorRefSpecs = []config.RefSpec{
+	"+refs/heads/*:refs/heads/*",
+	"+refs/tags/*:refs/tags/*",
+}
+
+// LocalProvider implements the provider interface on top of a directory of bare git repositories
+// Each repository <name>.git may be accompanied by a <name>.json sidecar file
+// holding its description, labels, issues and comments.
+type LocalProvider struct {
+	Root string
+	ID   *auth.ID
+
+	mu sync.Mutex
+}
+
+type (
+	localSidecar struct {
+		Description  string              `json:"description,omitempty"`
+		Archived     bool                `json:"archived,omitempty"`
+		Topics       []string            `json:"topics,omitempty"`
+		Visibility   string              `json:"visibility,omitempty"`
+		Labels       []*localLabel       `json:"labels"`
+		Issues       []*localIssue       `json:"issues"`
+		PullRequests []*localPullRequest `json:"pull_requests,omitempty"`
+		Members      []*localMember      `json:"members,omitempty"`
+	}
+
+	localMember struct {
+		Login      string `json:"login"`
+		Permission string `json:"permission"`
+		Group      string `json:"group,omitempty"`
+	}
+
+	localLabel struct {
+		Name        string `json:"name"`
+		Color       string `json:"color,omitempty"`
+		Description string `json:"description,omitempty"`
+	}
+
+	localUser struct {
+		Login string `json:"login,omitempty"`
+		Name  string `json:"name,omitempty"`
+		Email string `json:"email,omitempty"`
+	}
+
+	localIssue struct {
+		Number    int             `json:"number"`
+		Title     string          `json:"title"`
+		Body      string          `json:"body,omitempty"`
+		State     string          `json:"state"`
+		Labels    []string        `json:"labels,omitempty"`
+		User      *localUser      `json:"user,omitempty"`
+		Assignees []*localUser    `json:"assignees,omitempty"`
+		Comments  []*localComment `json:"comments,omitempty"`
+		// UpdatedAt is zero for issues written by hand, which always count as updated
+		UpdatedAt time.Time `json:"updated_at"`
+	}
+
+	localPullRequest struct {
+		Number       int                   `json:"number"`
+		Title        string                `json:"title"`
+		Body         string                `json:"body,omitempty"`
+		State        string                `json:"state"`
+		SourceBranch string                `json:"source_branch"`
+		TargetBranch string                `json:"target_branch"`
+		HeadSHA      string                `json:"head_sha,omitempty"`
+		Labels       []string              `json:"labels,omitempty"`
+		User         *localUser            `json:"user,omitempty"`
+		Assignees    []*localUser          `json:"assignees,omitempty"`
+		Changes      []*localFileChange    `json:"changes,omitempty"`
+		Comments     []*localReviewComment `json:"comments,omitempty"`
+	}
+
+	localFileChange struct {
+		OldPath     string `json:"old_path"`
+		NewPath     string `json:"new_path"`
+		Additions   int    `json:"additions"`
+		Deletions   int    `json:"deletions"`
+		NewFile     bool   `json:"new_file,omitempty"`
+		RenamedFile bool   `json:"renamed_file,omitempty"`
+		DeletedFile bool   `json:"deleted_file,omitempty"`
+	}
+
+	localReviewComment struct {
+		ID        int        `json:"id,omitempty"`
+		User      *localUser `json:"user,omitempty"`
+		Body      string     `json:"body"`
+		Path      string     `json:"path,omitempty"`
+		Line      int        `json:"line,omitempty"`
+		OldLine   int        `json:"old_line,omitempty"`
+		CommitID  string     `json:"commit_id,omitempty"`
+		CreatedAt time.Time  `json:"created_at"`
+		UpdatedAt time.Time  `json:"updated_at"`
+	}
+
+	localComment struct {
+		ID        int        `json:"id,omitempty"`
+		User      *localUser `json:"user,omitempty"`
+		Body      string     `json:"body"`
+		CreatedAt time.Time  `json:"created_at"`
+		UpdatedAt time.Time  `json:"updated_at"`
+	}
+)
+
+// NewLocalProvider creates a new provider rooted at the directory in the user's URL
+func NewLocalProvider(id *auth.ID) (GitProvider, error) {
+	root := strings.TrimPrefix(id.URL, "file://")
+	if root == "" {
+		return nil, fmt.Errorf("local provider directory cannot be empty")
+	}
+
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve directory %s due to: %v", id.URL, err)
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory %s due to: %v", root, err)
+	}
+
+	return &LocalProvider{
+		Root: root,
+		ID:   id,
+	}, nil
+}
+
+// CreateRepository initializes a new bare repository and its sidecar file
+func (l *LocalProvider) CreateRepository(ctx context.Context, repo *GitRepository) (*GitRepository, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	r, err := git.PlainInit(l.repoPath(repo.Name), true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create repository %s due to: %v", repo.Name, err)
+	}
+
+	sidecar, err := l.readSidecar(repo.Name)
+	if err != nil {
+		return nil, err
+	}
+	sidecar.Description = repo.Description
+	sidecar.Archived = repo.Archived
+	sidecar.Topics = repo.Topics
+	sidecar.Visibility = repo.Visibility
+	if err := l.writeSidecar(repo.Name, sidecar); err != nil {
+		return nil, err
+	}
+
+	return l.toGitRepository(repo.Name, r, sidecar), nil
+}
+
+// MigrateRepo mirrors the branches and tags of a repository from an existing provider into the directory
+func (l *LocalProvider) MigrateRepo(ctx context.Context, repo *GitRepository, token string) (string, error) {
+	r, err := git.PlainOpen(l.repoPath(repo.Name))
+	if err == git.ErrRepositoryNotExists {
+		r, err = git.PlainInit(l.repoPath(repo.Name), true)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to open repository %s due to: %v", repo.Name, err)
+	}
+
+	remote, err := replaceRemote(r, git.DefaultRemoteName, repo.CloneURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to configure remote for %s due to: %v", repo.Name, err)
+	}
+	auth := transportAuth(repo.CloneURL, token)
+
+	refs, err := remote.List(&git.ListOptions{Auth: auth})
+	if err == transport.ErrEmptyRemoteRepository {
+		return ImportComplete, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to list refs of %s due to: %v", repo.CloneURL, err)
+	}
+
+	err = remote.FetchContext(ctx, &git.FetchOptions{
+		RefSpecs: mirrorRefSpecs,
+		Auth:     auth,
+		Tags:     git.NoTags,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return "", fmt.Errorf("failed to fetch %s due to: %v", repo.CloneURL, err)
+	}
+
+	// keep the source's default branch
+	for _, ref := range refs {
+		if ref.Name() == plumbing.HEAD && ref.Type() == plumbing.SymbolicReference {
+			if err := r.Storer.SetReference(ref); err != nil {
+				return "", fmt.Errorf("failed to set HEAD of %s due to: %v", repo.Name, err)
+			}
+		}
+	}
+
+	return ImportComplete, nil
+}
+
+// PushMirror pushes the branches and tags of a local repository to a remote URL
+// Hosted providers cannot import from the local filesystem, so local sources are pushed instead.
+func (l *LocalProvider) PushMirror(ctx context.Context, repo *GitRepository, remoteURL, token string) error {
+	r, err := git.PlainOpen(l.repoPath(repo.Name))
+	if err != nil {
+		return fmt.Errorf("failed to open repository %s due to: %v", repo.Name, err)
+	}
+
+	remote, err := replaceRemote(r, mirrorRemoteName, remoteURL)
+	if err != nil {
+		return fmt.Errorf("failed to configure remote for %s due to: %v", repo.Name, err)
+	}
+	err = remote.PushContext(ctx, &git.PushOptions{
+		RemoteName: mirrorRemoteName,
+		RefSpecs:   mirrorRefSpecs,
+		Auth:       transportAuth(remoteURL, token),
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return fmt.Errorf("failed to push %s to %s due to: %v", repo.Name, remoteURL, err)
+	}
+	return nil
+}
+
+// replaceRemote points the named remote of a repository at a new URL
+func replaceRemote(r *git.Repository, name, remoteURL string) (*git.Remote, error) {
+	if err := r.DeleteRemote(name); err != nil && err != git.ErrRemoteNotFound {
+		return nil, err
+	}
+	return r.CreateRemote(&config.RemoteConfig{
+		Name: name,
+		URLs: []string{remoteURL},
+	})
+}
+
+// transportAuth authenticates HTTP remotes with a token and leaves other transports unchanged
+func transportAuth(remoteURL, token string) transport.AuthMethod {
+	if token == "" || !strings.HasPrefix(remoteURL, "http") {
+		return nil
+	}
+	return &githttp.BasicAuth{
+		Username: "oauth2",
+		Password: token,
+	}
+}
+
+// GetImportProgress reports an import as complete once the repository has any branches
+func (l *LocalProvider) GetImportProgress(ctx context.Context, repo string) (string, error) {
+	r, err := git.PlainOpen(l.repoPath(repo))
+	if err != nil {
+		return "", fmt.Errorf("failed to open repository %s due to: %v", repo, err)
+	}
+	if isEmptyRepository(r) {
+		return "", fmt.Errorf("no import found for %s", repo)
+	}
+	return ImportComplete, nil
+}
+
+// CreateIssue appends a new issue to a repository's sidecar file
+func (l *LocalProvider) CreateIssue(ctx context.Context, issue *GitIssue) (*GitIssue, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sidecar, err := l.readSidecar(issue.Repo)
+	if err != nil {
+		return nil, err
+	}
+
+	number := 1
+	for _, existing := range sidecar.Issues {
+		if existing.Number >= number {
+			number = existing.Number + 1
+		}
+	}
+
+	state := issue.State
+	if state == "" {
+		state = "open"
+	}
+	var assignees []*localUser
+	for _, assignee := range issue.Assignees {
+		assignees = append(assignees, toLocalUser(&assignee))
+	}
+	sidecar.Issues = append(sidecar.Issues, &localIssue{
+		Number:    number,
+		Title:     issue.Title,
+		Body:      issue.Body,
+		State:     state,
+		Labels:    *ToGitLabelStringSlice(issue.Labels),
+		User:      toLocalUser(issue.User),
+		Assignees: assignees,
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err := l.writeSidecar(issue.Repo, sidecar); err != nil {
+		return nil, err
+	}
+
+	created := *issue
+	created.Number = number
+	created.State = state
+	return &created, nil
+}
+
+// CreateIssueComment appends a comment to an issue in a repository's sidecar file
+func (l *LocalProvider) CreateIssueComment(ctx context.Context, issueNum int, comment *GitIssueComment) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sidecar, err := l.readSidecar(comment.Repo)
+	if err != nil {
+		return err
+	}
+
+	for _, issue := range sidecar.Issues {
+		if issue.Number != issueNum {
+			continue
+		}
+		issue.Comments = append(issue.Comments, &localComment{
+			ID:        len(issue.Comments) + 1,
+			User:      toLocalUser(&comment.User),
+			Body:      comment.Body,
+			CreatedAt: comment.CreatedAt,
+			UpdatedAt: comment.UpdatedAt,
+		})
+		issue.UpdatedAt = time.Now().UTC()
+		return l.writeSidecar(comment.Repo, sidecar)
+	}
+	return fmt.Errorf("issue number '%d' does not exist for %s", issueNum, comment.Repo)
+}
+
+// CreatePullRequest appends a new pull request to a repository's sidecar file
+func (l *LocalProvider) CreatePullRequest(ctx context.Context, pr *GitPullRequest) (*GitPullRequest, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sidecar, err := l.readSidecar(pr.Repo)
+	if err != nil {
+		return nil, err
+	}
+
+	number := 1
+	for _, existing := range sidecar.PullRequests {
+		if existing.Number >= number {
+			number = existing.Number + 1
+		}
+	}
+
+	var assignees []*localUser
+	for _, assignee := range pr.Assignees {
+		assignees = append(assignees, toLocalUser(&assignee))
+	}
+	sidecar.PullRequests = append(sidecar.PullRequests, &localPullRequest{
+		Number:       number,
+		Title:        pr.Title,
+		Body:         pr.Body,
+		State:        pr.State,
+		SourceBranch: pr.SourceBranch,
+		TargetBranch: pr.TargetBranch,
+		HeadSHA:      pr.HeadSHA,
+		Labels:       *ToGitLabelStringSlice(pr.Labels),
+		User:         toLocalUser(pr.User),
+		Assignees:    assignees,
+		Changes:      toLocalFileChanges(pr.Changes),
+	})
+	if err := l.writeSidecar(pr.Repo, sidecar); err != nil {
+		return nil, err
+	}
+
+	created := *pr
+	created.Number = number
+	return &created, nil
+}
+
+// CreateReviewComment appends a comment to a pull request in a repository's sidecar file
+func (l *LocalProvider) CreateReviewComment(ctx context.Context, pr *GitPullRequest, comment *GitReviewComment) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sidecar, err := l.readSidecar(comment.Repo)
+	if err != nil {
+		return err
+	}
+
+	for _, existing := range sidecar.PullRequests {
+		if existing.Number != pr.Number {
+			continue
+		}
+		existing.Comments = append(existing.Comments, &localReviewComment{
+			ID:        len(existing.Comments) + 1,
+			User:      toLocalUser(&comment.User),
+			Body:      comment.Body,
+			Path:      comment.Path,
+			Line:      comment.Line,
+			OldLine:   comment.OldLine,
+			CommitID:  comment.CommitID,
+			CreatedAt: comment.CreatedAt,
+			UpdatedAt: comment.UpdatedAt,
+		})
+		return l.writeSidecar(comment.Repo, sidecar)
+	}
+	return fmt.Errorf("pull request number '%d' does not exist for %s", pr.Number, comment.Repo)
+}
+
+// CreateLabel adds a label to a repository's sidecar file
+func (l *LocalProvider) CreateLabel(ctx context.Context, label *GitLabel) (*GitLabel, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sidecar, err := l.readSidecar(label.Repo)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, existing := range sidecar.Labels {
+		if existing.Name == label.Name {
+			return nil, fmt.Errorf("label %s already exists for %s", label.Name, label.Repo)
+		}
+	}
+	sidecar.Labels = append(sidecar.Labels, &localLabel{
+		Name:        label.Name,
+		Color:       label.Color,
+		Description: label.Description,
+	})
+	if err := l.writeSidecar(label.Repo, sidecar); err != nil {
+		return nil, err
+	}
+	return label, nil
+}
+
+// UpdateLabel replaces the color and description of a label in a repository's sidecar file
+func (l *LocalProvider) UpdateLabel(ctx context.Context, label *GitLabel) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sidecar, err := l.readSidecar(label.Repo)
+	if err != nil {
+		return err
+	}
+	for _, existing := range sidecar.Labels {
+		if existing.Name != label.Name {
+			continue
+		}
+		existing.Color = label.Color
+		existing.Description = label.Description
+		return l.writeSidecar(label.Repo, sidecar)
+	}
+	return fmt.Errorf("label %s does not exist for %s", label.Name, label.Repo)
+}
+
+// UpdateIssue replaces the title, body, state and labels of an issue in a repository's sidecar file
+func (l *LocalProvider) UpdateIssue(ctx context.Context, issue *GitIssue) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sidecar, err := l.readSidecar(issue.Repo)
+	if err != nil {
+		return err
+	}
+	for _, existing := range sidecar.Issues {
+		if existing.Number != issue.Number {
+			continue
+		}
+		existing.Title = issue.Title
+		existing.Body = issue.Body
+		existing.State = "open"
+		if issue.State == "closed" {
+			existing.State = "closed"
+		}
+		existing.Labels = *ToGitLabelStringSlice(issue.Labels)
+		existing.UpdatedAt = time.Now().UTC()
+		return l.writeSidecar(issue.Repo, sidecar)
+	}
+	return fmt.Errorf("issue number '%d' does not exist for %s", issue.Number, issue.Repo)
+}
+
+// GrantAccess adds members to a repository's sidecar file, replacing the permission of existing ones
+func (l *LocalProvider) GrantAccess(ctx context.Context, repo *GitRepository, members []*GitMember) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sidecar, err := l.readSidecar(repo.Name)
+	if err != nil {
+		return err
+	}
+	for _, member := range members {
+		granted := &localMember{Login: member.User.Login, Permission: string(member.Permission), Group: member.Group}
+		replaced := false
+		for i, existing := range sidecar.Members {
+			if existing.Login == granted.Login && existing.Group == granted.Group {
+				sidecar.Members[i], replaced = granted, true
+			}
+		}
+		if !replaced {
+			sidecar.Members = append(sidecar.Members, granted)
+		}
+	}
+	return l.writeSidecar(repo.Name, sidecar)
+}
+
+// GetMembers retrieves the members recorded in a repository's sidecar file
+func (l *LocalProvider) GetMembers(ctx context.Context, repo *GitRepository) ([]*GitMember, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sidecar, err := l.readSidecar(repo.Name)
+	if err != nil {
+		return nil, err
+	}
+	var members []*GitMember
+	for _, member := range sidecar.Members {
+		members = append(members, &GitMember{
+			User:       GitUser{Login: member.Login},
+			Permission: Permission(member.Permission),
+			Group:      member.Group,
+		})
+	}
+	return members, nil
+}
+
+// DeleteRepository removes a bare repository along with its wiki and sidecar file
+func (l *LocalProvider) DeleteRepository(ctx context.Context, repo *GitRepository) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := os.Stat(l.repoPath(repo.Name)); err != nil {
+		return fmt.Errorf("failed to delete repository %s due to: %v", repo.Name, err)
+	}
+	for _, path := range []string{l.repoPath(repo.Name), toWikiURL(l.repoPath(repo.Name)), l.sidecarPath(repo.Name)} {
+		if err := os.RemoveAll(path); err != nil {
+			return fmt.Errorf("failed to delete repository %s due to: %v", repo.Name, err)
+		}
+	}
+	return nil
+}
+
+// ArchiveRepository marks a repository as archived in its sidecar file
+func (l *LocalProvider) ArchiveRepository(ctx context.Context, repo *GitRepository) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sidecar, err := l.readSidecar(repo.Name)
+	if err != nil {
+		return err
+	}
+	sidecar.Archived = true
+	return l.writeSidecar(repo.Name, sidecar)
+}
+
+// DeleteLabel removes a label from a repository's sidecar file
+func (l *LocalProvider) DeleteLabel(ctx context.Context, label *GitLabel) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sidecar, err := l.readSidecar(label.Repo)
+	if err != nil {
+		return err
+	}
+	for i, existing := range sidecar.Labels {
+		if existing.Name == label.Name {
+			sidecar.Labels = append(sidecar.Labels[:i], sidecar.Labels[i+1:]...)
+			return l.writeSidecar(label.Repo, sidecar)
+		}
+	}
+	return fmt.Errorf("label %s does not exist for %s", label.Name, label.Repo)
+}
+
+// DeleteIssue removes an issue and its comments from a repository's sidecar file
+func (l *LocalProvider) DeleteIssue(ctx context.Context, issue *GitIssue) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sidecar, err := l.readSidecar(issue.Repo)
+	if err != nil {
+		return err
+	}
+	for i, existing := range sidecar.Issues {
+		if existing.Number == issue.Number {
+			sidecar.Issues = append(sidecar.Issues[:i], sidecar.Issues[i+1:]...)
+			return l.writeSidecar(issue.Repo, sidecar)
+		}
+	}
+	return fmt.Errorf("issue number '%d' does not exist for %s", issue.Number, issue.Repo)
+}
+
+// GetRepositories retrieves every bare repository in the directory
+func (l *LocalProvider) GetRepositories(ctx context.Context) ([]*GitRepository, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries, err := ioutil.ReadDir(l.Root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %s due to: %v", l.Root, err)
+	}
+
+	var repos []*GitRepository
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasSuffix(entry.Name(), ".git") || strings.HasSuffix(entry.Name(), ".wiki.git") {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), ".git")
+
+		r, err := git.PlainOpen(l.repoPath(name))
+		if err != nil {
+			continue
+		}
+		sidecar, err := l.readSidecar(name)
+		if err != nil {
+			return nil, err
+		}
+		repos = append(repos, l.toGitRepository(name, r, sidecar))
+	}
+	return repos, nil
+}
+
+// GetIssues retrieves the issues in a repository's sidecar file
+func (l *LocalProvider) GetIssues(ctx context.Context, pid int, repo string) ([]*GitIssue, error) {
+	return l.listIssues(pid, repo, time.Time{})
+}
+
+// GetIssuesUpdatedAfter retrieves the issues in a repository's sidecar file updated after since
+func (l *LocalProvider) GetIssuesUpdatedAfter(ctx context.Context, pid int, repo string, since time.Time) ([]*GitIssue, error) {
+	return l.listIssues(pid, repo, since)
+}
+
+// listIssues retrieves the issues in a repository's sidecar file, skipping those last updated before since
+func (l *LocalProvider) listIssues(pid int, repo string, since time.Time) ([]*GitIssue, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sidecar, err := l.readSidecar(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	issues := []*GitIssue{}
+	for _, issue := range sidecar.Issues {
+		if !issue.UpdatedAt.IsZero() && !issue.UpdatedAt.After(since) {
+			continue
+		}
+		assignees := []GitUser{}
+		for _, assignee := range issue.Assignees {
+			assignees = append(assignees, *fromLocalUser(assignee))
+		}
+		issues = append(issues, &GitIssue{
+			Repo:      repo,
+			PID:       pid,
+			Number:    issue.Number,
+			Title:     issue.Title,
+			Body:      issue.Body,
+			State:     issue.State,
+			Labels:    ToGitLabels(issue.Labels),
+			User:      fromLocalUser(issue.User),
+			Assignees: assignees,
+		})
+	}
+	return issues, nil
+}
+
+// GetComments retrieves the comments of an issue in a repository's sidecar file
+func (l *LocalProvider) GetComments(ctx context.Context, pid, issueNum int, repo string) ([]*GitIssueComment, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sidecar, err := l.readSidecar(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	comments := []*GitIssueComment{}
+	for _, issue := range sidecar.Issues {
+		if issue.Number != issueNum {
+			continue
+		}
+		for i, comment := range issue.Comments {
+			// comments written before IDs were stored are identified by position
+			id := comment.ID
+			if id == 0 {
+				id = i + 1
+			}
+			comments = append(comments, &GitIssueComment{
+				ID:        id,
+				Repo:      repo,
+				IssueNum:  issueNum,
+				User:      *fromLocalUser(comment.User),
+				Body:      comment.Body,
+				CreatedAt: comment.CreatedAt,
+				UpdatedAt: comment.UpdatedAt,
+			})
+		}
+	}
+	return comments, nil
+}
+
+// GetLabels retrieves the labels in a repository's sidecar file
+func (l *LocalProvider) GetLabels(ctx context.Context, pid int, repo string) ([]*GitLabel, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sidecar, err := l.readSidecar(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := []*GitLabel{}
+	for _, label := range sidecar.Labels {
+		labels = append(labels, &GitLabel{
+			Repo:        repo,
+			Name:        label.Name,
+			Color:       label.Color,
+			Description: label.Description,
+		})
+	}
+	return labels, nil
+}
+
+// GetRefs retrieves the branches and tags of a bare repository, annotated tags are peeled to their commit
+func (l *LocalProvider) GetRefs(ctx context.Context, pid int, repo string) ([]*GitRef, error) {
+	r, err := git.PlainOpen(l.repoPath(repo))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository %s due to: %v", repo, err)
+	}
+
+	iter, err := r.References()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list refs of %s due to: %v", repo, err)
+	}
+	defer iter.Close()
+
+	var refs []*GitRef
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() != plumbing.HashReference {
+			return nil
+		}
+		switch {
+		case ref.Name().IsBranch():
+			refs = append(refs, &GitRef{Name: ref.Name().Short(), SHA: ref.Hash().String()})
+		case ref.Name().IsTag():
+			sha := ref.Hash().String()
+			if tag, err := r.TagObject(ref.Hash()); err == nil {
+				sha = tag.Target.String()
+				if commit, err := tag.Commit(); err == nil {
+					sha = commit.Hash.String()
+				}
+			}
+			refs = append(refs, &GitRef{Name: ref.Name().Short(), Tag: true, SHA: sha})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list refs of %s due to: %v", repo, err)
+	}
+	return refs, nil
+}
+
+// GetPullRequests retrieves the pull requests in a repository's sidecar file
+func (l *LocalProvider) GetPullRequests(ctx context.Context, pid int, repo string) ([]*GitPullRequest, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sidecar, err := l.readSidecar(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	prs := []*GitPullRequest{}
+	for _, pr := range sidecar.PullRequests {
+		assignees := []GitUser{}
+		for _, assignee := range pr.Assignees {
+			assignees = append(assignees, *fromLocalUser(assignee))
+		}
+		prs = append(prs, &GitPullRequest{
+			Repo:         repo,
+			PID:          pid,
+			Number:       pr.Number,
+			Title:        pr.Title,
+			Body:         pr.Body,
+			State:        pr.State,
+			SourceBranch: pr.SourceBranch,
+			TargetBranch: pr.TargetBranch,
+			HeadSHA:      pr.HeadSHA,
+			Labels:       ToGitLabels(pr.Labels),
+			User:         fromLocalUser(pr.User),
+			Assignees:    assignees,
+			Changes:      fromLocalFileChanges(pr.Changes),
+		})
+	}
+	return prs, nil
+}
+
+// GetReviewComments retrieves the comments of a pull request in a repository's sidecar file
+func (l *LocalProvider) GetReviewComments(ctx context.Context, pid, pullNum int, repo string) ([]*GitReviewComment, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sidecar, err := l.readSidecar(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	comments := []*GitReviewComment{}
+	for _, pr := range sidecar.PullRequests {
+		if pr.Number != pullNum {
+			continue
+		}
+		for i, comment := range pr.Comments {
+			// comments written before IDs were stored are identified by position
+			id := comment.ID
+			if id == 0 {
+				id = i + 1
+			}
+			comments = append(comments, &GitReviewComment{
+				ID:        id,
+				Repo:      repo,
+				PullNum:   pullNum,
+				User:      *fromLocalUser(comment.User),
+				Body:      comment.Body,
+				Path:      comment.Path,
+				Line:      comment.Line,
+				OldLine:   comment.OldLine,
+				CommitID:  comment.CommitID,
+				CreatedAt: comment.CreatedAt,
+				UpdatedAt: comment.UpdatedAt,
+			})
+		}
+	}
+	return comments, nil
+}
+
+// GetAuth returns a string with a user's api authentication token
+func (l *LocalProvider) GetAuth() *auth.ID {
+	return l.ID
+}
+
+func (l *LocalProvider) repoPath(name string) string {
+	return filepath.Join(l.Root, name+".git")
+}
+
+func (l *LocalProvider) sidecarPath(name string) string {
+	return filepath.Join(l.Root, name+".json")
+}
+
+// readSidecar loads a repository's sidecar file, treating a missing file as empty
+func (l *LocalProvider) readSidecar(name string) (*localSidecar, error) {
+	sidecar := &localSidecar{}
+
+	data, err := ioutil.ReadFile(l.sidecarPath(name))
+	if os.IsNotExist(err) {
+		return sidecar, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sidecar for %s due to: %v", name, err)
+	}
+	if err := json.Unmarshal(data, sidecar); err != nil {
+		return nil, fmt.Errorf("failed to parse sidecar for %s due to: %v", name, err)
+	}
+
+	sort.SliceStable(sidecar.Issues, func(i, j int) bool {
+		return sidecar.Issues[i].Number < sidecar.Issues[j].Number
+	})
+	return sidecar, nil
+}
+
+// writeSidecar atomically replaces a repository's sidecar file
+func (l *LocalProvider) writeSidecar(name string, sidecar *localSidecar) error {
+	data, err := json.MarshalIndent(sidecar, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode sidecar for %s due to: %v", name, err)
+	}
+
+	tmp := l.sidecarPath(name) + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write sidecar for %s due to: %v", name, err)
+	}
+	if err := os.Rename(tmp, l.sidecarPath(name)); err != nil {
+		return fmt.Errorf("failed to write sidecar for %s due to: %v", name, err)
+	}
+	return nil
+}
+
+func (l *LocalProvider) toGitRepository(name string, r *git.Repository, sidecar *localSidecar) *GitRepository {
+	path := l.repoPath(name)
+	return &GitRepository{
+		Name:        name,
+		Description: sidecar.Description,
+		CloneURL:    path,
+		SSHURL:      path,
+		Owner:       l.ID.Owner,
+		Archived:    sidecar.Archived,
+		Empty:       isEmptyRepository(r),
+		PID:         hashPID(name),
+
+		Topics:     sidecar.Topics,
+		Visibility: sidecar.Visibility,
+	}
+}
+
+// isEmptyRepository reports whether a repository has no branches
+func isEmptyRepository(r *git.Repository) bool {
+	refs, err := r.References()
+	if err != nil {
+		return true
+	}
+	defer refs.Close()
+
+	empty := true
+	_ = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Name().IsBranch() {
+			empty = false
+			return storer.ErrStop
+		}
+		return nil
+	})
+	return empty
+}
+
+func toLocalUser(user *GitUser) *localUser {
+	if user == nil || *user == (GitUser{}) {
+		return nil
+	}
+	return &localUser{
+		Login: user.Login,
+		Name:  user.Name,
+		Email: user.Email,
+	}
+}
+
+func fromLocalUser(user *localUser) *GitUser {
+	if user == nil {
+		return &GitUser{}
+	}
+	return &GitUser{
+		Login: user.Login,
+		Name:  user.Name,
+		Email: user.Email,
+	}
+}
+
+func toLocalFileChanges(changes []GitFileChange) []*localFileChange {
+	var result []*localFileChange
+	for _, change := range changes {
+		result = append(result, &localFileChange{
+			OldPath:     change.OldPath,
+			NewPath:     change.NewPath,
+			Additions:   change.Additions,
+			Deletions:   change.Deletions,
+			NewFile:     change.NewFile,
+			RenamedFile: change.RenamedFile,
+			DeletedFile: change.DeletedFile,
+		})
+	}
+	return result
+}
+
+func fromLocalFileChanges(changes []*localFileChange) []GitFileChange {
+	result := []GitFileChange{}
+	for _, change := range changes {
+		result = append(result, GitFileChange{
+			OldPath:     change.OldPath,
+			NewPath:     change.NewPath,
+			Additions:   change.Additions,
+			Deletions:   change.Deletions,
+			NewFile:     change.NewFile,
+			RenamedFile: change.RenamedFile,
+			DeletedFile: change.DeletedFile,
+		})
+	}
+	return result
+}
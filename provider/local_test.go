@@ -0,0 +1,541 @@
+package provider
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+
+	"github.com/artur-sak13/gitmv/auth"
+)
+
+func setupLocal(t *testing.T) (*LocalProvider, func()) {
+	dir, err := ioutil.TempDir("", "gitmv-local")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+
+	prov, err := New(context.Background(), "local", auth.NewAuthID(dir, "", "testorg"))
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+
+	return prov.(*LocalProvider), func() { os.RemoveAll(dir) }
+}
+
+// setupWorkingRepo creates a non-bare repository with a single commit on master
+func setupWorkingRepo(t *testing.T) (string, plumbing.Hash, func()) {
+	dir, err := ioutil.TempDir("", "gitmv-src")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+
+	r, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("PlainInit returned error: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("hello\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatalf("Worktree returned error: %v", err)
+	}
+	if _, err := w.Add("README.md"); err != nil {
+		t.Fatalf("Add returned error: %v", err)
+	}
+	hash, err := w.Commit("initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("Commit returned error: %v", err)
+	}
+
+	return dir, hash, func() { os.RemoveAll(dir) }
+}
+
+func requireGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is required for the file transport")
+	}
+}
+
+func TestLocal_MigrateRepo(t *testing.T) {
+	requireGit(t)
+
+	prov, teardown := setupLocal(t)
+	defer teardown()
+	srcDir, hash, srcTeardown := setupWorkingRepo(t)
+	defer srcTeardown()
+
+	repo := &GitRepository{Name: "r", Description: "d", CloneURL: srcDir}
+	if _, err := prov.CreateRepository(context.Background(), repo); err != nil {
+		t.Fatalf("CreateRepository returned error: %v", err)
+	}
+	if _, err := prov.GetImportProgress(context.Background(), "r"); err == nil {
+		t.Errorf("GetImportProgress expected error for an empty repository")
+	}
+
+	status, err := prov.MigrateRepo(context.Background(), repo, "")
+	if err != nil {
+		t.Fatalf("MigrateRepo returned error: %v", err)
+	}
+	if status != "complete" {
+		t.Errorf("MigrateRepo = %q, want complete", status)
+	}
+	if status, err := prov.GetImportProgress(context.Background(), "r"); err != nil || status != "complete" {
+		t.Errorf("GetImportProgress = %q, %v, want complete", status, err)
+	}
+
+	r, err := git.PlainOpen(prov.repoPath("r"))
+	if err != nil {
+		t.Fatalf("PlainOpen returned error: %v", err)
+	}
+	ref, err := r.Reference(plumbing.NewBranchReferenceName("master"), true)
+	if err != nil {
+		t.Fatalf("Reference returned error: %v", err)
+	}
+	if ref.Hash() != hash {
+		t.Errorf("master = %s, want %s", ref.Hash(), hash)
+	}
+
+	got, err := prov.GetRepositories(context.Background())
+	if err != nil {
+		t.Errorf("GetRepositories returned error: %v", err)
+	}
+	want := []*GitRepository{
+		{
+			Name:        "r",
+			Description: "d",
+			CloneURL:    prov.repoPath("r"),
+			SSHURL:      prov.repoPath("r"),
+			Owner:       "testorg",
+			PID:         hashPID("r"),
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetRepositories = %+v, want %+v", got, want)
+	}
+}
+
+func TestLocal_PushMirror(t *testing.T) {
+	requireGit(t)
+
+	src, teardown := setupLocal(t)
+	defer teardown()
+	dest, destTeardown := setupLocal(t)
+	defer destTeardown()
+	srcDir, hash, srcTeardown := setupWorkingRepo(t)
+	defer srcTeardown()
+
+	repo := &GitRepository{Name: "r", CloneURL: srcDir}
+	if _, err := src.MigrateRepo(context.Background(), repo, ""); err != nil {
+		t.Fatalf("MigrateRepo returned error: %v", err)
+	}
+
+	destRepo, err := dest.CreateRepository(context.Background(), repo)
+	if err != nil {
+		t.Fatalf("CreateRepository returned error: %v", err)
+	}
+	status, err := MigrateRepo(context.Background(), src, dest, repo, destRepo)
+	if err != nil {
+		t.Fatalf("MigrateRepo returned error: %v", err)
+	}
+	if status != "complete" {
+		t.Errorf("MigrateRepo = %q, want complete", status)
+	}
+
+	r, err := git.PlainOpen(dest.repoPath("r"))
+	if err != nil {
+		t.Fatalf("PlainOpen returned error: %v", err)
+	}
+	ref, err := r.Reference(plumbing.NewBranchReferenceName("master"), true)
+	if err != nil {
+		t.Fatalf("Reference returned error: %v", err)
+	}
+	if ref.Hash() != hash {
+		t.Errorf("master = %s, want %s", ref.Hash(), hash)
+	}
+}
+
+func TestLocal_GetRefs(t *testing.T) {
+	requireGit(t)
+
+	prov, teardown := setupLocal(t)
+	defer teardown()
+	srcDir, hash, srcTeardown := setupWorkingRepo(t)
+	defer srcTeardown()
+
+	if _, err := prov.MigrateRepo(context.Background(), &GitRepository{Name: "r", CloneURL: srcDir}, ""); err != nil {
+		t.Fatalf("MigrateRepo returned error: %v", err)
+	}
+	r, err := git.PlainOpen(prov.repoPath("r"))
+	if err != nil {
+		t.Fatalf("PlainOpen returned error: %v", err)
+	}
+	if _, err := r.CreateTag("v1", hash, &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		Message: "release",
+	}); err != nil {
+		t.Fatalf("CreateTag returned error: %v", err)
+	}
+	if _, err := r.CreateTag("v2", hash, nil); err != nil {
+		t.Fatalf("CreateTag returned error: %v", err)
+	}
+
+	got, err := prov.GetRefs(context.Background(), 0, "r")
+	if err != nil {
+		t.Fatalf("GetRefs returned error: %v", err)
+	}
+	sort.Slice(got, func(i, j int) bool { return got[i].Name < got[j].Name })
+	want := []*GitRef{
+		{Name: "master", SHA: hash.String()},
+		{Name: "v1", Tag: true, SHA: hash.String()},
+		{Name: "v2", Tag: true, SHA: hash.String()},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetRefs = %+v, want %+v", got, want)
+	}
+}
+
+func TestSyncRefs(t *testing.T) {
+	requireGit(t)
+
+	src, teardown := setupLocal(t)
+	defer teardown()
+	dest, destTeardown := setupLocal(t)
+	defer destTeardown()
+	srcDir, hash, srcTeardown := setupWorkingRepo(t)
+	defer srcTeardown()
+
+	if _, err := src.MigrateRepo(context.Background(), &GitRepository{Name: "r", CloneURL: srcDir}, ""); err != nil {
+		t.Fatalf("MigrateRepo returned error: %v", err)
+	}
+	repo := &GitRepository{Name: "r", CloneURL: src.repoPath("r")}
+	destRepo, err := dest.CreateRepository(context.Background(), repo)
+	if err != nil {
+		t.Fatalf("CreateRepository returned error: %v", err)
+	}
+
+	if synced, err := SyncRefs(context.Background(), src, dest, repo, destRepo); err != nil || !synced {
+		t.Errorf("SyncRefs = %t, %v, want true", synced, err)
+	}
+	if synced, err := SyncRefs(context.Background(), src, dest, repo, destRepo); err != nil || synced {
+		t.Errorf("SyncRefs = %t, %v for an unchanged repository, want false", synced, err)
+	}
+
+	r, err := git.PlainOpen(src.repoPath("r"))
+	if err != nil {
+		t.Fatalf("PlainOpen returned error: %v", err)
+	}
+	if _, err := r.CreateTag("v1", hash, nil); err != nil {
+		t.Fatalf("CreateTag returned error: %v", err)
+	}
+	if synced, err := SyncRefs(context.Background(), src, dest, repo, destRepo); err != nil || !synced {
+		t.Errorf("SyncRefs = %t, %v after a new tag, want true", synced, err)
+	}
+
+	// hosted providers are mirrored through memory
+	other, otherTeardown := setupLocal(t)
+	defer otherTeardown()
+	if _, err := other.CreateRepository(context.Background(), repo); err != nil {
+		t.Fatalf("CreateRepository returned error: %v", err)
+	}
+	if err := mirrorRefs(context.Background(), src.repoPath("r"), "", other.repoPath("r"), ""); err != nil {
+		t.Fatalf("mirrorRefs returned error: %v", err)
+	}
+
+	for _, prov := range []*LocalProvider{dest, other} {
+		got, err := prov.GetRefs(context.Background(), 0, "r")
+		if err != nil {
+			t.Fatalf("GetRefs returned error: %v", err)
+		}
+		sort.Slice(got, func(i, j int) bool { return got[i].Name < got[j].Name })
+		want := []*GitRef{
+			{Name: "master", SHA: hash.String()},
+			{Name: "v1", Tag: true, SHA: hash.String()},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("GetRefs = %+v, want %+v", got, want)
+		}
+	}
+}
+
+func TestLocal_Sidecar(t *testing.T) {
+	prov, teardown := setupLocal(t)
+	defer teardown()
+
+	if _, err := prov.CreateRepository(context.Background(), &GitRepository{Name: "r"}); err != nil {
+		t.Fatalf("CreateRepository returned error: %v", err)
+	}
+
+	label := &GitLabel{Repo: "r", Name: "bug", Color: "ff0000"}
+	if _, err := prov.CreateLabel(context.Background(), label); err != nil {
+		t.Errorf("CreateLabel returned error: %v", err)
+	}
+	if _, err := prov.CreateLabel(context.Background(), label); err == nil {
+		t.Errorf("CreateLabel expected error for a duplicate label")
+	}
+
+	user := &GitUser{Login: "jane", Name: "Jane", Email: "jane@example.com"}
+	issue, err := prov.CreateIssue(context.Background(), &GitIssue{
+		Repo:      "r",
+		Number:    12,
+		Title:     "t",
+		Body:      "b",
+		State:     "closed",
+		Labels:    []GitLabel{{Name: "bug"}},
+		User:      user,
+		Assignees: []GitUser{*user},
+	})
+	if err != nil {
+		t.Fatalf("CreateIssue returned error: %v", err)
+	}
+	if issue.Number != 1 {
+		t.Errorf("CreateIssue number = %d, want 1", issue.Number)
+	}
+
+	created := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	comment := &GitIssueComment{Repo: "r", IssueNum: 12, User: *user, Body: "c", CreatedAt: created, UpdatedAt: created}
+	if err := prov.CreateIssueComment(context.Background(), issue.Number, comment); err != nil {
+		t.Errorf("CreateIssueComment returned error: %v", err)
+	}
+	if err := prov.CreateIssueComment(context.Background(), 2, comment); err == nil {
+		t.Errorf("CreateIssueComment expected error for a missing issue")
+	}
+
+	label = &GitLabel{Repo: "r", Name: "bug", Color: "00ff00", Description: "d"}
+	if err := prov.UpdateLabel(context.Background(), label); err != nil {
+		t.Errorf("UpdateLabel returned error: %v", err)
+	}
+	if err := prov.UpdateLabel(context.Background(), &GitLabel{Repo: "r", Name: "missing"}); err == nil {
+		t.Errorf("UpdateLabel expected error for a missing label")
+	}
+
+	labels, err := prov.GetLabels(context.Background(), 1, "r")
+	if err != nil {
+		t.Errorf("GetLabels returned error: %v", err)
+	}
+	if want := []*GitLabel{label}; !reflect.DeepEqual(labels, want) {
+		t.Errorf("GetLabels = %+v, want %+v", labels, want)
+	}
+
+	issues, err := prov.GetIssues(context.Background(), 1, "r")
+	if err != nil {
+		t.Errorf("GetIssues returned error: %v", err)
+	}
+	wantIssues := []*GitIssue{
+		{
+			Repo:      "r",
+			PID:       1,
+			Number:    1,
+			Title:     "t",
+			Body:      "b",
+			State:     "closed",
+			Labels:    []GitLabel{{Name: "bug"}},
+			User:      user,
+			Assignees: []GitUser{*user},
+		},
+	}
+	if !reflect.DeepEqual(issues, wantIssues) {
+		t.Errorf("GetIssues = %+v, want %+v", issues, wantIssues)
+	}
+
+	comments, err := prov.GetComments(context.Background(), 1, 1, "r")
+	if err != nil {
+		t.Errorf("GetComments returned error: %v", err)
+	}
+	wantComments := []*GitIssueComment{
+		{ID: 1, Repo: "r", IssueNum: 1, User: *user, Body: "c", CreatedAt: created, UpdatedAt: created},
+	}
+	if !reflect.DeepEqual(comments, wantComments) {
+		t.Errorf("GetComments = %+v, want %+v", comments, wantComments)
+	}
+}
+
+func TestLocal_Members(t *testing.T) {
+	prov, teardown := setupLocal(t)
+	defer teardown()
+
+	repo := &GitRepository{Name: "r"}
+	if _, err := prov.CreateRepository(context.Background(), repo); err != nil {
+		t.Fatalf("CreateRepository returned error: %v", err)
+	}
+
+	granted := []*GitMember{
+		{User: GitUser{Login: "jane"}, Permission: PermissionRead},
+		{User: GitUser{Login: "jane"}, Permission: PermissionWrite, Group: "g"},
+	}
+	if err := prov.GrantAccess(context.Background(), repo, granted); err != nil {
+		t.Errorf("GrantAccess returned error: %v", err)
+	}
+	upgraded := []*GitMember{{User: GitUser{Login: "jane"}, Permission: PermissionMaintain}}
+	if err := prov.GrantAccess(context.Background(), repo, upgraded); err != nil {
+		t.Errorf("GrantAccess returned error: %v", err)
+	}
+
+	members, err := prov.GetMembers(context.Background(), repo)
+	if err != nil {
+		t.Errorf("GetMembers returned error: %v", err)
+	}
+	want := []*GitMember{upgraded[0], granted[1]}
+	if !reflect.DeepEqual(members, want) {
+		t.Errorf("GetMembers = %+v, want %+v", members, want)
+	}
+}
+
+func TestLocal_UpdateIssue(t *testing.T) {
+	prov, teardown := setupLocal(t)
+	defer teardown()
+
+	if _, err := prov.CreateRepository(context.Background(), &GitRepository{Name: "r"}); err != nil {
+		t.Fatalf("CreateRepository returned error: %v", err)
+	}
+	if _, err := prov.CreateIssue(context.Background(), &GitIssue{Repo: "r", Title: "t"}); err != nil {
+		t.Fatalf("CreateIssue returned error: %v", err)
+	}
+	since := time.Now()
+	if issues, _ := prov.GetIssuesUpdatedAfter(context.Background(), 0, "r", since); len(issues) != 0 {
+		t.Errorf("GetIssuesUpdatedAfter = %+v, want none", issues)
+	}
+
+	update := &GitIssue{Repo: "r", Number: 1, Title: "t2", Body: "b", State: "closed", Labels: []GitLabel{{Name: "bug"}}}
+	if err := prov.UpdateIssue(context.Background(), update); err != nil {
+		t.Fatalf("UpdateIssue returned error: %v", err)
+	}
+	if err := prov.UpdateIssue(context.Background(), &GitIssue{Repo: "r", Number: 2}); err == nil {
+		t.Errorf("UpdateIssue expected error for a missing issue")
+	}
+
+	issues, err := prov.GetIssuesUpdatedAfter(context.Background(), 0, "r", since)
+	if err != nil {
+		t.Fatalf("GetIssuesUpdatedAfter returned error: %v", err)
+	}
+	want := []*GitIssue{
+		{Repo: "r", Number: 1, Title: "t2", Body: "b", State: "closed", Labels: []GitLabel{{Name: "bug"}}, User: &GitUser{}, Assignees: []GitUser{}},
+	}
+	if !reflect.DeepEqual(issues, want) {
+		t.Errorf("GetIssuesUpdatedAfter = %+v, want %+v", issues, want)
+	}
+}
+
+func TestLocal_Delete(t *testing.T) {
+	prov, teardown := setupLocal(t)
+	defer teardown()
+
+	repo := &GitRepository{Name: "r"}
+	if _, err := prov.CreateRepository(context.Background(), repo); err != nil {
+		t.Fatalf("CreateRepository returned error: %v", err)
+	}
+	if _, err := prov.CreateLabel(context.Background(), &GitLabel{Repo: "r", Name: "bug"}); err != nil {
+		t.Fatalf("CreateLabel returned error: %v", err)
+	}
+	if _, err := prov.CreateIssue(context.Background(), &GitIssue{Repo: "r", Title: "t"}); err != nil {
+		t.Fatalf("CreateIssue returned error: %v", err)
+	}
+
+	if err := prov.DeleteLabel(context.Background(), &GitLabel{Repo: "r", Name: "bug"}); err != nil {
+		t.Errorf("DeleteLabel returned error: %v", err)
+	}
+	if err := prov.DeleteLabel(context.Background(), &GitLabel{Repo: "r", Name: "bug"}); err == nil {
+		t.Errorf("DeleteLabel expected error for a missing label")
+	}
+	if err := prov.DeleteIssue(context.Background(), &GitIssue{Repo: "r", Number: 1}); err != nil {
+		t.Errorf("DeleteIssue returned error: %v", err)
+	}
+	if labels, _ := prov.GetLabels(context.Background(), 0, "r"); len(labels) != 0 {
+		t.Errorf("GetLabels = %+v, want none", labels)
+	}
+	if issues, _ := prov.GetIssues(context.Background(), 0, "r"); len(issues) != 0 {
+		t.Errorf("GetIssues = %+v, want none", issues)
+	}
+
+	if err := prov.ArchiveRepository(context.Background(), repo); err != nil {
+		t.Errorf("ArchiveRepository returned error: %v", err)
+	}
+	if repos, _ := prov.GetRepositories(context.Background()); len(repos) != 1 || !repos[0].Archived {
+		t.Errorf("GetRepositories = %+v, want one archived repository", repos)
+	}
+
+	if err := prov.DeleteRepository(context.Background(), repo); err != nil {
+		t.Errorf("DeleteRepository returned error: %v", err)
+	}
+	if repos, _ := prov.GetRepositories(context.Background()); len(repos) != 0 {
+		t.Errorf("GetRepositories = %+v, want none", repos)
+	}
+	if _, err := os.Stat(prov.sidecarPath("r")); !os.IsNotExist(err) {
+		t.Errorf("DeleteRepository left the sidecar file behind")
+	}
+}
+
+func TestLocal_PullRequests(t *testing.T) {
+	prov, teardown := setupLocal(t)
+	defer teardown()
+
+	if _, err := prov.CreateRepository(context.Background(), &GitRepository{Name: "r"}); err != nil {
+		t.Fatalf("CreateRepository returned error: %v", err)
+	}
+
+	pr, err := prov.CreatePullRequest(context.Background(), &GitPullRequest{
+		Repo:         "r",
+		Number:       7,
+		Title:        "t",
+		State:        "merged",
+		SourceBranch: "feature",
+		TargetBranch: "master",
+		Changes:      []GitFileChange{{OldPath: "a.go", NewPath: "a.go", Additions: 1}},
+	})
+	if err != nil {
+		t.Fatalf("CreatePullRequest returned error: %v", err)
+	}
+	if pr.Number != 1 {
+		t.Errorf("CreatePullRequest number = %d, want 1", pr.Number)
+	}
+
+	created := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	comment := &GitReviewComment{Repo: "r", PullNum: 1, Body: "c", Path: "a.go", Line: 3, CreatedAt: created, UpdatedAt: created}
+	if err := prov.CreateReviewComment(context.Background(), pr, comment); err != nil {
+		t.Errorf("CreateReviewComment returned error: %v", err)
+	}
+
+	prs, err := prov.GetPullRequests(context.Background(), 1, "r")
+	if err != nil {
+		t.Errorf("GetPullRequests returned error: %v", err)
+	}
+	want := []*GitPullRequest{
+		{
+			Repo:         "r",
+			PID:          1,
+			Number:       1,
+			Title:        "t",
+			State:        "merged",
+			SourceBranch: "feature",
+			TargetBranch: "master",
+			Labels:       []GitLabel{},
+			User:         &GitUser{},
+			Assignees:    []GitUser{},
+			Changes:      []GitFileChange{{OldPath: "a.go", NewPath: "a.go", Additions: 1}},
+		},
+	}
+	if !reflect.DeepEqual(prs, want) {
+		t.Errorf("GetPullRequests = %+v, want %+v", prs, want)
+	}
+
+	comments, err := prov.GetReviewComments(context.Background(), 1, 1, "r")
+	if err != nil {
+		t.Errorf("GetReviewComments returned error: %v", err)
+	}
+	comment.ID = 1
+	if want := []*GitReviewComment{comment}; !reflect.DeepEqual(comments, want) {
+		t.Errorf("GetReviewComments = %+v, want %+v", comments, want)
+	}
+}
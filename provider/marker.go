@@ -0,0 +1,108 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 Artur Sak
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// markerPattern matches the hidden source marker appended to migrated issues and comments
+var markerPattern = regexp.MustCompile(`\n*<!-- gitmv:([a-z0-9-]*):(-?\d+):(\d+)(?::note:(\d+))? -->`)
+
+// Marker identifies the source issue or comment a destination entity was migrated from
+type Marker struct {
+	Provider string
+	PID      int
+	Number   int
+	// NoteID is the source comment's ID, it is zero for issues
+	NoteID int
+}
+
+// IssueMarker identifies a source issue of the given provider kind
+func IssueMarker(kind string, issue *GitIssue) Marker {
+	return Marker{
+		Provider: strings.ToLower(kind),
+		PID:      issue.PID,
+		Number:   issue.Number,
+	}
+}
+
+// CommentMarker identifies a source issue comment of the given provider kind
+func CommentMarker(kind string, issue *GitIssue, comment *GitIssueComment) Marker {
+	marker := IssueMarker(kind, issue)
+	marker.NoteID = comment.ID
+	return marker
+}
+
+// MarkIssue returns a copy of a source issue whose body carries its marker
+func MarkIssue(kind string, issue *GitIssue) *GitIssue {
+	marked := *issue
+	marked.Body = IssueMarker(kind, issue).Stamp(issue.Body)
+	return &marked
+}
+
+// MarkComment returns a copy of a source comment whose body carries its marker
+func MarkComment(kind string, issue *GitIssue, comment *GitIssueComment) *GitIssueComment {
+	marked := *comment
+	marked.Body = CommentMarker(kind, issue, comment).Stamp(comment.Body)
+	return &marked
+}
+
+// String formats the marker as the HTML comment stored in a body
+func (m Marker) String() string {
+	if m.NoteID != 0 {
+		return fmt.Sprintf("<!-- gitmv:%s:%d:%d:note:%d -->", m.Provider, m.PID, m.Number, m.NoteID)
+	}
+	return fmt.Sprintf("<!-- gitmv:%s:%d:%d -->", m.Provider, m.PID, m.Number)
+}
+
+// Stamp appends the marker to a body, replacing the marker of any earlier migration
+func (m Marker) Stamp(body string) string {
+	body = strings.TrimRight(markerPattern.ReplaceAllString(body, ""), "\n")
+	if body == "" {
+		return m.String()
+	}
+	return body + "\n\n" + m.String()
+}
+
+// ParseMarker returns the last marker found in a body
+func ParseMarker(body string) (Marker, bool) {
+	matches := markerPattern.FindAllStringSubmatch(body, -1)
+	if len(matches) == 0 {
+		return Marker{}, false
+	}
+	match := matches[len(matches)-1]
+
+	pid, _ := strconv.Atoi(match[2])
+	number, _ := strconv.Atoi(match[3])
+	noteID, _ := strconv.Atoi(match[4])
+	return Marker{
+		Provider: match[1],
+		PID:      pid,
+		Number:   number,
+		NoteID:   noteID,
+	}, true
+}
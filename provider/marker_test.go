@@ -0,0 +1,106 @@
+package provider
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMarker(t *testing.T) {
+	issue := &GitIssue{Repo: "r", PID: 12, Number: 4, Body: "body"}
+	comment := &GitIssueComment{ID: 55, Body: "comment"}
+
+	tests := []struct {
+		name   string
+		marker Marker
+		body   string
+		want   string
+	}{
+		{
+			name:   "test issue marker",
+			marker: IssueMarker("GitLab", issue),
+			body:   "body\n",
+			want:   "body\n\n<!-- gitmv:gitlab:12:4 -->",
+		},
+		{
+			name:   "test comment marker",
+			marker: CommentMarker("gitlab", issue, comment),
+			body:   "comment",
+			want:   "comment\n\n<!-- gitmv:gitlab:12:4:note:55 -->",
+		},
+		{
+			name:   "test empty body",
+			marker: IssueMarker("gitlab", issue),
+			body:   "",
+			want:   "<!-- gitmv:gitlab:12:4 -->",
+		},
+		{
+			name:   "test replaces earlier marker",
+			marker: IssueMarker("github", &GitIssue{PID: 7, Number: 1}),
+			body:   "body\n\n<!-- gitmv:gitlab:12:4 -->",
+			want:   "body\n\n<!-- gitmv:github:7:1 -->",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.marker.Stamp(tc.body)
+			if got != tc.want {
+				t.Errorf("Stamp = %q, want %q", got, tc.want)
+			}
+			parsed, ok := ParseMarker(got)
+			if !ok || parsed != tc.marker {
+				t.Errorf("ParseMarker = %+v, %v, want %+v", parsed, ok, tc.marker)
+			}
+		})
+	}
+}
+
+func TestParseMarker_Missing(t *testing.T) {
+	if _, ok := ParseMarker("<!-- a regular comment -->"); ok {
+		t.Errorf("ParseMarker found a marker in a body without one")
+	}
+}
+
+func TestLoadCache_Markers(t *testing.T) {
+	prov, teardown := setupLocal(t)
+	defer teardown()
+
+	if _, err := prov.CreateRepository(context.Background(), &GitRepository{Name: "r"}); err != nil {
+		t.Fatalf("CreateRepository returned error: %v", err)
+	}
+
+	// issues sharing a title must not collide
+	first := &GitIssue{Repo: "r", PID: 12, Number: 4, Title: "same"}
+	second := &GitIssue{Repo: "r", PID: 12, Number: 9, Title: "same"}
+	comment := &GitIssueComment{ID: 55, Repo: "r", Body: "c"}
+	for _, issue := range []*GitIssue{first, second} {
+		created, err := prov.CreateIssue(context.Background(), MarkIssue("gitlab", issue))
+		if err != nil {
+			t.Fatalf("CreateIssue returned error: %v", err)
+		}
+		if err := prov.CreateIssueComment(context.Background(), created.Number, MarkComment("gitlab", issue, comment)); err != nil {
+			t.Fatalf("CreateIssueComment returned error: %v", err)
+		}
+	}
+	if _, err := prov.CreateIssue(context.Background(), &GitIssue{Repo: "r", Title: "same"}); err != nil {
+		t.Fatalf("CreateIssue returned error: %v", err)
+	}
+
+	cache, err := LoadCache(context.Background(), prov, 1)
+	if err != nil {
+		t.Fatalf("LoadCache returned error: %v", err)
+	}
+	issues := cache["r"].Issues
+	if len(issues) != 2 {
+		t.Fatalf("LoadCache cached %d issues, want 2", len(issues))
+	}
+	for _, issue := range []*GitIssue{first, second} {
+		cached, ok := issues[IssueMarker("gitlab", issue)]
+		if !ok {
+			t.Fatalf("LoadCache did not cache issue #%d", issue.Number)
+		}
+		if _, ok := cached.Comments[CommentMarker("gitlab", issue, comment)]; !ok {
+			t.Errorf("LoadCache did not cache the comment of issue #%d", issue.Number)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 Artur Sak
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package provider
+
+import "fmt"
+
+// PlaceholderLabel marks the closed issues created to fill gaps in a source's issue numbers
+const PlaceholderLabel = "migration-placeholder"
+
+// NewPlaceholderLabel returns the label of a repository's placeholder issues
+func NewPlaceholderLabel(repo string) *GitLabel {
+	return &GitLabel{
+		Repo:        repo,
+		Name:        PlaceholderLabel,
+		Color:       "ededed",
+		Description: "Keeps issue numbers in sync with the source repository",
+	}
+}
+
+// NewPlaceholderIssue returns the closed issue that takes a number missing from the source
+func NewPlaceholderIssue(repo *GitRepository, number int) *GitIssue {
+	return &GitIssue{
+		Repo:   repo.Name,
+		PID:    repo.PID,
+		Number: number,
+		Title:  fmt.Sprintf("Placeholder for #%d", number),
+		Body:   "This issue does not exist in the source repository. It was created to keep issue numbers in sync.",
+		State:  "closed",
+		Labels: []GitLabel{{Name: PlaceholderLabel}},
+	}
+}
+
+// PlaceholderNumbers lists the numbers below the highest issue number that no issue has, in ascending order
+func PlaceholderNumbers(issues []*GitIssue) []int {
+	taken := make(map[int]bool, len(issues))
+	highest := 0
+	for _, issue := range issues {
+		taken[issue.Number] = true
+		if issue.Number > highest {
+			highest = issue.Number
+		}
+	}
+
+	var numbers []int
+	for number := 1; number < highest; number++ {
+		if !taken[number] {
+			numbers = append(numbers, number)
+		}
+	}
+	return numbers
+}
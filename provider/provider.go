@@ -22,7 +22,12 @@
 
 package provider
 
-import "time"
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+)
 
 type (
 	// GitRepository stores general git repository data
@@ -36,6 +41,15 @@ type (
 		Fork        bool
 		Empty       bool
 		PID         int
+
+		// Namespace is the group path of the repository, e.g. a GitLab group and its subgroups
+		Namespace    string
+		Topics       []string
+		Visibility   string
+		LastActivity time.Time
+
+		// Path is the repository's path in its namespace when a naming strategy derived Name from it
+		Path string
 	}
 	// GitIssue stores general git SaaS issue data
 	GitIssue struct {
@@ -64,8 +78,17 @@ type (
 		Email string
 	}
 
+	// GitMember stores a user's permission on a repository, granted directly or through a group
+	GitMember struct {
+		User       GitUser
+		Permission Permission
+		// Group is the full path of the group granting the permission, empty for direct members
+		Group string
+	}
+
 	// GitIssueComment stores general SaaS git issue comment data
 	GitIssueComment struct {
+		ID        int
 		Repo      string
 		IssueNum  int
 		User      GitUser
@@ -73,8 +96,102 @@ type (
 		CreatedAt time.Time
 		UpdatedAt time.Time
 	}
+
+	// GitPullRequest stores general git SaaS pull and merge request data
+	GitPullRequest struct {
+		Repo         string
+		PID          int
+		Number       int
+		Title        string
+		Body         string
+		State        string
+		SourceBranch string
+		TargetBranch string
+		HeadSHA      string
+		Labels       []GitLabel
+		User         *GitUser
+		Assignees    []GitUser
+		Changes      []GitFileChange
+		// IsIssue is set when a destination created an issue in place of the pull request
+		IsIssue bool
+	}
+
+	// GitFileChange stores a summary of the changes to one file in a pull request
+	GitFileChange struct {
+		OldPath     string
+		NewPath     string
+		Additions   int
+		Deletions   int
+		NewFile     bool
+		RenamedFile bool
+		DeletedFile bool
+	}
+
+	// GitRef stores a branch or tag and the commit it points at, annotated tags are peeled to their commit
+	GitRef struct {
+		Name string
+		Tag  bool
+		SHA  string
+	}
+
+	// GitReviewComment stores general git SaaS pull request comment data
+	// Path and Line are only set for comments positioned on the diff.
+	GitReviewComment struct {
+		ID        int
+		Repo      string
+		PullNum   int
+		User      GitUser
+		Body      string
+		Path      string
+		Line      int
+		OldLine   int
+		CommitID  string
+		CreatedAt time.Time
+		UpdatedAt time.Time
+	}
+)
+
+// Permission is the access a member has to a repository
+type Permission string
+
+// Permissions from the weakest to the strongest, a permission includes the weaker ones
+const (
+	PermissionRead     Permission = "read"
+	PermissionTriage   Permission = "triage"
+	PermissionWrite    Permission = "write"
+	PermissionMaintain Permission = "maintain"
+	PermissionAdmin    Permission = "admin"
 )
 
+var permissionRanks = map[Permission]int{
+	PermissionRead:     1,
+	PermissionTriage:   2,
+	PermissionWrite:    3,
+	PermissionMaintain: 4,
+	PermissionAdmin:    5,
+}
+
+// Includes reports whether p grants at least the access of other
+func (p Permission) Includes(other Permission) bool {
+	return permissionRanks[p] >= permissionRanks[other]
+}
+
+// FullPath returns the namespace and path of a repository in its source, falling back to its owner and name
+func (r *GitRepository) FullPath() string {
+	namespace := r.Namespace
+	if namespace == "" {
+		namespace = r.Owner
+	}
+	name := r.Path
+	if name == "" {
+		name = r.Name
+	}
+	if namespace == "" {
+		return name
+	}
+	return namespace + "/" + name
+}
+
 // ToGitLabels converts a list of strings into a list of GitLabels
 func ToGitLabels(names []string) []GitLabel {
 	answer := []GitLabel{}
@@ -104,3 +221,93 @@ func UsersToString(users []GitUser) *[]string {
 	}
 	return &result
 }
+
+// hashPID derives a stable numeric project ID for providers that identify repositories by UUID
+func hashPID(uuid string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(uuid))
+	return int(h.Sum32())
+}
+
+// PullRequestAsIssue converts a pull request into an issue for destinations where it cannot be recreated
+// The issue body records the branches and a summary of the diff.
+func PullRequestAsIssue(pr *GitPullRequest) *GitIssue {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Merge request #%d from `%s` into `%s`", pr.Number, pr.SourceBranch, pr.TargetBranch)
+	if pr.State == "merged" {
+		body.WriteString(" (merged)")
+	}
+	body.WriteString("\n\n")
+	if pr.Body != "" {
+		body.WriteString(strings.TrimSpace(pr.Body))
+		body.WriteString("\n\n")
+	}
+	if len(pr.Changes) > 0 {
+		body.WriteString("| File | Additions | Deletions |\n| --- | --- | --- |\n")
+		for _, change := range pr.Changes {
+			fmt.Fprintf(&body, "| %s | +%d | -%d |\n", change.describe(), change.Additions, change.Deletions)
+		}
+	}
+
+	state := "open"
+	if pr.State != "open" {
+		state = "closed"
+	}
+	return &GitIssue{
+		Repo:      pr.Repo,
+		PID:       pr.PID,
+		Title:     pr.Title,
+		Body:      strings.TrimSpace(body.String()),
+		State:     state,
+		Labels:    pr.Labels,
+		User:      pr.User,
+		Assignees: pr.Assignees,
+	}
+}
+
+// ReviewCommentAsIssueComment converts a pull request comment into an issue comment, quoting its position in the diff
+func ReviewCommentAsIssueComment(issueNum int, comment *GitReviewComment) *GitIssueComment {
+	body := comment.Body
+	if comment.Path != "" {
+		line := comment.Line
+		if line == 0 {
+			line = comment.OldLine
+		}
+		body = fmt.Sprintf("> `%s` line %d\n\n%s", comment.Path, line, comment.Body)
+	}
+	return &GitIssueComment{
+		Repo:      comment.Repo,
+		IssueNum:  issueNum,
+		User:      comment.User,
+		Body:      body,
+		CreatedAt: comment.CreatedAt,
+		UpdatedAt: comment.UpdatedAt,
+	}
+}
+
+func (c GitFileChange) describe() string {
+	switch {
+	case c.NewFile:
+		return fmt.Sprintf("`%s` (added)", c.NewPath)
+	case c.DeletedFile:
+		return fmt.Sprintf("`%s` (deleted)", c.OldPath)
+	case c.RenamedFile:
+		return fmt.Sprintf("`%s` → `%s`", c.OldPath, c.NewPath)
+	default:
+		return fmt.Sprintf("`%s`", c.NewPath)
+	}
+}
+
+// countDiffLines counts the added and removed lines of diff hunks without file headers
+func countDiffLines(diff string) (int, int) {
+	additions, deletions := 0, 0
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+"):
+			additions++
+		case strings.HasPrefix(line, "-"):
+			deletions++
+		}
+	}
+	return additions, deletions
+}
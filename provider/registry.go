@@ -0,0 +1,87 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 Artur Sak
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/artur-sak13/gitmv/auth"
+)
+
+// Factory creates a new GitProvider from a user's authentication data
+type Factory func(context.Context, *auth.ID) (GitProvider, error)
+
+var registry = map[string]Factory{
+	"github": NewGithubProvider,
+	"gitlab": func(ctx context.Context, id *auth.ID) (GitProvider, error) {
+		return NewGitlabProvider(id)
+	},
+	"gitea": func(ctx context.Context, id *auth.ID) (GitProvider, error) {
+		return NewGiteaProvider(id)
+	},
+	"forgejo": func(ctx context.Context, id *auth.ID) (GitProvider, error) {
+		return NewGiteaProvider(id)
+	},
+	"azure-devops": func(ctx context.Context, id *auth.ID) (GitProvider, error) {
+		return NewAzureProvider(id)
+	},
+	"bitbucket": func(ctx context.Context, id *auth.ID) (GitProvider, error) {
+		return NewBitbucketProvider(id)
+	},
+	"bitbucket-server": func(ctx context.Context, id *auth.ID) (GitProvider, error) {
+		return NewBitbucketServerProvider(id)
+	},
+	"local": func(ctx context.Context, id *auth.ID) (GitProvider, error) {
+		return NewLocalProvider(id)
+	},
+	"fake": func(ctx context.Context, id *auth.ID) (GitProvider, error) {
+		return NewFakeProvider(), nil
+	},
+}
+
+// Register makes a GitProvider factory available under the given kind
+func Register(kind string, factory Factory) {
+	registry[strings.ToLower(kind)] = factory
+}
+
+// New creates a GitProvider of the given kind, e.g. "github" or "gitlab"
+func New(ctx context.Context, kind string, id *auth.ID) (GitProvider, error) {
+	factory, ok := registry[strings.ToLower(kind)]
+	if !ok {
+		return nil, fmt.Errorf("unknown git provider %q, must be one of: %s", kind, strings.Join(Kinds(), ", "))
+	}
+	return factory(ctx, id)
+}
+
+// Kinds returns the sorted list of registered GitProvider kinds
+func Kinds() []string {
+	var kinds []string
+	for kind := range registry {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
@@ -0,0 +1,101 @@
+package provider
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/artur-sak13/gitmv/auth"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		kind     string
+		wantType reflect.Type
+		wantErr  bool
+	}{
+		{
+			name:     "test github provider",
+			kind:     "github",
+			wantType: reflect.TypeOf(&GithubProvider{}),
+		},
+		{
+			name:     "test gitlab provider",
+			kind:     "GitLab",
+			wantType: reflect.TypeOf(&GitlabProvider{}),
+		},
+		{
+			name:     "test gitea provider",
+			kind:     "gitea",
+			wantType: reflect.TypeOf(&GiteaProvider{}),
+		},
+		{
+			name:     "test forgejo provider",
+			kind:     "forgejo",
+			wantType: reflect.TypeOf(&GiteaProvider{}),
+		},
+		{
+			name:     "test azure devops provider",
+			kind:     "azure-devops",
+			wantType: reflect.TypeOf(&AzureProvider{}),
+		},
+		{
+			name:     "test bitbucket provider",
+			kind:     "bitbucket",
+			wantType: reflect.TypeOf(&BitbucketProvider{}),
+		},
+		{
+			name:     "test bitbucket server provider",
+			kind:     "bitbucket-server",
+			wantType: reflect.TypeOf(&BitbucketServerProvider{}),
+		},
+		{
+			name:     "test fake provider",
+			kind:     "fake",
+			wantType: reflect.TypeOf(&FakeProvider{}),
+		},
+		{
+			name:    "test unknown provider",
+			kind:    "svn",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			id := auth.NewAuthID("https://git.example.com", "test-token", "testorg")
+			got, err := New(context.Background(), tt.kind, id)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if reflect.TypeOf(got) != tt.wantType {
+				t.Errorf("New() = %T, want %v", got, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register("Custom", func(ctx context.Context, id *auth.ID) (GitProvider, error) {
+		return NewFakeProvider(), nil
+	})
+	defer delete(registry, "custom")
+
+	if _, err := New(context.Background(), "custom", auth.NewAuthID("", "", "")); err != nil {
+		t.Errorf("New() returned error for registered provider: %v", err)
+	}
+
+	kinds := Kinds()
+	if !sort.StringsAreSorted(kinds) {
+		t.Errorf("Kinds() = %v, want sorted kinds", kinds)
+	}
+	if i := sort.SearchStrings(kinds, "custom"); i == len(kinds) || kinds[i] != "custom" {
+		t.Errorf("Kinds() = %v, want it to contain custom", kinds)
+	}
+}
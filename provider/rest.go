@@ -0,0 +1,138 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 Artur Sak
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// restClient is a minimal JSON API client for providers without a vendored client library
+type restClient struct {
+	client  *http.Client
+	baseURL *url.URL
+	header  http.Header
+}
+
+// RESTError reports a non 2xx response from a provider's REST API
+type RESTError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Message    string
+}
+
+func (e *RESTError) Error() string {
+	return fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.StatusCode, e.Message)
+}
+
+func newRESTClient(client *http.Client, baseURL string, header http.Header) (*restClient, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/") + "/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse base url %s: %v", baseURL, err)
+	}
+
+	return &restClient{
+		client:  client,
+		baseURL: u,
+		header:  header,
+	}, nil
+}
+
+// do sends a request with a JSON encoded body and decodes the JSON response into v.
+// Paths are resolved against the base URL unless they are absolute URLs.
+func (c *restClient) do(ctx context.Context, method, path string, query url.Values, body, v interface{}) (*http.Response, error) {
+	u, err := c.baseURL.Parse(strings.TrimPrefix(path, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if query != nil {
+		q := u.Query()
+		for key, values := range query {
+			q[key] = values
+		}
+		u.RawQuery = q.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequest(method, u.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for key, values := range c.header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return resp, &RESTError{
+			Method:     method,
+			URL:        u.String(),
+			StatusCode: resp.StatusCode,
+			Message:    strings.TrimSpace(string(msg)),
+		}
+	}
+
+	if v != nil && resp.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil && err != io.EOF {
+			return resp, fmt.Errorf("failed to decode response from %s: %v", u.String(), err)
+		}
+	}
+	return resp, nil
+}
+
+// isNotFound checks if an error is a 404 response from a REST API
+func isNotFound(err error) bool {
+	restErr, ok := err.(*RESTError)
+	return ok && restErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,258 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 Artur Sak
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package provider
+
+import (
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RetryPolicy bounds how often and how long a failed API request is retried
+type RetryPolicy struct {
+	// Retries is the number of times a request is retried before its last response is returned
+	Retries int
+	// MinBackoff and MaxBackoff bound the exponential backoff between attempts
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// MaxWait is the longest a request waits for a rate limit to reset
+	MaxWait time.Duration
+}
+
+// DefaultRetryPolicy is the RetryPolicy used unless Retry is changed
+var DefaultRetryPolicy = RetryPolicy{
+	Retries:    5,
+	MinBackoff: time.Second,
+	MaxBackoff: 30 * time.Second,
+	MaxWait:    time.Hour,
+}
+
+// Retry is the RetryPolicy of the HTTP clients built by the New* provider constructors
+var Retry = DefaultRetryPolicy
+
+// RetryTransport is an http.RoundTripper that retries rate limited and failed requests.
+// Rate limited requests are retried after the delay given by the Retry-After, X-RateLimit-Reset
+// or RateLimit-Reset headers, other failures with exponential backoff and jitter. Server errors
+// are only retried for idempotent methods, since a failed POST may still have been applied.
+type RetryTransport struct {
+	Base   http.RoundTripper
+	Policy RetryPolicy
+}
+
+// newHTTPClient returns an HTTP client that retries requests according to Retry
+func newHTTPClient() *http.Client {
+	return &http.Client{Transport: &RetryTransport{Policy: Retry}}
+}
+
+// RoundTrip sends a request, retrying it while the policy allows
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := base.RoundTrip(req)
+
+		wait, retry := t.Policy.delay(req, resp, err, attempt)
+		if !retry || attempt >= t.Policy.Retries || !rewindable(req) {
+			if err := t.waitForReset(req, resp, err); err != nil {
+				return nil, err
+			}
+			return resp, nil
+		}
+
+		if err != nil {
+			logrus.Debugf("%s %s failed: %v, retrying in %s", req.Method, req.URL, err, wait)
+		} else {
+			logrus.Debugf("%s %s returned %s, retrying in %s", req.Method, req.URL, resp.Status, wait)
+			drain(resp)
+		}
+
+		if err := sleep(req, wait); err != nil {
+			return nil, err
+		}
+		if req, err = rewind(req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// waitForReset holds back a successful response that used up the rate limit until the limit resets,
+// so that clients tracking the limit themselves do not refuse the next request
+func (t *RetryTransport) waitForReset(req *http.Request, resp *http.Response, err error) error {
+	if err != nil || resp.StatusCode >= 300 || rateLimitHeader(resp, "Remaining") != "0" {
+		return err
+	}
+
+	wait, ok := resetDelay(resp)
+	if !ok || wait > t.Policy.MaxWait {
+		return nil
+	}
+	logrus.Infof("rate limit of %s exhausted, waiting %s for it to reset", req.URL.Host, wait)
+	if err := sleep(req, wait); err != nil {
+		drain(resp)
+		return err
+	}
+	return nil
+}
+
+// delay returns how long to wait before retrying a request, and whether it should be retried at all
+func (p RetryPolicy) delay(req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	if err != nil {
+		return p.backoff(attempt), req.Context().Err() == nil && idempotent(req.Method)
+	}
+
+	switch {
+	case isRateLimited(resp):
+		wait, ok := retryAfter(resp)
+		if !ok {
+			wait, ok = resetDelay(resp)
+		}
+		if !ok {
+			wait = p.backoff(attempt)
+		}
+		return wait, wait <= p.MaxWait
+	case resp.StatusCode == http.StatusBadGateway,
+		resp.StatusCode == http.StatusServiceUnavailable,
+		resp.StatusCode == http.StatusGatewayTimeout:
+		return p.backoff(attempt), idempotent(req.Method)
+	}
+	return 0, false
+}
+
+// backoff returns an exponential delay for the given attempt with up to half of it randomized
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.MaxBackoff
+	if attempt < 32 && p.MinBackoff<<uint(attempt) < p.MaxBackoff {
+		d = p.MinBackoff << uint(attempt)
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// isRateLimited checks if a response rejected a request for exceeding a rate limit.
+// GitHub answers 403 to both exhausted primary and secondary (abuse) rate limits.
+func isRateLimited(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusForbidden:
+		return resp.Header.Get("Retry-After") != "" || rateLimitHeader(resp, "Remaining") == "0"
+	}
+	return false
+}
+
+// retryAfter parses the Retry-After header, given either in seconds or as an HTTP date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return positive(time.Until(at)), true
+	}
+	return 0, false
+}
+
+// resetDelay returns the time left until the rate limit resets, from the epoch seconds
+// in GitHub's X-RateLimit-Reset or GitLab's RateLimit-Reset headers
+func resetDelay(resp *http.Response) (time.Duration, bool) {
+	epoch, err := strconv.ParseInt(rateLimitHeader(resp, "Reset"), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	// the reset is given in whole seconds, wait for the next one to be sure it has passed
+	return positive(time.Until(time.Unix(epoch, 0))) + time.Second, true
+}
+
+// rateLimitHeader returns the X-RateLimit-<name> header, or GitLab's RateLimit-<name>
+func rateLimitHeader(resp *http.Response, name string) string {
+	if value := resp.Header.Get("X-RateLimit-" + name); value != "" {
+		return value
+	}
+	return resp.Header.Get("RateLimit-" + name)
+}
+
+func positive(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// idempotent checks if a request with the given method can be safely sent twice
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// rewindable checks if a request's body can be sent again
+func rewindable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// rewind returns a copy of a request with a fresh body
+func rewind(req *http.Request) (*http.Request, error) {
+	if req.GetBody == nil {
+		return req, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	copied := req.WithContext(req.Context())
+	copied.Body = body
+	return copied, nil
+}
+
+// sleep waits for d or until the request is cancelled
+func sleep(req *http.Request, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+// drain discards the rest of a response so its connection can be reused
+func drain(resp *http.Response) {
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+}
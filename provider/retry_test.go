@@ -0,0 +1,183 @@
+package provider
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// statusServer answers with the given responses in order, repeating the last one
+func statusServer(t *testing.T, responses ...func(http.ResponseWriter)) (*httptest.Server, *int32) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&calls, 1)) - 1
+		if n >= len(responses) {
+			n = len(responses) - 1
+		}
+		if body, _ := ioutil.ReadAll(r.Body); r.Method == http.MethodPost && string(body) != "payload" {
+			t.Errorf("attempt %d sent body %q, want %q", n+1, body, "payload")
+		}
+		responses[n](w)
+	}))
+	return srv, &calls
+}
+
+func respond(code int, header ...string) func(http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		for i := 0; i+1 < len(header); i += 2 {
+			w.Header().Set(header[i], header[i+1])
+		}
+		w.WriteHeader(code)
+	}
+}
+
+func TestRetryTransport(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		responses []func(http.ResponseWriter)
+		want      int
+		calls     int32
+	}{
+		{
+			name:      "server errors are retried",
+			method:    http.MethodGet,
+			responses: []func(http.ResponseWriter){respond(502), respond(503), respond(200)},
+			want:      200,
+			calls:     3,
+		},
+		{
+			name:      "server errors are not retried for POST",
+			method:    http.MethodPost,
+			responses: []func(http.ResponseWriter){respond(502), respond(201)},
+			want:      502,
+			calls:     1,
+		},
+		{
+			name:      "retry budget",
+			method:    http.MethodGet,
+			responses: []func(http.ResponseWriter){respond(503)},
+			want:      503,
+			calls:     3,
+		},
+		{
+			name:      "Retry-After",
+			method:    http.MethodPost,
+			responses: []func(http.ResponseWriter){respond(429, "Retry-After", "0"), respond(201)},
+			want:      201,
+			calls:     2,
+		},
+		{
+			name:   "GitHub abuse rate limit",
+			method: http.MethodPost,
+			responses: []func(http.ResponseWriter){
+				respond(403, "Retry-After", "0"),
+				respond(201),
+			},
+			want:  201,
+			calls: 2,
+		},
+		{
+			name:   "rate limit reset beyond the maximum wait",
+			method: http.MethodGet,
+			responses: []func(http.ResponseWriter){
+				respond(403, "X-RateLimit-Remaining", "0", "X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(2*time.Hour).Unix(), 10)),
+				respond(200),
+			},
+			want:  403,
+			calls: 1,
+		},
+		{
+			name:      "client errors are not retried",
+			method:    http.MethodGet,
+			responses: []func(http.ResponseWriter){respond(404), respond(200)},
+			want:      404,
+			calls:     1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, calls := statusServer(t, tt.responses...)
+			defer srv.Close()
+
+			client := &http.Client{Transport: &RetryTransport{Policy: RetryPolicy{
+				Retries:    2,
+				MinBackoff: time.Millisecond,
+				MaxBackoff: 5 * time.Millisecond,
+				MaxWait:    time.Hour,
+			}}}
+			req, err := http.NewRequest(tt.method, srv.URL, strings.NewReader("payload"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Do returned error: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+			if *calls != tt.calls {
+				t.Errorf("server was called %d times, want %d", *calls, tt.calls)
+			}
+		})
+	}
+}
+
+func TestRetryTransport_CancelledWait(t *testing.T) {
+	reset := strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10)
+	srv, _ := statusServer(t, respond(200, "X-RateLimit-Remaining", "0", "X-RateLimit-Reset", reset))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transport := &RetryTransport{Policy: RetryPolicy{MaxWait: time.Hour}}
+	resp, err := transport.RoundTrip(req.WithContext(ctx))
+	if err != context.DeadlineExceeded {
+		t.Errorf("RoundTrip returned error %v, want %v", err, context.DeadlineExceeded)
+	}
+	if resp != nil {
+		t.Errorf("RoundTrip returned a response along with its error")
+	}
+}
+
+func TestResetDelay(t *testing.T) {
+	reset := time.Now().Add(time.Minute)
+	for _, name := range []string{"X-RateLimit-Reset", "RateLimit-Reset"} {
+		resp := &http.Response{Header: http.Header{}}
+		resp.Header.Set(name, strconv.FormatInt(reset.Unix(), 10))
+
+		got, ok := resetDelay(resp)
+		if !ok || got < 59*time.Second || got > 61*time.Second {
+			t.Errorf("resetDelay(%s) = %s, %t, want about a minute", name, got, ok)
+		}
+	}
+
+	if _, ok := resetDelay(&http.Response{Header: http.Header{}}); ok {
+		t.Errorf("resetDelay without headers returned ok")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := RetryPolicy{MinBackoff: time.Second, MaxBackoff: 8 * time.Second}
+	for attempt, max := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 8 * time.Second} {
+		got := p.backoff(attempt)
+		if got < max/2 || got > max {
+			t.Errorf("backoff(%d) = %s, want between %s and %s", attempt, got, max/2, max)
+		}
+	}
+}
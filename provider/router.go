@@ -0,0 +1,417 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 Artur Sak
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package provider
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/artur-sak13/gitmv/auth"
+)
+
+// Route sends the source repositories matching Pattern to the destination owner Owner
+// Pattern is a path.Match pattern matched against the full path of a repository and each of its namespaces,
+// so platform/* matches platform/api and every repository of the platform/data subgroup.
+type Route struct {
+	Pattern string
+	Owner   string
+}
+
+// Match reports whether the route applies to a repository's full path
+func (r Route) Match(fullPath string) bool {
+	fullPath = strings.ToLower(fullPath)
+	pattern := strings.ToLower(strings.Trim(r.Pattern, "/"))
+	for p := fullPath; p != "." && p != "/" && p != ""; p = path.Dir(p) {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadRoutes reads a rules file listing a namespace pattern and a destination owner on each line
+// Blank lines and lines starting with # are ignored, the first matching rule routes a repository.
+func LoadRoutes(file string) ([]Route, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open rules %s due to: %v", file, err)
+	}
+	defer f.Close()
+
+	var routes []Route
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: want a namespace pattern and an owner, got %q", file, n, line)
+		}
+		if _, err := path.Match(fields[0], ""); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid pattern %q due to: %v", file, n, fields[0], err)
+		}
+		routes = append(routes, Route{Pattern: fields[0], Owner: fields[1]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rules %s due to: %v", file, err)
+	}
+	return routes, nil
+}
+
+// Router is a destination GitProvider sending the calls about each repository to the provider of its owner
+// Repositories are matched to Routes by their full path in Src, those matching no route go to the Default owner.
+// Src is listed again when a repository name is unknown, e.g. after it was created in the source.
+type Router struct {
+	Src    GitProvider
+	Routes []Route
+
+	// Dests holds a provider for every owner, including Default
+	Dests   map[string]GitProvider
+	Default string
+
+	mu     sync.Mutex
+	owners map[string]string
+	listed bool
+}
+
+// NewRouter creates a Router over one destination provider per owner
+func NewRouter(src GitProvider, routes []Route, dests map[string]GitProvider, defaultOwner string) *Router {
+	return &Router{
+		Src:     src,
+		Routes:  routes,
+		Dests:   dests,
+		Default: defaultOwner,
+		owners:  make(map[string]string),
+	}
+}
+
+// Owner returns the destination owner of a source repository
+func (r *Router) Owner(repo *GitRepository) string {
+	for _, route := range r.Routes {
+		if route.Match(repo.FullPath()) {
+			return route.Owner
+		}
+	}
+	return r.Default
+}
+
+// For returns the destination provider of a repository name
+func (r *Router) For(ctx context.Context, repo string) (GitProvider, error) {
+	owner, err := r.lookup(ctx, repo, true)
+	if err != nil {
+		return nil, err
+	}
+	return r.Dests[owner], nil
+}
+
+// lookup resolves the owner of a repository name, listing Src if it is unknown and refresh is set
+// Names still unknown after listing belong to the Default owner.
+func (r *Router) lookup(ctx context.Context, repo string, refresh bool) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if owner, ok := r.owners[repo]; ok {
+		return owner, nil
+	}
+	if !refresh && r.listed {
+		return r.Default, nil
+	}
+
+	repos, err := r.Src.GetRepositories(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list source repositories to route %s due to: %v", repo, err)
+	}
+	r.listed = true
+	for _, srcRepo := range repos {
+		r.owners[srcRepo.Name] = r.Owner(srcRepo)
+	}
+	owner, ok := r.owners[repo]
+	if !ok {
+		owner = r.Default
+		if refresh {
+			r.owners[repo] = owner
+		}
+	}
+	return owner, nil
+}
+
+// sortedOwners returns the destination owners in a stable order
+func (r *Router) sortedOwners() []string {
+	owners := make([]string, 0, len(r.Dests))
+	for owner := range r.Dests {
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+	return owners
+}
+
+// CreateRepository creates a repository in the destination of its owner
+func (r *Router) CreateRepository(ctx context.Context, repo *GitRepository) (*GitRepository, error) {
+	dest, err := r.For(ctx, repo.Name)
+	if err != nil {
+		return nil, err
+	}
+	return dest.CreateRepository(ctx, repo)
+}
+
+// CreateIssue creates an issue in the destination of its repository
+func (r *Router) CreateIssue(ctx context.Context, issue *GitIssue) (*GitIssue, error) {
+	dest, err := r.For(ctx, issue.Repo)
+	if err != nil {
+		return nil, err
+	}
+	return dest.CreateIssue(ctx, issue)
+}
+
+// CreateIssueComment creates a comment in the destination of its repository
+func (r *Router) CreateIssueComment(ctx context.Context, number int, comment *GitIssueComment) error {
+	dest, err := r.For(ctx, comment.Repo)
+	if err != nil {
+		return err
+	}
+	return dest.CreateIssueComment(ctx, number, comment)
+}
+
+// CreateLabel creates a label in the destination of its repository
+func (r *Router) CreateLabel(ctx context.Context, label *GitLabel) (*GitLabel, error) {
+	dest, err := r.For(ctx, label.Repo)
+	if err != nil {
+		return nil, err
+	}
+	return dest.CreateLabel(ctx, label)
+}
+
+// MigrateRepo imports a repository into the destination of its owner
+func (r *Router) MigrateRepo(ctx context.Context, repo *GitRepository, token string) (string, error) {
+	dest, err := r.For(ctx, repo.Name)
+	if err != nil {
+		return "", err
+	}
+	return dest.MigrateRepo(ctx, repo, token)
+}
+
+// CreatePullRequest creates a pull request in the destination of its repository
+func (r *Router) CreatePullRequest(ctx context.Context, pr *GitPullRequest) (*GitPullRequest, error) {
+	dest, err := r.For(ctx, pr.Repo)
+	if err != nil {
+		return nil, err
+	}
+	return dest.CreatePullRequest(ctx, pr)
+}
+
+// CreateReviewComment creates a review comment in the destination of its pull request's repository
+func (r *Router) CreateReviewComment(ctx context.Context, pr *GitPullRequest, comment *GitReviewComment) error {
+	dest, err := r.For(ctx, pr.Repo)
+	if err != nil {
+		return err
+	}
+	return dest.CreateReviewComment(ctx, pr, comment)
+}
+
+// GrantAccess grants members access to a repository in the destination of its owner
+func (r *Router) GrantAccess(ctx context.Context, repo *GitRepository, members []*GitMember) error {
+	dest, err := r.For(ctx, repo.Name)
+	if err != nil {
+		return err
+	}
+	return dest.GrantAccess(ctx, repo, members)
+}
+
+// UpdateIssue updates an issue in the destination of its repository
+func (r *Router) UpdateIssue(ctx context.Context, issue *GitIssue) error {
+	dest, err := r.For(ctx, issue.Repo)
+	if err != nil {
+		return err
+	}
+	return dest.UpdateIssue(ctx, issue)
+}
+
+// UpdateLabel updates a label in the destination of its repository
+func (r *Router) UpdateLabel(ctx context.Context, label *GitLabel) error {
+	dest, err := r.For(ctx, label.Repo)
+	if err != nil {
+		return err
+	}
+	return dest.UpdateLabel(ctx, label)
+}
+
+// DeleteRepository deletes a repository from the destination of its owner
+func (r *Router) DeleteRepository(ctx context.Context, repo *GitRepository) error {
+	dest, err := r.For(ctx, repo.Name)
+	if err != nil {
+		return err
+	}
+	return dest.DeleteRepository(ctx, repo)
+}
+
+// ArchiveRepository archives a repository in the destination of its owner
+func (r *Router) ArchiveRepository(ctx context.Context, repo *GitRepository) error {
+	dest, err := r.For(ctx, repo.Name)
+	if err != nil {
+		return err
+	}
+	return dest.ArchiveRepository(ctx, repo)
+}
+
+// DeleteLabel deletes a label from the destination of its repository
+func (r *Router) DeleteLabel(ctx context.Context, label *GitLabel) error {
+	dest, err := r.For(ctx, label.Repo)
+	if err != nil {
+		return err
+	}
+	return dest.DeleteLabel(ctx, label)
+}
+
+// DeleteIssue deletes an issue from the destination of its repository
+func (r *Router) DeleteIssue(ctx context.Context, issue *GitIssue) error {
+	dest, err := r.For(ctx, issue.Repo)
+	if err != nil {
+		return err
+	}
+	return dest.DeleteIssue(ctx, issue)
+}
+
+// GetRepositories lists the repositories of every owner that are routed to it
+// Repositories of the Default owner are listed even if the source has none of that name.
+func (r *Router) GetRepositories(ctx context.Context) ([]*GitRepository, error) {
+	var repos []*GitRepository
+	for _, owner := range r.sortedOwners() {
+		ownerRepos, err := r.Dests[owner].GetRepositories(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list repositories of %s due to: %v", owner, err)
+		}
+		for _, repo := range ownerRepos {
+			routed, err := r.lookup(ctx, repo.Name, false)
+			if err != nil {
+				return nil, err
+			}
+			if routed == owner {
+				repos = append(repos, repo)
+			}
+		}
+	}
+	return repos, nil
+}
+
+// GetIssues lists the issues of a repository in the destination of its owner
+func (r *Router) GetIssues(ctx context.Context, pid int, repo string) ([]*GitIssue, error) {
+	dest, err := r.For(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	return dest.GetIssues(ctx, pid, repo)
+}
+
+// GetIssuesUpdatedAfter lists the issues of a repository updated after since in the destination of its owner
+func (r *Router) GetIssuesUpdatedAfter(ctx context.Context, pid int, repo string, since time.Time) ([]*GitIssue, error) {
+	dest, err := r.For(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	return dest.GetIssuesUpdatedAfter(ctx, pid, repo, since)
+}
+
+// GetComments lists the comments of an issue in the destination of its repository
+func (r *Router) GetComments(ctx context.Context, pid, number int, repo string) ([]*GitIssueComment, error) {
+	dest, err := r.For(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	return dest.GetComments(ctx, pid, number, repo)
+}
+
+// GetLabels lists the labels of a repository in the destination of its owner
+func (r *Router) GetLabels(ctx context.Context, pid int, repo string) ([]*GitLabel, error) {
+	dest, err := r.For(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	return dest.GetLabels(ctx, pid, repo)
+}
+
+// GetPullRequests lists the pull requests of a repository in the destination of its owner
+func (r *Router) GetPullRequests(ctx context.Context, pid int, repo string) ([]*GitPullRequest, error) {
+	dest, err := r.For(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	return dest.GetPullRequests(ctx, pid, repo)
+}
+
+// GetReviewComments lists the review comments of a pull request in the destination of its repository
+func (r *Router) GetReviewComments(ctx context.Context, pid, number int, repo string) ([]*GitReviewComment, error) {
+	dest, err := r.For(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	return dest.GetReviewComments(ctx, pid, number, repo)
+}
+
+// GetRefs lists the branches and tags of a repository in the destination of its owner
+func (r *Router) GetRefs(ctx context.Context, pid int, repo string) ([]*GitRef, error) {
+	dest, err := r.For(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	return dest.GetRefs(ctx, pid, repo)
+}
+
+// GetMembers lists the members of a repository in the destination of its owner
+func (r *Router) GetMembers(ctx context.Context, repo *GitRepository) ([]*GitMember, error) {
+	dest, err := r.For(ctx, repo.Name)
+	if err != nil {
+		return nil, err
+	}
+	return dest.GetMembers(ctx, repo)
+}
+
+// GetAuth returns the authentication data of the Default owner's provider
+func (r *Router) GetAuth() *auth.ID {
+	return r.Dests[r.Default].GetAuth()
+}
+
+// GetImportProgress checks the import of a repository in the destination of its owner
+func (r *Router) GetImportProgress(ctx context.Context, repo string) (string, error) {
+	dest, err := r.For(ctx, repo)
+	if err != nil {
+		return "", err
+	}
+	return dest.GetImportProgress(ctx, repo)
+}
+
+// routed resolves the provider a Router sends a repository to, other providers are returned as is
+func routed(ctx context.Context, p GitProvider, repo string) (GitProvider, error) {
+	if r, ok := p.(*Router); ok {
+		return r.For(ctx, repo)
+	}
+	return p, nil
+}
@@ -0,0 +1,136 @@
+package provider
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// listProvider lists a fixed set of repositories and counts the listings
+type listProvider struct {
+	GitProvider
+	repos    []*GitRepository
+	listings int
+}
+
+func (l *listProvider) GetRepositories(ctx context.Context) ([]*GitRepository, error) {
+	l.listings++
+	return l.repos, nil
+}
+
+func TestRoute_Match(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"platform/*", "platform/api", true},
+		{"platform/*", "platform/data/etl", true},
+		{"platform/*", "platform", false},
+		{"platform", "Platform/api", true},
+		{"data/*", "platform/data/etl", false},
+		{"*/etl", "platform/data/etl", false},
+		{"*/*/etl", "platform/data/etl", true},
+	}
+	for _, tt := range tests {
+		if got := (Route{Pattern: tt.pattern}).Match(tt.path); got != tt.want {
+			t.Errorf("Route %q Match(%q) = %t, want %t", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestLoadRoutes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gitmv-rules")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "rules")
+	if err := ioutil.WriteFile(file, []byte("# orgs\nplatform/*  platform-org\n\ndata data-org\n"), 0644); err != nil {
+		t.Fatalf("failed to write rules: %v", err)
+	}
+	got, err := LoadRoutes(file)
+	if err != nil {
+		t.Fatalf("LoadRoutes returned error: %v", err)
+	}
+	want := []Route{{Pattern: "platform/*", Owner: "platform-org"}, {Pattern: "data", Owner: "data-org"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadRoutes = %+v, want %+v", got, want)
+	}
+
+	if err := ioutil.WriteFile(file, []byte("platform/*\n"), 0644); err != nil {
+		t.Fatalf("failed to write rules: %v", err)
+	}
+	if _, err := LoadRoutes(file); err == nil {
+		t.Errorf("LoadRoutes accepted a rule without an owner")
+	}
+}
+
+func TestRouter(t *testing.T) {
+	ctx := context.Background()
+	src := &listProvider{repos: []*GitRepository{
+		{Name: "api", Namespace: "platform/backend"},
+		{Name: "etl", Namespace: "data"},
+		{Name: "misc", Namespace: "tools"},
+	}}
+	platform, data, other := NewFakeProvider(), NewFakeProvider(), NewFakeProvider()
+	router := NewRouter(src, []Route{{"platform/*", "platform-org"}, {"data", "data-org"}},
+		map[string]GitProvider{"platform-org": platform, "data-org": data, "other": other}, "other")
+
+	for _, repo := range src.repos {
+		if _, err := router.CreateRepository(ctx, repo); err != nil {
+			t.Fatalf("CreateRepository returned error: %v", err)
+		}
+	}
+	if _, err := router.CreateIssue(ctx, &GitIssue{Repo: "etl", Title: "t"}); err != nil {
+		t.Fatalf("CreateIssue returned error: %v", err)
+	}
+	if _, err := router.CreateRepository(ctx, &GitRepository{Name: "new"}); err != nil {
+		t.Fatalf("CreateRepository returned error: %v", err)
+	}
+
+	for owner, want := range map[GitProvider][]string{platform: {"api"}, data: {"etl"}, other: {"misc", "new"}} {
+		var got []string
+		owner.(*FakeProvider).Repositories.Range(func(name, _ interface{}) bool {
+			got = append(got, name.(string))
+			return true
+		})
+		if len(got) != len(want) {
+			t.Errorf("destination holds %v, want %v", got, want)
+		}
+	}
+	if _, ok := data.(*FakeProvider).Repositories.Load("etl"); !ok {
+		t.Errorf("etl was not routed to data-org")
+	}
+	if repo, _ := data.(*FakeProvider).Repositories.Load("etl"); repo.(*FakeRepository).issueCount != 1 {
+		t.Errorf("issue was not routed to data-org")
+	}
+	// the unknown name lists the source again once, later calls remember it
+	if src.listings != 2 {
+		t.Errorf("source was listed %d times, want 2", src.listings)
+	}
+	if _, err := router.For(ctx, "new"); err != nil || src.listings != 2 {
+		t.Errorf("For listed the source again for a known name")
+	}
+}
+
+func TestRouter_GetRepositories(t *testing.T) {
+	src := &listProvider{repos: []*GitRepository{{Name: "api", Namespace: "platform"}}}
+	platform := &listProvider{repos: []*GitRepository{{Name: "api", Owner: "platform-org"}, {Name: "stale", Owner: "platform-org"}}}
+	other := &listProvider{repos: []*GitRepository{{Name: "api", Owner: "other"}, {Name: "legacy", Owner: "other"}}}
+	router := NewRouter(src, []Route{{"platform", "platform-org"}},
+		map[string]GitProvider{"platform-org": platform, "other": other}, "other")
+
+	got, err := router.GetRepositories(context.Background())
+	if err != nil {
+		t.Fatalf("GetRepositories returned error: %v", err)
+	}
+	want := []*GitRepository{other.repos[1], platform.repos[0]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetRepositories = %+v, want %+v", got, want)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/artur-sak13/gitmv/provider"
+	"github.com/artur-sak13/gitmv/state"
 )
 
 const reposHelp = `Migrate all repos from one Git provider to another.`
@@ -24,79 +25,148 @@ func (cmd *reposCommand) Run(ctx context.Context, args []string) error {
 	return runCommand(ctx, cmd.handleRepos)
 }
 
-// handleRepo will return
+// handleRepos will create any repositories, labels, issues and comments missing from the destination
+// Entities recorded in the state journal are skipped, the destination is only scanned for those that are not.
 func (cmd *reposCommand) handleRepos(ctx context.Context, src, dest provider.GitProvider) error {
-	repos, err := src.GetRepositories()
+	repos, err := src.GetRepositories(ctx)
 	if err != nil {
 		return err
 	}
-	github := dest.(*provider.GithubProvider)
+	repos = repoFilter.Select(repos)
 
-	err = github.LoadCache()
-	if err != nil {
-		return err
+	var cache provider.RepoCache
+	cachedRepo := func(destRepo *provider.GitRepository) (*provider.CachedRepo, error) {
+		if cache == nil {
+			if cache, err = provider.LoadCache(ctx, dest, repoWorkers); err != nil {
+				return nil, err
+			}
+		}
+		cachedrepo, ok := cache[destRepo.Name]
+		if !ok {
+			cachedrepo = provider.NewCachedRepo(destRepo)
+			cache[destRepo.Name] = cachedrepo
+		}
+		return cachedrepo, nil
 	}
+
 	count := 0
 	for _, repo := range repos {
-		if repo.Fork || repo.Empty {
-			continue
-		}
-		cachedrepo, ok := github.Repocache[repo.Name]
-		if !ok {
-			count++
-			fmt.Printf("Missing repo: %s\n", repo.Name)
-			_, err := dest.CreateRepository(repo)
+		var destRepo *provider.GitRepository
+		if !journal.Lookup(repo.Name, state.KindRepo, repo.Name, &destRepo) {
+			cachedrepo, err := cachedRepo(repo)
 			if err != nil {
-				return fmt.Errorf("error creating repository: %v\n%+v", err, repo)
+				return err
 			}
-			_, err = dest.MigrateRepo(repo, src.GetAuth().Token)
-			if err != nil {
-				return fmt.Errorf("error migrating repository: %v", err)
+			destRepo = cachedrepo.Repo
+			// repositories missing from the destination are cached with their source
+			if destRepo == repo {
+				count++
+				fmt.Printf("Missing repo: %s\n", repo.Name)
+				newRepo, err := dest.CreateRepository(ctx, repo)
+				if err != nil {
+					return fmt.Errorf("error creating repository: %v\n%+v", err, repo)
+				}
+				_, err = provider.MigrateRepo(ctx, src, dest, repo, newRepo)
+				if err != nil {
+					return fmt.Errorf("error migrating repository: %v", err)
+				}
+				cachedrepo.Repo = newRepo
+				if err := journal.Record(repo.Name, state.KindRepo, repo.Name, newRepo); err != nil {
+					return err
+				}
+				destRepo = newRepo
+			} else if err := journal.Adopt(repo.Name, state.KindRepo, repo.Name, destRepo); err != nil {
+				return err
 			}
 		}
 
-		labels, err := src.GetLabels(repo.PID, repo.Name)
+		labels, err := src.GetLabels(ctx, repo.PID, repo.Name)
 		if err != nil {
 			return err
 		}
 
 		for _, label := range labels {
+			if journal.Lookup(repo.Name, state.KindLabel, label.Name, nil) {
+				continue
+			}
+			cachedrepo, err := cachedRepo(destRepo)
+			if err != nil {
+				return err
+			}
+			record := journal.Adopt
 			_, ok := cachedrepo.Labels[label.Name]
 			if !ok {
 				fmt.Printf("Missing label: %s\n", label.Name)
-				_, err := dest.CreateLabel(label)
+				_, err := dest.CreateLabel(ctx, label)
 				if err != nil {
 					return fmt.Errorf("error creating label: %v\n%+v", err, label)
 				}
+				record = journal.Record
+			}
+			if err := record(repo.Name, state.KindLabel, label.Name, label.Name); err != nil {
+				return err
 			}
 		}
 
-		issues, err := src.GetIssues(repo.PID, repo.Name)
+		issues, err := src.GetIssues(ctx, repo.PID, repo.Name)
 		if err != nil {
 			return err
 		}
 		for _, issue := range issues {
-			cachedissue, ok := cachedrepo.Issues[issue.Title]
-			if !ok {
-				fmt.Printf("Missing issue: %s\n", issue.Title)
-				newIssue, err := dest.CreateIssue(issue)
+			var number int
+			var cachedissue *provider.CachedIssue
+			if !journal.Lookup(repo.Name, state.KindIssue, state.NumberID(issue.Number), &number) {
+				cachedrepo, err := cachedRepo(destRepo)
 				if err != nil {
-					return fmt.Errorf("error creating issue: %v\n%+v", err, issue)
+					return err
+				}
+				var ok bool
+				record := journal.Adopt
+				cachedissue, ok = cachedrepo.Issues[provider.IssueMarker(from.kind, issue)]
+				if !ok {
+					fmt.Printf("Missing issue: %s\n", issue.Title)
+					newIssue, err := dest.CreateIssue(ctx, provider.MarkIssue(from.kind, issue))
+					if err != nil {
+						return fmt.Errorf("error creating issue: %v\n%+v", err, issue)
+					}
+					cachedissue = provider.NewCachedIssue(newIssue)
+					record = journal.Record
+				}
+				number = cachedissue.Issue.Number
+				if err := record(repo.Name, state.KindIssue, state.NumberID(issue.Number), number); err != nil {
+					return err
 				}
-				cachedissue = github.NewCachedIssue(newIssue)
 			}
-			comments, err := src.GetComments(repo.PID, issue.Number, repo.Name)
+			comments, err := src.GetComments(ctx, repo.PID, issue.Number, repo.Name)
 			if err != nil {
 				return err
 			}
 			for _, comment := range comments {
-				_, ok := cachedissue.Comments[comment.CreatedAt]
+				id := state.CommentID(issue.Number, comment.ID)
+				if journal.Lookup(repo.Name, state.KindComment, id, nil) {
+					continue
+				}
+				if cachedissue == nil {
+					cachedrepo, err := cachedRepo(destRepo)
+					if err != nil {
+						return err
+					}
+					if cachedissue = cachedrepo.Issues[provider.IssueMarker(from.kind, issue)]; cachedissue == nil {
+						cachedissue = provider.NewCachedIssue(&provider.GitIssue{Number: number})
+					}
+				}
+				record := journal.Adopt
+				_, ok := cachedissue.Comments[provider.CommentMarker(from.kind, issue, comment)]
 				if !ok {
 					fmt.Printf("Missing comment: %s\n", comment.Body)
-					err := dest.CreateIssueComment(cachedissue.Issue.Number, comment)
+					err := dest.CreateIssueComment(ctx, number, provider.MarkComment(from.kind, issue, comment))
 					if err != nil {
 						return fmt.Errorf("error creating comment: %v\n%+v", err, comment)
 					}
+					record = journal.Record
+				}
+				if err := record(repo.Name, state.KindComment, id, number); err != nil {
+					return err
 				}
 			}
 		}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/artur-sak13/gitmv/provider"
+	"github.com/artur-sak13/gitmv/rollback"
+	"github.com/artur-sak13/gitmv/state"
+)
+
+const rollbackHelp = `Remove the repositories, labels and issues a migration created, as recorded in its state file.`
+
+func (cmd *rollbackCommand) Name() string      { return "rollback" }
+func (cmd *rollbackCommand) Args() string      { return "[OPTIONS]" }
+func (cmd *rollbackCommand) ShortHelp() string { return rollbackHelp }
+func (cmd *rollbackCommand) LongHelp() string  { return rollbackHelp }
+func (cmd *rollbackCommand) Hidden() bool      { return false }
+
+func (cmd *rollbackCommand) Register(fs *flag.FlagSet) {
+	fs.BoolVar(&cmd.archive, "archive", false, "archive created repositories instead of deleting them")
+	fs.BoolVar(&cmd.yes, "yes", false, "do not ask for confirmation")
+	fs.BoolVar(&cmd.yes, "y", false, "do not ask for confirmation")
+}
+
+type rollbackCommand struct {
+	archive bool
+	yes     bool
+}
+
+func (cmd *rollbackCommand) Run(ctx context.Context, args []string) error {
+	if stateFile == "" {
+		return fmt.Errorf("rollback needs the --state file of the migration")
+	}
+	if _, err := os.Stat(stateFile); err != nil {
+		return fmt.Errorf("failed to read state file %s due to: %v", stateFile, err)
+	}
+	return runCommand(ctx, cmd.handleRollback)
+}
+
+// handleRollback previews the rollback and, unless it is a dry run, removes the entities once confirmed
+func (cmd *rollbackCommand) handleRollback(ctx context.Context, src, dest provider.GitProvider) error {
+	// dry runs leave the journal closed, it is only read here
+	j := journal
+	if j == nil {
+		var err error
+		if j, err = state.Open(stateFile); err != nil {
+			return err
+		}
+		defer j.Close()
+	}
+
+	r := rollback.New(dest, j)
+	r.Archive = cmd.archive
+
+	preview, err := r.Preview()
+	if err != nil {
+		return err
+	}
+	if len(preview.Steps) == 0 {
+		fmt.Printf("Nothing to roll back in %s\n", stateFile)
+		return nil
+	}
+	if err := preview.Print(os.Stdout); err != nil {
+		return err
+	}
+	if dryrun {
+		return nil
+	}
+
+	if !cmd.yes && !confirm(fmt.Sprintf("\nRemove %s from %s?", describe(preview), to.planEndpoint())) {
+		return fmt.Errorf("rollback cancelled")
+	}
+	return r.Run(ctx, preview)
+}
+
+// describe summarizes the entities of a rollback, e.g. "2 repositories and 3 issues"
+func describe(preview *rollback.Preview) string {
+	counts := preview.Counts()
+	var parts []string
+	for _, kind := range []struct {
+		kind         state.Kind
+		one, several string
+	}{
+		{state.KindRepo, "repository", "repositories"},
+		{state.KindLabel, "label", "labels"},
+		{state.KindIssue, "issue", "issues"},
+	} {
+		switch n := counts[kind.kind]; n {
+		case 0:
+		case 1:
+			parts = append(parts, "1 "+kind.one)
+		default:
+			parts = append(parts, fmt.Sprintf("%d %s", n, kind.several))
+		}
+	}
+	if len(parts) > 1 {
+		return strings.Join(parts[:len(parts)-1], ", ") + " and " + parts[len(parts)-1]
+	}
+	return strings.Join(parts, "")
+}
+
+// confirm asks a yes/no question on the terminal, anything but yes declines
+func confirm(question string) bool {
+	fmt.Printf("%s Type yes to continue: ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	return strings.EqualFold(strings.TrimSpace(answer), "yes")
+}
@@ -0,0 +1,256 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 Artur Sak
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package rollback
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/artur-sak13/gitmv/provider"
+	"github.com/artur-sak13/gitmv/state"
+)
+
+// Action is what a rollback does to a destination entity
+type Action string
+
+// Rollback actions
+const (
+	Delete  Action = "delete"
+	Archive Action = "archive"
+)
+
+// Step removes one destination entity a migration created
+type Step struct {
+	Repo   string
+	Kind   state.Kind
+	ID     string
+	Action Action
+	// Target names the destination entity
+	Target string
+
+	repo  *provider.GitRepository
+	label *provider.GitLabel
+	issue *provider.GitIssue
+
+	// covered are the journal entries removed along with the entity, e.g. the issues of a deleted repository
+	covered []*state.Entry
+}
+
+// Preview lists what a rollback removes
+type Preview struct {
+	Steps []*Step
+	// Kept are created entities that cannot be removed on their own, such as comments on issues that existed before the run
+	Kept []*state.Entry
+}
+
+// Rollback removes the entities a migration created from Dest, leaving those it found there alone
+type Rollback struct {
+	Dest  provider.GitProvider
+	State *state.Journal
+
+	// Archive makes created repositories read-only instead of deleting them
+	Archive bool
+}
+
+// New creates a rollback of the entities journaled in j
+func New(dest provider.GitProvider, j *state.Journal) *Rollback {
+	return &Rollback{
+		Dest:  dest,
+		State: j,
+	}
+}
+
+// Preview reads the journal into the steps of the rollback without changing anything
+// Entities inside a created repository go with it, as do the comments of a deleted issue.
+func (r *Rollback) Preview() (*Preview, error) {
+	entries := r.State.Entries()
+
+	destRepos := make(map[string]*provider.GitRepository)
+	created := make(map[string]*Step)
+	preview := &Preview{}
+	for _, entry := range entries {
+		if entry.Kind != state.KindRepo {
+			continue
+		}
+		var destRepo *provider.GitRepository
+		if err := json.Unmarshal(entry.Dest, &destRepo); err != nil || destRepo == nil {
+			return nil, fmt.Errorf("failed to read repository %s from the state file due to: %v", entry.Repo, err)
+		}
+		destRepos[entry.Repo] = destRepo
+		if entry.Existing {
+			continue
+		}
+
+		step := &Step{Repo: entry.Repo, Kind: entry.Kind, ID: entry.ID, Action: Delete, Target: destRepo.Name, repo: destRepo}
+		if r.Archive {
+			step.Action = Archive
+		}
+		created[entry.Repo] = step
+		preview.Steps = append(preview.Steps, step)
+	}
+
+	issues := make(map[string]map[int]*Step)
+	for _, entry := range entries {
+		if entry.Existing {
+			continue
+		}
+		if repoStep, ok := created[entry.Repo]; ok {
+			if entry.Kind != state.KindRepo {
+				repoStep.covered = append(repoStep.covered, entry)
+			}
+			continue
+		}
+
+		destRepo := entry.Repo
+		if repo, ok := destRepos[entry.Repo]; ok {
+			destRepo = repo.Name
+		}
+
+		switch entry.Kind {
+		case state.KindLabel:
+			var name string
+			if err := json.Unmarshal(entry.Dest, &name); err != nil {
+				return nil, fmt.Errorf("failed to read label %s of %s from the state file due to: %v", entry.ID, entry.Repo, err)
+			}
+			preview.Steps = append(preview.Steps, &Step{
+				Repo:   entry.Repo,
+				Kind:   entry.Kind,
+				ID:     entry.ID,
+				Action: Delete,
+				Target: destRepo + " label " + name,
+				label:  &provider.GitLabel{Repo: destRepo, Name: name},
+			})
+		case state.KindIssue:
+			var number int
+			if err := json.Unmarshal(entry.Dest, &number); err != nil {
+				return nil, fmt.Errorf("failed to read issue %s of %s from the state file due to: %v", entry.ID, entry.Repo, err)
+			}
+			step := &Step{
+				Repo:   entry.Repo,
+				Kind:   entry.Kind,
+				ID:     entry.ID,
+				Action: Delete,
+				Target: fmt.Sprintf("%s#%d", destRepo, number),
+				issue:  &provider.GitIssue{Repo: destRepo, Number: number},
+			}
+			if issues[entry.Repo] == nil {
+				issues[entry.Repo] = make(map[int]*Step)
+			}
+			issues[entry.Repo][number] = step
+			preview.Steps = append(preview.Steps, step)
+		}
+	}
+
+	for _, entry := range entries {
+		if entry.Existing || created[entry.Repo] != nil || entry.Kind == state.KindRepo || entry.Kind == state.KindLabel || entry.Kind == state.KindIssue {
+			continue
+		}
+		var number int
+		if entry.Kind == state.KindComment && json.Unmarshal(entry.Dest, &number) == nil && issues[entry.Repo][number] != nil {
+			step := issues[entry.Repo][number]
+			step.covered = append(step.covered, entry)
+			continue
+		}
+		preview.Kept = append(preview.Kept, entry)
+	}
+	return preview, nil
+}
+
+// Run executes the steps of a preview, forgetting every removed entity in the journal so that a later migration creates it again
+// A failed step is logged and the rollback continues, an error is returned if any step failed.
+func (r *Rollback) Run(ctx context.Context, preview *Preview) error {
+	failed := 0
+	for _, step := range preview.Steps {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := r.apply(ctx, step); err != nil {
+			logrus.Errorf("failed to %s %s %s: %v", step.Action, step.Kind, step.Target, err)
+			failed++
+			continue
+		}
+		logrus.WithFields(logrus.Fields{
+			"action": step.Action,
+			"kind":   step.Kind,
+			"target": step.Target,
+		}).Info("rolled back")
+
+		for _, entry := range append(step.covered, &state.Entry{Repo: step.Repo, Kind: step.Kind, ID: step.ID}) {
+			if err := r.State.Forget(entry.Repo, entry.Kind, entry.ID); err != nil {
+				logrus.Warnf("error recording rollback state: %v", err)
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to roll back %d of %d entities", failed, len(preview.Steps))
+	}
+	return nil
+}
+
+func (r *Rollback) apply(ctx context.Context, step *Step) error {
+	switch {
+	case step.repo != nil && step.Action == Archive:
+		return r.Dest.ArchiveRepository(ctx, step.repo)
+	case step.repo != nil:
+		return r.Dest.DeleteRepository(ctx, step.repo)
+	case step.label != nil:
+		return r.Dest.DeleteLabel(ctx, step.label)
+	case step.issue != nil:
+		return r.Dest.DeleteIssue(ctx, step.issue)
+	}
+	return fmt.Errorf("nothing to roll back for %s %s", step.Kind, step.ID)
+}
+
+// Counts returns the number of steps per entity kind
+func (p *Preview) Counts() map[state.Kind]int {
+	counts := make(map[state.Kind]int)
+	for _, step := range p.Steps {
+		counts[step.Kind]++
+	}
+	return counts
+}
+
+// Print writes a table of the steps followed by the created entities left in place
+func (p *Preview) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "ACTION\tENTITY\tTARGET\tINCLUDES")
+	for _, step := range p.Steps {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\n", step.Action, step.Kind, step.Target, len(step.covered))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(p.Kept) > 0 {
+		fmt.Fprintf(w, "\n%d created entities cannot be removed and are left in place:\n", len(p.Kept))
+		for _, entry := range p.Kept {
+			fmt.Fprintf(w, "  %s %s %s\n", entry.Repo, entry.Kind, entry.ID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package rollback
+
+import (
+	"context"
+	"testing"
+
+	"github.com/artur-sak13/gitmv/provider"
+	"github.com/artur-sak13/gitmv/state"
+)
+
+func TestRollback(t *testing.T) {
+	ctx := context.Background()
+	dest := provider.NewFakeProvider().(*provider.FakeProvider)
+	j := state.New()
+
+	for _, name := range []string{"new", "old"} {
+		if _, err := dest.CreateRepository(ctx, &provider.GitRepository{Name: name}); err != nil {
+			t.Fatalf("CreateRepository returned error: %v", err)
+		}
+	}
+	for _, label := range []string{"bug", "docs"} {
+		if _, err := dest.CreateLabel(ctx, &provider.GitLabel{Repo: "old", Name: label}); err != nil {
+			t.Fatalf("CreateLabel returned error: %v", err)
+		}
+	}
+	for _, number := range []int{2, 5} {
+		if _, err := dest.CreateIssue(ctx, &provider.GitIssue{Repo: "old", Number: number}); err != nil {
+			t.Fatalf("CreateIssue returned error: %v", err)
+		}
+	}
+
+	// new was created by the run, old existed along with its bug label and issue #2
+	j.Record("new", state.KindRepo, "new", &provider.GitRepository{Name: "new"})
+	j.Record("new", state.KindLabel, "bug", "bug")
+	j.Record("new", state.KindIssue, "1", 1)
+	j.Adopt("old", state.KindRepo, "old", &provider.GitRepository{Name: "old"})
+	j.Adopt("old", state.KindLabel, "bug", "bug")
+	j.Record("old", state.KindLabel, "docs", "docs")
+	j.Adopt("old", state.KindIssue, "1", 2)
+	j.Record("old", state.KindComment, state.CommentID(1, 10), 2)
+	j.Record("old", state.KindIssue, "3", 5)
+	j.Record("old", state.KindComment, state.CommentID(3, 11), 5)
+
+	r := New(dest, j)
+	preview, err := r.Preview()
+	if err != nil {
+		t.Fatalf("Preview returned error: %v", err)
+	}
+
+	want := []struct {
+		kind    state.Kind
+		target  string
+		covered int
+	}{
+		{state.KindRepo, "new", 2},
+		{state.KindLabel, "old label docs", 0},
+		{state.KindIssue, "old#5", 1},
+	}
+	if len(preview.Steps) != len(want) {
+		t.Fatalf("Preview returned %d steps, want %d: %+v", len(preview.Steps), len(want), preview.Steps)
+	}
+	for i, w := range want {
+		step := preview.Steps[i]
+		if step.Kind != w.kind || step.Target != w.target || step.Action != Delete || len(step.covered) != w.covered {
+			t.Errorf("step %d = %s %s %s covering %d, want delete %s %s covering %d", i,
+				step.Action, step.Kind, step.Target, len(step.covered), w.kind, w.target, w.covered)
+		}
+	}
+	if len(preview.Kept) != 1 || preview.Kept[0].ID != state.CommentID(1, 10) {
+		t.Errorf("Kept = %+v, want the comment on the existing issue", preview.Kept)
+	}
+
+	if err := r.Run(ctx, preview); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	if _, ok := dest.Repositories.Load("new"); ok {
+		t.Errorf("Run did not delete the created repository")
+	}
+	old, ok := dest.Repositories.Load("old")
+	if !ok {
+		t.Fatalf("Run deleted the existing repository")
+	}
+	repo := old.(*provider.FakeRepository)
+	if len(repo.Labels) != 1 || repo.Labels[0].Name != "bug" {
+		t.Errorf("Labels = %+v, want only the existing bug label", repo.Labels)
+	}
+	if _, ok := repo.Issues.Load(5); ok {
+		t.Errorf("Run did not delete the created issue")
+	}
+	if _, ok := repo.Issues.Load(2); !ok {
+		t.Errorf("Run deleted the existing issue")
+	}
+
+	for _, entry := range j.Entries() {
+		if !entry.Existing && entry.Kind != state.KindComment {
+			t.Errorf("journal still records rolled back %s %s/%s", entry.Kind, entry.Repo, entry.ID)
+		}
+	}
+	if preview, _ := r.Preview(); len(preview.Steps) != 0 {
+		t.Errorf("Preview after Run returned %+v, want no steps", preview.Steps)
+	}
+}
+
+func TestRollback_Archive(t *testing.T) {
+	ctx := context.Background()
+	dest := provider.NewFakeProvider().(*provider.FakeProvider)
+	if _, err := dest.CreateRepository(ctx, &provider.GitRepository{Name: "r"}); err != nil {
+		t.Fatalf("CreateRepository returned error: %v", err)
+	}
+	j := state.New()
+	j.Record("r", state.KindRepo, "r", &provider.GitRepository{Name: "r"})
+
+	r := New(dest, j)
+	r.Archive = true
+	preview, err := r.Preview()
+	if err != nil {
+		t.Fatalf("Preview returned error: %v", err)
+	}
+	if len(preview.Steps) != 1 || preview.Steps[0].Action != Archive {
+		t.Fatalf("Preview = %+v, want one archive step", preview.Steps)
+	}
+	if err := r.Run(ctx, preview); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	repo, ok := dest.Repositories.Load("r")
+	if !ok || !repo.(*provider.FakeRepository).GitRepo.Archived {
+		t.Errorf("Run did not archive the repository")
+	}
+}
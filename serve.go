@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/artur-sak13/gitmv/provider"
+	"github.com/artur-sak13/gitmv/webhook"
+)
+
+const serveHelp = `Receive GitLab and GitHub webhooks and replay the changes they announce onto the destination.`
+
+// maxEventAttempts bounds the replays of an event, a later sync pass still copies the changes of a dropped one
+const maxEventAttempts = 5
+
+// eventRetryDelay is the pause after a failed replay so that a destination outage is not retried in a tight loop
+const eventRetryDelay = 30 * time.Second
+
+func (cmd *serveCommand) Name() string      { return "serve" }
+func (cmd *serveCommand) Args() string      { return "[OPTIONS]" }
+func (cmd *serveCommand) ShortHelp() string { return serveHelp }
+func (cmd *serveCommand) LongHelp() string  { return serveHelp }
+func (cmd *serveCommand) Hidden() bool      { return false }
+
+func (cmd *serveCommand) Register(fs *flag.FlagSet) {
+	fs.StringVar(&cmd.listen, "listen", ":8080", "address to receive webhooks on")
+	fs.StringVar(&cmd.secret, "secret", os.Getenv("GITMV_WEBHOOK_SECRET"), "GitLab secret token or GitHub signing secret of the webhooks (or env var GITMV_WEBHOOK_SECRET)")
+	fs.StringVar(&cmd.queue, "queue", "gitmv-queue.jsonl", "file keeping received events until they are replayed")
+}
+
+type serveCommand struct {
+	listen string
+	secret string
+	queue  string
+}
+
+func (cmd *serveCommand) Run(ctx context.Context, args []string) error {
+	if dryrun {
+		return fmt.Errorf("serve cannot be a dry run, use plan to preview a migration")
+	}
+	if stateFile == "" {
+		return fmt.Errorf("serve needs a --state file to find the destination of source repositories")
+	}
+	if cmd.secret == "" {
+		return fmt.Errorf("serve needs a --secret to verify webhooks")
+	}
+	return runCommand(ctx, cmd.handleServe)
+}
+
+// handleServe queues webhook events and replays them one at a time until ctx is cancelled
+func (cmd *serveCommand) handleServe(ctx context.Context, src, dest provider.GitProvider) error {
+	queue, err := webhook.OpenQueue(cmd.queue)
+	if err != nil {
+		return err
+	}
+	defer queue.Close()
+
+	server := &http.Server{
+		Addr:    cmd.listen,
+		Handler: &webhook.Handler{Secret: cmd.secret, Queue: queue, Naming: repoNaming},
+	}
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+	logrus.Infof("receiving webhooks on %s, %d events queued", cmd.listen, queue.Len())
+
+	go func() {
+		cmd.replayEvents(ctx, queue, src, dest)
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+
+	if err := <-serverErr; err != http.ErrServerClosed {
+		return fmt.Errorf("failed to receive webhooks due to: %v", err)
+	}
+	logrus.Infof("stopped receiving webhooks, %d events queued", queue.Len())
+	return nil
+}
+
+// replayEvents copies the changes of queued events, events interrupted by cancellation stay queued
+func (cmd *serveCommand) replayEvents(ctx context.Context, queue *webhook.Queue, src, dest provider.GitProvider) {
+	for {
+		event, err := queue.Next(ctx)
+		if err != nil {
+			return
+		}
+
+		mig := newMigrator(src, dest)
+		mig.State = journal
+		if event.Kind == webhook.KindPush {
+			err = mig.SyncRefs(ctx, event.Repository())
+		} else {
+			err = mig.SyncIssues(ctx, event.Repository(), event.Changed)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		switch {
+		case err == nil:
+			logrus.Infof("replayed %s event", event)
+			err = queue.Done(event)
+		case event.Attempts+1 >= maxEventAttempts:
+			logrus.Errorf("dropping %s event after %d attempts: %v", event, event.Attempts+1, err)
+			err = queue.Done(event)
+		default:
+			logrus.Warnf("error replaying %s event, retrying it later: %v", event, err)
+			if err = queue.Retry(event); err == nil {
+				if sleepContext(ctx, eventRetryDelay) != nil {
+					return
+				}
+				continue
+			}
+		}
+		if err != nil {
+			logrus.Errorf("error updating webhook queue: %v", err)
+			if sleepContext(ctx, eventRetryDelay) != nil {
+				return
+			}
+		}
+	}
+}
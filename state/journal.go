@@ -0,0 +1,252 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 Artur Sak
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package state
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// Kind identifies the type of a migrated entity
+type Kind string
+
+// Entity kinds recorded in the journal
+const (
+	KindRepo          Kind = "repo"
+	KindImport        Kind = "import"
+	KindWiki          Kind = "wiki"
+	KindLabel         Kind = "label"
+	KindIssue         Kind = "issue"
+	KindComment       Kind = "comment"
+	KindPullRequest   Kind = "pull_request"
+	KindReviewComment Kind = "review_comment"
+	KindMembers       Kind = "members"
+)
+
+// Journal is an append-only JSON lines file mapping source entities to the destination entities created from them
+// A nil Journal records nothing and finds nothing, so callers need not check whether resuming is enabled.
+type Journal struct {
+	mu      sync.Mutex
+	file    *os.File
+	entries map[key]*Entry
+	order   []key
+}
+
+type key struct {
+	Repo string
+	Kind Kind
+	ID   string
+}
+
+// Entry maps a source entity to its destination entity
+type Entry struct {
+	Repo string          `json:"repo"`
+	Kind Kind            `json:"kind"`
+	ID   string          `json:"id"`
+	Dest json.RawMessage `json:"dest"`
+	// Existing marks a destination entity that was found rather than created, rollback leaves it alone
+	Existing bool `json:"existing,omitempty"`
+}
+
+// line is one line of the journal, a removed line forgets an entity that was rolled back
+type line struct {
+	Entry
+	Removed bool `json:"removed,omitempty"`
+}
+
+// New creates a journal that is only kept in memory
+func New() *Journal {
+	return &Journal{
+		entries: make(map[key]*Entry),
+	}
+}
+
+// Open replays the journal at path, creating it if needed, and appends new records to it
+func Open(path string) (*Journal, error) {
+	j := New()
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state file %s due to: %v", path, err)
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var l line
+		// a line cut short by a crash is dropped and its entity migrated again
+		if err := json.Unmarshal(scanner.Bytes(), &l); err != nil {
+			continue
+		}
+		j.apply(l)
+	}
+	if err := scanner.Err(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to read state file %s due to: %v", path, err)
+	}
+
+	// terminate a line cut short by a crash so that new records start on their own line
+	if info, err := f.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			if _, err := f.Write([]byte{'\n'}); err != nil {
+				f.Close()
+				return nil, fmt.Errorf("failed to repair state file %s due to: %v", path, err)
+			}
+		}
+	}
+
+	j.file = f
+	return j, nil
+}
+
+// apply adds or removes the entity of a line, the caller must hold mu or own the journal
+func (j *Journal) apply(l line) {
+	k := key{l.Repo, l.Kind, l.ID}
+	if l.Removed {
+		delete(j.entries, k)
+		return
+	}
+	if _, ok := j.entries[k]; !ok {
+		j.order = append(j.order, k)
+	}
+	entry := l.Entry
+	j.entries[k] = &entry
+}
+
+// Lookup reports whether a source entity was migrated and decodes its destination into v, which may be nil
+func (j *Journal) Lookup(repo string, kind Kind, id string, v interface{}) bool {
+	if j == nil {
+		return false
+	}
+
+	j.mu.Lock()
+	entry, ok := j.entries[key{repo, kind, id}]
+	j.mu.Unlock()
+
+	if !ok {
+		return false
+	}
+	if v != nil {
+		if err := json.Unmarshal(entry.Dest, v); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Record stores the destination created from a source entity and flushes it to disk
+func (j *Journal) Record(repo string, kind Kind, id string, dest interface{}) error {
+	return j.record(repo, kind, id, dest, false)
+}
+
+// Adopt stores a destination entity that already existed for a source entity, so that it is reused but never rolled back
+func (j *Journal) Adopt(repo string, kind Kind, id string, dest interface{}) error {
+	return j.record(repo, kind, id, dest, true)
+}
+
+func (j *Journal) record(repo string, kind Kind, id string, dest interface{}, existing bool) error {
+	if j == nil {
+		return nil
+	}
+
+	raw, err := json.Marshal(dest)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s %s/%s due to: %v", kind, repo, id, err)
+	}
+	return j.write(line{Entry: Entry{Repo: repo, Kind: kind, ID: id, Dest: raw, Existing: existing}})
+}
+
+// Forget removes a source entity whose destination was rolled back, so that it is migrated again
+func (j *Journal) Forget(repo string, kind Kind, id string) error {
+	if j == nil {
+		return nil
+	}
+	return j.write(line{Entry: Entry{Repo: repo, Kind: kind, ID: id}, Removed: true})
+}
+
+func (j *Journal) write(l line) error {
+	data, err := json.Marshal(l)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s %s/%s due to: %v", l.Kind, l.Repo, l.ID, err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file != nil {
+		if _, err := j.file.Write(append(data, '\n')); err != nil {
+			return fmt.Errorf("failed to record %s %s/%s due to: %v", l.Kind, l.Repo, l.ID, err)
+		}
+		if err := j.file.Sync(); err != nil {
+			return fmt.Errorf("failed to record %s %s/%s due to: %v", l.Kind, l.Repo, l.ID, err)
+		}
+	}
+	j.apply(l)
+	return nil
+}
+
+// Entries returns every recorded entity in the order it was first recorded
+func (j *Journal) Entries() []*Entry {
+	if j == nil {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var entries []*Entry
+	seen := make(map[key]bool)
+	for _, k := range j.order {
+		entry, ok := j.entries[k]
+		if !ok || seen[k] {
+			continue
+		}
+		seen[k] = true
+		copied := *entry
+		entries = append(entries, &copied)
+	}
+	return entries
+}
+
+// Close closes the journal's file
+func (j *Journal) Close() error {
+	if j == nil || j.file == nil {
+		return nil
+	}
+	return j.file.Close()
+}
+
+// NumberID identifies issues and pull requests by their source number
+func NumberID(number int) string {
+	return strconv.Itoa(number)
+}
+
+// CommentID identifies a comment by its source issue or pull request and its source ID
+func CommentID(number, commentID int) string {
+	return fmt.Sprintf("%d#%d", number, commentID)
+}
@@ -0,0 +1,143 @@
+package state
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gitmv-state")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.jsonl")
+
+	j, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	if j.Lookup("r", KindIssue, NumberID(1), nil) {
+		t.Errorf("Lookup found an issue in an empty journal")
+	}
+	if err := j.Record("r", KindIssue, NumberID(1), 7); err != nil {
+		t.Fatalf("Record returned error: %v", err)
+	}
+	if err := j.Record("r", KindComment, CommentID(1, 10), true); err != nil {
+		t.Fatalf("Record returned error: %v", err)
+	}
+	if err := j.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	// simulate a crash in the middle of a write
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("failed to open journal: %v", err)
+	}
+	f.WriteString(`{"repo":"r","kind":"issue","id":"2","de`)
+	f.Close()
+
+	j, err = Open(path)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+
+	if err := j.Record("r", KindIssue, NumberID(3), 9); err != nil {
+		t.Fatalf("Record returned error: %v", err)
+	}
+	j.Close()
+
+	j, err = Open(path)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	defer j.Close()
+
+	var number int
+	if !j.Lookup("r", KindIssue, NumberID(3), &number) || number != 9 {
+		t.Errorf("Lookup = %d, want 9 after a truncated record", number)
+	}
+	if !j.Lookup("r", KindIssue, NumberID(1), &number) || number != 7 {
+		t.Errorf("Lookup = %d, want 7", number)
+	}
+	if !j.Lookup("r", KindComment, CommentID(1, 10), nil) {
+		t.Errorf("Lookup did not find the recorded comment")
+	}
+	if j.Lookup("r", KindIssue, NumberID(2), nil) {
+		t.Errorf("Lookup found the truncated record")
+	}
+}
+
+func TestJournal_Nil(t *testing.T) {
+	var j *Journal
+	if err := j.Record("r", KindRepo, "r", "r"); err != nil {
+		t.Errorf("Record returned error: %v", err)
+	}
+	if j.Lookup("r", KindRepo, "r", nil) {
+		t.Errorf("Lookup found a repository in a nil journal")
+	}
+}
+
+func TestJournal_Memory(t *testing.T) {
+	j := New()
+	if err := j.Record("r", KindIssue, NumberID(1), 3); err != nil {
+		t.Errorf("Record returned error: %v", err)
+	}
+	var number int
+	if !j.Lookup("r", KindIssue, NumberID(1), &number) || number != 3 {
+		t.Errorf("Lookup = %d, want 3", number)
+	}
+	if err := j.Close(); err != nil {
+		t.Errorf("Close returned error: %v", err)
+	}
+}
+
+func TestJournal_Entries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gitmv-state")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.jsonl")
+
+	j, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	if err := j.Adopt("r", KindRepo, "r", "r"); err != nil {
+		t.Fatalf("Adopt returned error: %v", err)
+	}
+	if err := j.Record("r", KindLabel, "bug", "bug"); err != nil {
+		t.Fatalf("Record returned error: %v", err)
+	}
+	if err := j.Record("r", KindIssue, NumberID(1), 4); err != nil {
+		t.Fatalf("Record returned error: %v", err)
+	}
+	if err := j.Forget("r", KindLabel, "bug"); err != nil {
+		t.Fatalf("Forget returned error: %v", err)
+	}
+	j.Close()
+
+	j, err = Open(path)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	defer j.Close()
+
+	if j.Lookup("r", KindLabel, "bug", nil) {
+		t.Errorf("Lookup found a forgotten label")
+	}
+	entries := j.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("Entries returned %d entries, want 2: %+v", len(entries), entries)
+	}
+	if entries[0].Kind != KindRepo || !entries[0].Existing {
+		t.Errorf("Entries[0] = %+v, want an existing repo", entries[0])
+	}
+	if entries[1].Kind != KindIssue || entries[1].Existing || string(entries[1].Dest) != "4" {
+		t.Errorf("Entries[1] = %+v, want a created issue #4", entries[1])
+	}
+}
@@ -0,0 +1,102 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 Artur Sak
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Marks is a JSON file holding, per repository, the time up to which the source's changes were synced
+// A nil Marks records nothing and finds nothing.
+type Marks struct {
+	mu    sync.Mutex
+	path  string
+	marks map[string]time.Time
+}
+
+// OpenMarks loads the marks at path, a missing file holds no marks
+func OpenMarks(path string) (*Marks, error) {
+	m := &Marks{path: path, marks: make(map[string]time.Time)}
+
+	buf, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sync marks %s due to: %v", path, err)
+	}
+	if err := json.Unmarshal(buf, &m.marks); err != nil {
+		return nil, fmt.Errorf("failed to parse sync marks %s due to: %v", path, err)
+	}
+	return m, nil
+}
+
+// Get returns the mark of a repository and whether it was ever synced
+func (m *Marks) Get(repo string) (time.Time, bool) {
+	if m == nil {
+		return time.Time{}, false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.marks[repo]
+	return t, ok
+}
+
+// Set moves the mark of a repository and saves every mark, replacing the file only once it is complete
+func (m *Marks) Set(repo string, t time.Time) error {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.marks[repo] = t.UTC()
+
+	buf, err := json.MarshalIndent(m.marks, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode sync marks due to: %v", err)
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(m.path), filepath.Base(m.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write sync marks %s due to: %v", m.path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(buf, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write sync marks %s due to: %v", m.path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write sync marks %s due to: %v", m.path, err)
+	}
+	if err := os.Rename(tmp.Name(), m.path); err != nil {
+		return fmt.Errorf("failed to write sync marks %s due to: %v", m.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package state
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMarks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gitmv-marks")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "marks.json")
+
+	m, err := OpenMarks(path)
+	if err != nil {
+		t.Fatalf("OpenMarks returned error: %v", err)
+	}
+	if _, ok := m.Get("r"); ok {
+		t.Errorf("Get found a mark in an empty file")
+	}
+	synced := time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := m.Set("r", synced); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+
+	m, err = OpenMarks(path)
+	if err != nil {
+		t.Fatalf("OpenMarks returned error: %v", err)
+	}
+	if got, ok := m.Get("r"); !ok || !got.Equal(synced) {
+		t.Errorf("Get returned %v, %t, want %v", got, ok, synced)
+	}
+
+	var none *Marks
+	if err := none.Set("r", synced); err != nil {
+		t.Errorf("Set on a nil Marks returned error: %v", err)
+	}
+	if _, ok := none.Get("r"); ok {
+		t.Errorf("Get on a nil Marks found a mark")
+	}
+}
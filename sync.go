@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/artur-sak13/gitmv/migrator"
+	"github.com/artur-sak13/gitmv/provider"
+	"github.com/artur-sak13/gitmv/state"
+)
+
+const syncHelp = `Keep the destination up to date with the source until cutover, syncing every interval.`
+
+func (cmd *syncCommand) Name() string      { return "sync" }
+func (cmd *syncCommand) Args() string      { return "[OPTIONS]" }
+func (cmd *syncCommand) ShortHelp() string { return syncHelp }
+func (cmd *syncCommand) LongHelp() string  { return syncHelp }
+func (cmd *syncCommand) Hidden() bool      { return false }
+
+func (cmd *syncCommand) Register(fs *flag.FlagSet) {
+	fs.DurationVar(&cmd.interval, "interval", 5*time.Minute, "time to wait between sync passes")
+	fs.StringVar(&cmd.marks, "marks", "gitmv-sync.json", "file recording up to when each repository was synced")
+	fs.BoolVar(&cmd.once, "once", false, "run a single sync pass and exit")
+}
+
+type syncCommand struct {
+	interval time.Duration
+	marks    string
+	once     bool
+}
+
+func (cmd *syncCommand) Run(ctx context.Context, args []string) error {
+	if dryrun {
+		return fmt.Errorf("sync cannot be a dry run, use plan to preview a migration")
+	}
+	if stateFile == "" {
+		return fmt.Errorf("sync needs a --state file to find the destination of source issues")
+	}
+	return runCommand(ctx, cmd.handleSync)
+}
+
+// handleSync repeats sync passes until ctx is cancelled
+// Failed passes are logged and retried by the next one, so that a transient outage does not stop the sync.
+func (cmd *syncCommand) handleSync(ctx context.Context, src, dest provider.GitProvider) error {
+	marks, err := state.OpenMarks(cmd.marks)
+	if err != nil {
+		return err
+	}
+
+	for {
+		start := time.Now()
+		mig := newMigrator(src, dest)
+		mig.State = journal
+
+		err := mig.Sync(ctx, marks)
+		if ctx.Err() != nil {
+			logrus.Infof("sync stopped")
+			return nil
+		}
+		switch err {
+		case nil:
+			logrus.Infof("sync pass finished in %s", time.Since(start))
+		case migrator.ErrIncomplete:
+			logrus.Warnf("sync pass finished in %s with %d errors, retrying them next pass", time.Since(start), len(mig.Report.Errors()))
+		default:
+			logrus.Errorf("sync pass failed: %v", err)
+		}
+		if cmd.once {
+			return err
+		}
+
+		if sleepContext(ctx, cmd.interval) != nil {
+			logrus.Infof("sync stopped")
+			return nil
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+
+	"github.com/artur-sak13/gitmv/provider"
+	"github.com/artur-sak13/gitmv/verify"
+)
+
+const verifyHelp = `Compare the branches, tags, labels, issues, comments and wikis of the destination with the source.`
+
+func (cmd *verifyCommand) Name() string      { return "verify" }
+func (cmd *verifyCommand) Args() string      { return "[OPTIONS]" }
+func (cmd *verifyCommand) ShortHelp() string { return verifyHelp }
+func (cmd *verifyCommand) LongHelp() string  { return verifyHelp }
+func (cmd *verifyCommand) Hidden() bool      { return false }
+
+func (cmd *verifyCommand) Register(fs *flag.FlagSet) {
+	fs.BoolVar(&cmd.skipWikis, "skip-wikis", false, "do not compare wiki pages, which requires SSH access to both wikis")
+}
+
+type verifyCommand struct {
+	skipWikis bool
+}
+
+func (cmd *verifyCommand) Run(ctx context.Context, args []string) error {
+	return runCommand(ctx, cmd.handleVerify)
+}
+
+// handleVerify prints a pass/fail report and exits non-zero if any check failed
+func (cmd *verifyCommand) handleVerify(ctx context.Context, src, dest provider.GitProvider) error {
+	verifier := verify.NewVerifier(src, dest, from.kind)
+	verifier.Wikis = !cmd.skipWikis
+	verifier.Workers = repoWorkers
+	verifier.Filter = repoFilter
+
+	report, err := verifier.Verify(ctx)
+	if err != nil {
+		return err
+	}
+	if err := report.Print(os.Stdout); err != nil {
+		return err
+	}
+	if report.Failed() {
+		os.Exit(1)
+	}
+	return nil
+}
@@ -0,0 +1,335 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 Artur Sak
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package verify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/artur-sak13/gitmv/filter"
+	"github.com/artur-sak13/gitmv/pool"
+	"github.com/artur-sak13/gitmv/provider"
+)
+
+// DefaultWorkers is the number of repositories a Verifier compares at once
+const DefaultWorkers = 4
+
+// Names of the checks run on every repository
+const (
+	CheckRepository  = "repository"
+	CheckBranches    = "branches"
+	CheckTags        = "tags"
+	CheckLabels      = "labels"
+	CheckIssues      = "issues"
+	CheckIssueStates = "issue states"
+	CheckComments    = "comments"
+	CheckWiki        = "wiki pages"
+)
+
+// Check is the outcome of comparing one aspect of a repository
+type Check struct {
+	Repo   string
+	Name   string
+	Passed bool
+	// Detail summarizes what was compared and lists any mismatches
+	Detail string
+}
+
+// Verifier compares every repository of Src with the repository of the same name in Dest
+// Issues and comments are matched by the source markers gitmv stamps on them.
+type Verifier struct {
+	Src  provider.GitProvider
+	Dest provider.GitProvider
+
+	// SourceKind is the registry kind of Src, it identifies the source in the markers of migrated issues and comments
+	SourceKind string
+
+	// Wikis enables comparing wiki pages, which requires SSH access to both wikis
+	Wikis bool
+
+	// Filter selects the source repositories to verify, all but forks and empty repositories when it is nil
+	Filter *filter.Filter
+
+	Workers int
+}
+
+// NewVerifier creates a verifier comparing src with dest
+func NewVerifier(src, dest provider.GitProvider, sourceKind string) *Verifier {
+	return &Verifier{
+		Src:        src,
+		Dest:       dest,
+		SourceKind: sourceKind,
+		Wikis:      true,
+		Workers:    DefaultWorkers,
+	}
+}
+
+// Verify runs every check on every source repository, an error is only returned if the repositories cannot be listed
+func (v *Verifier) Verify(ctx context.Context) (*Report, error) {
+	repos, err := v.Src.GetRepositories(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source repositories due to: %v", err)
+	}
+	repos = v.Filter.Select(repos)
+	cache, err := provider.LoadCache(ctx, v.Dest, v.Workers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read destination due to: %v", err)
+	}
+
+	checks := make([][]*Check, len(repos))
+	workers := pool.New(v.Workers)
+	for i, repo := range repos {
+		i, repo := i, repo
+		workers.Go(func() {
+			checks[i] = v.verifyRepo(ctx, repo, cache[repo.Name])
+		})
+	}
+	workers.Wait()
+
+	report := &Report{}
+	for _, repoChecks := range checks {
+		report.Checks = append(report.Checks, repoChecks...)
+	}
+	return report, nil
+}
+
+func (v *Verifier) verifyRepo(ctx context.Context, repo *provider.GitRepository, cached *provider.CachedRepo) []*Check {
+	if cached == nil {
+		return []*Check{{Repo: repo.Name, Name: CheckRepository, Detail: "missing from the destination"}}
+	}
+	destRepo := cached.Repo
+
+	checks := v.verifyRefs(ctx, repo, destRepo)
+	checks = append(checks, v.verifyLabels(ctx, repo, cached))
+	checks = append(checks, v.verifyIssues(ctx, repo, cached)...)
+	if v.Wikis {
+		checks = append(checks, v.verifyWiki(ctx, repo, destRepo))
+	}
+	return checks
+}
+
+// verifyRefs checks that every source branch and tag exists in the destination at the same commit
+func (v *Verifier) verifyRefs(ctx context.Context, repo, destRepo *provider.GitRepository) []*Check {
+	branches := &Check{Repo: repo.Name, Name: CheckBranches}
+	tags := &Check{Repo: repo.Name, Name: CheckTags}
+
+	srcRefs, err := v.Src.GetRefs(ctx, repo.PID, repo.Name)
+	if err != nil {
+		branches.Detail = fmt.Sprintf("failed to list source refs: %v", err)
+		tags.Detail = branches.Detail
+		return []*Check{branches, tags}
+	}
+	destRefs, err := v.Dest.GetRefs(ctx, destRepo.PID, destRepo.Name)
+	if err != nil {
+		branches.Detail = fmt.Sprintf("failed to list destination refs: %v", err)
+		tags.Detail = branches.Detail
+		return []*Check{branches, tags}
+	}
+
+	compareRefs(branches, false, srcRefs, destRefs)
+	compareRefs(tags, true, srcRefs, destRefs)
+	return []*Check{branches, tags}
+}
+
+func compareRefs(check *Check, tag bool, srcRefs, destRefs []*provider.GitRef) {
+	dest := make(map[string]string)
+	for _, ref := range destRefs {
+		if ref.Tag == tag {
+			dest[ref.Name] = ref.SHA
+		}
+	}
+
+	total := 0
+	var mismatches []string
+	for _, ref := range srcRefs {
+		if ref.Tag != tag {
+			continue
+		}
+		total++
+		sha, ok := dest[ref.Name]
+		switch {
+		case !ok:
+			mismatches = append(mismatches, ref.Name+" missing")
+		case sha != ref.SHA:
+			mismatches = append(mismatches, fmt.Sprintf("%s at %s, want %s", ref.Name, short(sha), short(ref.SHA)))
+		}
+	}
+	check.conclude(total, mismatches)
+}
+
+// verifyLabels checks that every source label exists in the destination, which may have more
+func (v *Verifier) verifyLabels(ctx context.Context, repo *provider.GitRepository, cached *provider.CachedRepo) *Check {
+	check := &Check{Repo: repo.Name, Name: CheckLabels}
+	labels, err := v.Src.GetLabels(ctx, repo.PID, repo.Name)
+	if err != nil {
+		check.Detail = fmt.Sprintf("failed to get source labels: %v", err)
+		return check
+	}
+
+	var mismatches []string
+	for _, label := range labels {
+		if _, ok := cached.Labels[label.Name]; !ok {
+			mismatches = append(mismatches, label.Name+" missing")
+		}
+	}
+	check.conclude(len(labels), mismatches)
+	return check
+}
+
+// verifyIssues checks that every source issue and comment has a marked counterpart and that issue states match
+func (v *Verifier) verifyIssues(ctx context.Context, repo *provider.GitRepository, cached *provider.CachedRepo) []*Check {
+	issues := &Check{Repo: repo.Name, Name: CheckIssues}
+	states := &Check{Repo: repo.Name, Name: CheckIssueStates}
+	comments := &Check{Repo: repo.Name, Name: CheckComments}
+
+	srcIssues, err := v.Src.GetIssues(ctx, repo.PID, repo.Name)
+	if err != nil {
+		issues.Detail = fmt.Sprintf("failed to get source issues: %v", err)
+		states.Detail, comments.Detail = issues.Detail, issues.Detail
+		return []*Check{issues, states, comments}
+	}
+
+	var missing, wrongState, missingComments []string
+	totalComments := 0
+	for _, issue := range srcIssues {
+		cachedissue, ok := cached.Issues[provider.IssueMarker(v.SourceKind, issue)]
+		if !ok {
+			missing = append(missing, fmt.Sprintf("#%d missing", issue.Number))
+		} else if closed(issue.State) != closed(cachedissue.Issue.State) {
+			wrongState = append(wrongState, fmt.Sprintf("#%d is %s, want %s", issue.Number, cachedissue.Issue.State, issue.State))
+		}
+
+		srcComments, err := v.Src.GetComments(ctx, issue.PID, issue.Number, repo.Name)
+		if err != nil {
+			missingComments = append(missingComments, fmt.Sprintf("failed to get comments of #%d: %v", issue.Number, err))
+			continue
+		}
+		totalComments += len(srcComments)
+		for _, comment := range srcComments {
+			found := false
+			if cachedissue != nil {
+				_, found = cachedissue.Comments[provider.CommentMarker(v.SourceKind, issue, comment)]
+			}
+			if !found {
+				missingComments = append(missingComments, fmt.Sprintf("#%d comment %d missing", issue.Number, comment.ID))
+			}
+		}
+	}
+
+	issues.conclude(len(srcIssues), missing)
+	states.conclude(len(srcIssues)-len(missing), wrongState)
+	comments.conclude(totalComments, missingComments)
+	return []*Check{issues, states, comments}
+}
+
+// verifyWiki checks that both wikis have the same set of pages
+func (v *Verifier) verifyWiki(ctx context.Context, repo, destRepo *provider.GitRepository) *Check {
+	check := &Check{Repo: repo.Name, Name: CheckWiki}
+	srcPages, err := provider.GetWikiPages(ctx, repo)
+	if err != nil {
+		check.Detail = fmt.Sprintf("failed to read source wiki: %v", err)
+		return check
+	}
+	destPages, err := provider.GetWikiPages(ctx, destRepo)
+	if err != nil {
+		check.Detail = fmt.Sprintf("failed to read destination wiki: %v", err)
+		return check
+	}
+
+	dest := make(map[string]bool)
+	for _, page := range destPages {
+		dest[page] = true
+	}
+	var mismatches []string
+	for _, page := range srcPages {
+		if !dest[page] {
+			mismatches = append(mismatches, page+" missing")
+		}
+		delete(dest, page)
+	}
+	for page := range dest {
+		mismatches = append(mismatches, page+" not in the source")
+	}
+	sort.Strings(mismatches)
+	check.conclude(len(srcPages), mismatches)
+	return check
+}
+
+// conclude passes a check if nothing out of total mismatched
+func (c *Check) conclude(total int, mismatches []string) {
+	c.Passed = len(mismatches) == 0
+	c.Detail = fmt.Sprintf("%d of %d match", total-len(mismatches), total)
+	if !c.Passed {
+		c.Detail += ": " + strings.Join(mismatches, ", ")
+	}
+}
+
+func closed(state string) bool {
+	return strings.EqualFold(state, "closed")
+}
+
+func short(sha string) string {
+	if len(sha) > 10 {
+		return sha[:10]
+	}
+	return sha
+}
+
+// Report lists the checks of every repository
+type Report struct {
+	Checks []*Check
+}
+
+// Failed reports whether any check failed
+func (r *Report) Failed() bool {
+	for _, check := range r.Checks {
+		if !check.Passed {
+			return true
+		}
+	}
+	return false
+}
+
+// Print writes a table of every check followed by the number that failed
+func (r *Report) Print(w io.Writer) error {
+	failed := 0
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "REPO\tCHECK\tRESULT\tDETAIL")
+	for _, check := range r.Checks {
+		result := "PASS"
+		if !check.Passed {
+			result = "FAIL"
+			failed++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", check.Repo, check.Name, result, check.Detail)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%d of %d checks failed\n", failed, len(r.Checks))
+	return err
+}
@@ -0,0 +1,116 @@
+package verify
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/artur-sak13/gitmv/provider"
+)
+
+// memProvider serves the reads of a Verifier from memory
+type memProvider struct {
+	provider.GitProvider
+	repos    []*provider.GitRepository
+	refs     map[string][]*provider.GitRef
+	labels   map[string][]*provider.GitLabel
+	issues   map[string][]*provider.GitIssue
+	comments map[int][]*provider.GitIssueComment
+}
+
+func (m *memProvider) GetRepositories(ctx context.Context) ([]*provider.GitRepository, error) {
+	return m.repos, nil
+}
+
+func (m *memProvider) GetRefs(ctx context.Context, pid int, repo string) ([]*provider.GitRef, error) {
+	return m.refs[repo], nil
+}
+
+func (m *memProvider) GetLabels(ctx context.Context, pid int, repo string) ([]*provider.GitLabel, error) {
+	return m.labels[repo], nil
+}
+
+func (m *memProvider) GetIssues(ctx context.Context, pid int, repo string) ([]*provider.GitIssue, error) {
+	return m.issues[repo], nil
+}
+
+func (m *memProvider) GetComments(ctx context.Context, pid, issueNum int, repo string) ([]*provider.GitIssueComment, error) {
+	return m.comments[issueNum], nil
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	first := &provider.GitIssue{Repo: "r", PID: 1, Number: 1, State: "closed"}
+	second := &provider.GitIssue{Repo: "r", PID: 1, Number: 2, State: "opened"}
+	comment := &provider.GitIssueComment{ID: 10, Repo: "r", IssueNum: 1, Body: "hello"}
+
+	src := &memProvider{
+		repos: []*provider.GitRepository{{Name: "r", PID: 1}, {Name: "gone", PID: 2}, {Name: "fork", PID: 3, Fork: true}},
+		refs: map[string][]*provider.GitRef{
+			"r": {{Name: "master", SHA: "abc"}, {Name: "dev", SHA: "def"}, {Name: "v1", Tag: true, SHA: "abc"}},
+		},
+		labels: map[string][]*provider.GitLabel{
+			"r": {{Repo: "r", Name: "bug"}},
+		},
+		issues: map[string][]*provider.GitIssue{
+			"r": {first, second},
+		},
+		comments: map[int][]*provider.GitIssueComment{1: {comment}},
+	}
+
+	migrated := provider.MarkIssue("gitlab", first)
+	dest := &memProvider{
+		repos: []*provider.GitRepository{{Name: "r", PID: 7}},
+		refs: map[string][]*provider.GitRef{
+			"r": {{Name: "master", SHA: "abc"}, {Name: "dev", SHA: "123"}, {Name: "v1", Tag: true, SHA: "abc"}},
+		},
+		labels: map[string][]*provider.GitLabel{
+			"r": {{Repo: "r", Name: "bug"}, {Repo: "r", Name: "extra"}},
+		},
+		issues: map[string][]*provider.GitIssue{
+			"r": {{Repo: "r", Number: 5, State: "open", Body: migrated.Body}},
+		},
+		comments: map[int][]*provider.GitIssueComment{5: {provider.MarkComment("gitlab", first, comment)}},
+	}
+
+	v := NewVerifier(src, dest, "gitlab")
+	v.Wikis = false
+	report, err := v.Verify(context.Background())
+	if err != nil {
+		t.Fatalf("Verify returned error: %v", err)
+	}
+
+	want := []struct {
+		repo, name string
+		passed     bool
+		detail     string
+	}{
+		{"r", CheckBranches, false, "1 of 2 match: dev at 123, want def"},
+		{"r", CheckTags, true, "1 of 1 match"},
+		{"r", CheckLabels, true, "1 of 1 match"},
+		{"r", CheckIssues, false, "1 of 2 match: #2 missing"},
+		{"r", CheckIssueStates, false, "0 of 1 match: #1 is open, want closed"},
+		{"r", CheckComments, true, "1 of 1 match"},
+		{"gone", CheckRepository, false, "missing from the destination"},
+	}
+	if len(report.Checks) != len(want) {
+		t.Fatalf("Verify ran %d checks, want %d: %+v", len(report.Checks), len(want), report.Checks)
+	}
+	for i, w := range want {
+		check := report.Checks[i]
+		if check.Repo != w.repo || check.Name != w.name || check.Passed != w.passed || check.Detail != w.detail {
+			t.Errorf("check %d = %+v, want %+v", i, *check, w)
+		}
+	}
+	if !report.Failed() {
+		t.Errorf("Failed = false, want true")
+	}
+
+	var buf bytes.Buffer
+	if err := report.Print(&buf); err != nil {
+		t.Fatalf("Print returned error: %v", err)
+	}
+	if !strings.HasSuffix(buf.String(), "4 of 7 checks failed\n") {
+		t.Errorf("Print = %q, want a summary of 4 of 7 checks failed", buf.String())
+	}
+}
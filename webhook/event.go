@@ -0,0 +1,207 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 Artur Sak
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/artur-sak13/gitmv/provider"
+)
+
+// Kind is the type of change a webhook event announces
+type Kind string
+
+// Event kinds
+const (
+	KindPush  Kind = "push"
+	KindIssue Kind = "issue"
+	KindNote  Kind = "note"
+)
+
+// Event is a change to a source repository announced by a webhook
+type Event struct {
+	// Seq orders the events of a Queue
+	Seq int64 `json:"-"`
+	// Attempts counts the failed replays of the event
+	Attempts int    `json:"attempts,omitempty"`
+	Provider string `json:"provider"`
+	Kind     Kind   `json:"kind"`
+	Repo     string `json:"repo"`
+	PID      int    `json:"pid"`
+	CloneURL string `json:"clone_url,omitempty"`
+	// Issue is the number of the changed issue, it is zero for pushes
+	Issue int `json:"issue,omitempty"`
+	// Changed is when the source changed, or when the event was received if the payload does not say
+	Changed time.Time `json:"changed"`
+}
+
+// Repository returns the source repository of the event
+func (e *Event) Repository() *provider.GitRepository {
+	return &provider.GitRepository{
+		Name:     e.Repo,
+		PID:      e.PID,
+		CloneURL: e.CloneURL,
+	}
+}
+
+func (e *Event) String() string {
+	if e.Issue != 0 {
+		return fmt.Sprintf("%s %s %s#%d", e.Provider, e.Kind, e.Repo, e.Issue)
+	}
+	return fmt.Sprintf("%s %s %s", e.Provider, e.Kind, e.Repo)
+}
+
+type gitlabHook struct {
+	ObjectKind string `json:"object_kind"`
+	Project    struct {
+		ID                int    `json:"id"`
+		PathWithNamespace string `json:"path_with_namespace"`
+		GitHTTPURL        string `json:"git_http_url"`
+	} `json:"project"`
+	ObjectAttributes struct {
+		IID          int    `json:"iid"`
+		NoteableType string `json:"noteable_type"`
+		UpdatedAt    string `json:"updated_at"`
+	} `json:"object_attributes"`
+	Issue struct {
+		IID int `json:"iid"`
+	} `json:"issue"`
+}
+
+// gitlabTimeFormats are the layouts GitLab versions use for timestamps in webhooks
+var gitlabTimeFormats = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04:05 -0700",
+}
+
+// parseGitlab reads a GitLab push, tag push, issue or issue note hook, other hooks are ignored with a nil event
+// The repository is named with naming, or by its path when naming is nil.
+func parseGitlab(body []byte, naming provider.NameFunc) (*Event, error) {
+	var hook gitlabHook
+	if err := json.Unmarshal(body, &hook); err != nil {
+		return nil, fmt.Errorf("failed to parse gitlab webhook due to: %v", err)
+	}
+
+	event := &Event{
+		Provider: "gitlab",
+		PID:      hook.Project.ID,
+		CloneURL: hook.Project.GitHTTPURL,
+	}
+	switch hook.ObjectKind {
+	case "push", "tag_push":
+		event.Kind = KindPush
+	case "issue":
+		event.Kind = KindIssue
+		event.Issue = hook.ObjectAttributes.IID
+	case "note":
+		// merge request notes are copied with their merge request
+		if hook.ObjectAttributes.NoteableType != "Issue" {
+			return nil, nil
+		}
+		event.Kind = KindNote
+		event.Issue = hook.Issue.IID
+	default:
+		return nil, nil
+	}
+	if hook.Project.PathWithNamespace == "" {
+		return nil, fmt.Errorf("gitlab %s webhook has no project", hook.ObjectKind)
+	}
+	event.Repo = path.Base(hook.Project.PathWithNamespace)
+	if naming != nil {
+		var namespace string
+		if i := strings.LastIndex(hook.Project.PathWithNamespace, "/"); i >= 0 {
+			namespace = hook.Project.PathWithNamespace[:i]
+		}
+		name, err := naming(namespace, event.Repo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to name %s due to: %v", hook.Project.PathWithNamespace, err)
+		}
+		event.Repo = name
+	}
+
+	for _, layout := range gitlabTimeFormats {
+		if changed, err := time.Parse(layout, hook.ObjectAttributes.UpdatedAt); err == nil {
+			event.Changed = changed.UTC()
+			break
+		}
+	}
+	return event, nil
+}
+
+type githubHook struct {
+	Repository struct {
+		ID       int    `json:"id"`
+		Name     string `json:"name"`
+		CloneURL string `json:"clone_url"`
+	} `json:"repository"`
+	Issue *struct {
+		Number      int              `json:"number"`
+		UpdatedAt   time.Time        `json:"updated_at"`
+		PullRequest *json.RawMessage `json:"pull_request"`
+	} `json:"issue"`
+	Comment *struct {
+		UpdatedAt time.Time `json:"updated_at"`
+	} `json:"comment"`
+}
+
+// parseGithub reads a GitHub push, create, issues or issue_comment delivery, other events are ignored with a nil event
+func parseGithub(kind string, body []byte) (*Event, error) {
+	var hook githubHook
+	if err := json.Unmarshal(body, &hook); err != nil {
+		return nil, fmt.Errorf("failed to parse github webhook due to: %v", err)
+	}
+
+	event := &Event{
+		Provider: "github",
+		Repo:     hook.Repository.Name,
+		PID:      hook.Repository.ID,
+		CloneURL: hook.Repository.CloneURL,
+	}
+	switch kind {
+	case "push", "create":
+		event.Kind = KindPush
+	case "issues", "issue_comment":
+		// comments on pull requests are copied with their pull request
+		if hook.Issue == nil || hook.Issue.PullRequest != nil {
+			return nil, nil
+		}
+		event.Kind = KindIssue
+		event.Issue = hook.Issue.Number
+		event.Changed = hook.Issue.UpdatedAt
+		if hook.Comment != nil {
+			event.Kind = KindNote
+			event.Changed = hook.Comment.UpdatedAt
+		}
+	default:
+		return nil, nil
+	}
+	if hook.Repository.Name == "" {
+		return nil, fmt.Errorf("github %s webhook has no repository", kind)
+	}
+	return event, nil
+}
@@ -0,0 +1,132 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 Artur Sak
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/artur-sak13/gitmv/provider"
+	"github.com/sirupsen/logrus"
+)
+
+// maxPayload bounds the size of a webhook delivery, GitLab and GitHub cap theirs at 25MB
+const maxPayload = 25 << 20
+
+// Handler verifies webhook deliveries and queues the events they announce
+// GitLab deliveries must carry Secret as their token and GitHub deliveries must be signed with it.
+// A delivery is only acknowledged once its event is queued, so the sender retries it otherwise.
+type Handler struct {
+	Secret string
+	Queue  *Queue
+	// Naming derives the name of GitLab repositories like the source provider does, only the path is used when it is nil
+	Naming provider.NameFunc
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "webhooks must be posted", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxPayload))
+	if err != nil {
+		http.Error(w, "failed to read webhook", http.StatusBadRequest)
+		return
+	}
+
+	var event *Event
+	switch {
+	case r.Header.Get("X-Gitlab-Event") != "":
+		if !h.validToken(r.Header.Get("X-Gitlab-Token")) {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		event, err = parseGitlab(body, h.Naming)
+	case r.Header.Get("X-GitHub-Event") != "":
+		if !h.validSignature(r.Header, body) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		event, err = parseGithub(r.Header.Get("X-GitHub-Event"), body)
+	default:
+		http.Error(w, "not a GitLab or GitHub webhook", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if event == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if event.Changed.IsZero() {
+		event.Changed = time.Now().UTC()
+	}
+	if err := h.Queue.Push(event); err != nil {
+		logrus.Errorf("error queueing %s event: %v", event, err)
+		http.Error(w, "failed to queue event", http.StatusInternalServerError)
+		return
+	}
+	logrus.Infof("queued %s event", event)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// validToken compares a GitLab secret token with the Secret, an empty Secret accepts nothing
+func (h *Handler) validToken(token string) bool {
+	return h.Secret != "" && subtle.ConstantTimeCompare([]byte(token), []byte(h.Secret)) == 1
+}
+
+// validSignature checks the HMAC of a GitHub delivery, preferring SHA-256 over the legacy SHA-1 signature
+func (h *Handler) validSignature(header http.Header, body []byte) bool {
+	if h.Secret == "" {
+		return false
+	}
+	if signature := header.Get("X-Hub-Signature-256"); signature != "" {
+		return checkMAC(sha256.New, "sha256=", signature, h.Secret, body)
+	}
+	return checkMAC(sha1.New, "sha1=", header.Get("X-Hub-Signature"), h.Secret, body)
+}
+
+func checkMAC(hashFn func() hash.Hash, prefix, signature, secret string, body []byte) bool {
+	if !strings.HasPrefix(signature, prefix) {
+		return false
+	}
+	got, err := hex.DecodeString(strings.TrimPrefix(signature, prefix))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(hashFn, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
@@ -0,0 +1,144 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setupQueue(t *testing.T) (*Queue, string, func()) {
+	dir, err := ioutil.TempDir("", "gitmv-webhook")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	path := filepath.Join(dir, "queue.jsonl")
+	q, err := OpenQueue(path)
+	if err != nil {
+		t.Fatalf("OpenQueue returned error: %v", err)
+	}
+	return q, path, func() {
+		q.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func deliver(h http.Handler, header map[string]string, body string) int {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w.Code
+}
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestHandler_Gitlab(t *testing.T) {
+	q, _, teardown := setupQueue(t)
+	defer teardown()
+	h := &Handler{Secret: "s3cret", Queue: q}
+
+	issue := `{"object_kind":"issue","project":{"id":7,"path_with_namespace":"group/r","git_http_url":"https://gitlab.example.com/group/r.git"},
+		"object_attributes":{"iid":3,"updated_at":"2019-01-01 12:00:00 UTC"}}`
+	if code := deliver(h, map[string]string{"X-Gitlab-Event": "Issue Hook", "X-Gitlab-Token": "wrong"}, issue); code != http.StatusUnauthorized {
+		t.Errorf("delivery with a wrong token returned %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := deliver(h, map[string]string{"X-Gitlab-Event": "Issue Hook", "X-Gitlab-Token": "s3cret"}, issue); code != http.StatusAccepted {
+		t.Errorf("issue delivery returned %d, want %d", code, http.StatusAccepted)
+	}
+
+	mr := `{"object_kind":"note","project":{"id":7,"path_with_namespace":"group/r"},"object_attributes":{"noteable_type":"MergeRequest"}}`
+	if code := deliver(h, map[string]string{"X-Gitlab-Event": "Note Hook", "X-Gitlab-Token": "s3cret"}, mr); code != http.StatusNoContent {
+		t.Errorf("merge request note delivery returned %d, want %d", code, http.StatusNoContent)
+	}
+
+	if q.Len() != 1 {
+		t.Fatalf("queue holds %d events, want 1", q.Len())
+	}
+	want := &Event{
+		Seq:      1,
+		Provider: "gitlab",
+		Kind:     KindIssue,
+		Repo:     "r",
+		PID:      7,
+		CloneURL: "https://gitlab.example.com/group/r.git",
+		Issue:    3,
+		Changed:  time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+	if got := q.pending[0]; !reflect.DeepEqual(got, want) {
+		t.Errorf("queued event = %+v, want %+v", got, want)
+	}
+}
+
+func TestHandler_GitlabNaming(t *testing.T) {
+	q, _, teardown := setupQueue(t)
+	defer teardown()
+	h := &Handler{Secret: "s3cret", Queue: q, Naming: func(namespace, path string) (string, error) {
+		return strings.Replace(namespace, "/", "-", -1) + "-" + path, nil
+	}}
+
+	push := `{"object_kind":"push","project":{"id":7,"path_with_namespace":"group/sub/r"}}`
+	if code := deliver(h, map[string]string{"X-Gitlab-Event": "Push Hook", "X-Gitlab-Token": "s3cret"}, push); code != http.StatusAccepted {
+		t.Errorf("push delivery returned %d, want %d", code, http.StatusAccepted)
+	}
+	if q.Len() != 1 {
+		t.Fatalf("queue holds %d events, want 1", q.Len())
+	}
+	if got, want := q.pending[0].Repo, "group-sub-r"; got != want {
+		t.Errorf("queued event repo = %q, want %q", got, want)
+	}
+}
+
+func TestHandler_Github(t *testing.T) {
+	q, _, teardown := setupQueue(t)
+	defer teardown()
+	h := &Handler{Secret: "s3cret", Queue: q}
+
+	comment := `{"repository":{"id":9,"name":"r","clone_url":"https://github.com/o/r.git"},
+		"issue":{"number":4,"updated_at":"2019-01-01T00:00:00Z"},"comment":{"updated_at":"2019-01-02T00:00:00Z"}}`
+	if code := deliver(h, map[string]string{"X-GitHub-Event": "issue_comment", "X-Hub-Signature-256": sign("wrong", comment)}, comment); code != http.StatusUnauthorized {
+		t.Errorf("delivery with a wrong signature returned %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := deliver(h, map[string]string{"X-GitHub-Event": "issue_comment", "X-Hub-Signature-256": sign("s3cret", comment)}, comment); code != http.StatusAccepted {
+		t.Errorf("comment delivery returned %d, want %d", code, http.StatusAccepted)
+	}
+
+	ping := `{"zen":"Keep it logically awesome."}`
+	if code := deliver(h, map[string]string{"X-GitHub-Event": "ping", "X-Hub-Signature-256": sign("s3cret", ping)}, ping); code != http.StatusNoContent {
+		t.Errorf("ping delivery returned %d, want %d", code, http.StatusNoContent)
+	}
+	if code := deliver(&Handler{Queue: q}, map[string]string{"X-GitHub-Event": "ping", "X-Hub-Signature-256": sign("", ping)}, ping); code != http.StatusUnauthorized {
+		t.Errorf("delivery to a handler without a secret returned %d, want %d", code, http.StatusUnauthorized)
+	}
+
+	if q.Len() != 1 {
+		t.Fatalf("queue holds %d events, want 1", q.Len())
+	}
+	want := &Event{
+		Seq:      1,
+		Provider: "github",
+		Kind:     KindNote,
+		Repo:     "r",
+		PID:      9,
+		CloneURL: "https://github.com/o/r.git",
+		Issue:    4,
+		Changed:  time.Date(2019, 1, 2, 0, 0, 0, 0, time.UTC),
+	}
+	if got := q.pending[0]; !reflect.DeepEqual(got, want) {
+		t.Errorf("queued event = %+v, want %+v", got, want)
+	}
+}
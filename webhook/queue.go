@@ -0,0 +1,218 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 Artur Sak
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package webhook
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Queue is a JSON lines file of the events waiting to be replayed, queued events survive restarts until they are done
+// It is meant for a single consumer, Next returns the oldest event until it is done or retried.
+type Queue struct {
+	mu      sync.Mutex
+	file    *os.File
+	pending []*Event
+	seq     int64
+	ready   chan struct{}
+}
+
+// queueLine adds an event to the queue or, once it is done, removes it
+type queueLine struct {
+	Seq   int64  `json:"seq"`
+	Event *Event `json:"event,omitempty"`
+	Done  bool   `json:"done,omitempty"`
+}
+
+// OpenQueue replays the queue at path, creating it if needed
+// The file is rewritten with only the pending events so that it does not grow with every event ever received.
+func OpenQueue(path string) (*Queue, error) {
+	q := &Queue{ready: make(chan struct{}, 1)}
+
+	events := make(map[int64]*Event)
+	f, err := os.Open(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to open queue %s due to: %v", path, err)
+	}
+	if err == nil {
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			var l queueLine
+			// a line cut short by a crash is dropped, its sender retries the unacknowledged delivery
+			if err := json.Unmarshal(scanner.Bytes(), &l); err != nil {
+				continue
+			}
+			if l.Seq > q.seq {
+				q.seq = l.Seq
+			}
+			if l.Done {
+				delete(events, l.Seq)
+			} else if l.Event != nil {
+				l.Event.Seq = l.Seq
+				events[l.Seq] = l.Event
+			}
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read queue %s due to: %v", path, err)
+		}
+	}
+	for _, event := range events {
+		q.pending = append(q.pending, event)
+	}
+	sort.Slice(q.pending, func(i, j int) bool { return q.pending[i].Seq < q.pending[j].Seq })
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to compact queue %s due to: %v", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriter(tmp)
+	for _, event := range q.pending {
+		data, err := json.Marshal(queueLine{Seq: event.Seq, Event: event})
+		if err != nil {
+			tmp.Close()
+			return nil, fmt.Errorf("failed to encode %s event due to: %v", event, err)
+		}
+		w.Write(append(data, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to compact queue %s due to: %v", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compact queue %s due to: %v", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, fmt.Errorf("failed to compact queue %s due to: %v", path, err)
+	}
+
+	q.file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open queue %s due to: %v", path, err)
+	}
+	if len(q.pending) > 0 {
+		q.ready <- struct{}{}
+	}
+	return q, nil
+}
+
+// Push appends an event to the queue, it is on disk once Push returns
+func (q *Queue) Push(event *Event) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.seq++
+	event.Seq = q.seq
+	if err := q.write(queueLine{Seq: event.Seq, Event: event}); err != nil {
+		return err
+	}
+	q.pending = append(q.pending, event)
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Next waits for the oldest pending event or until ctx is cancelled
+func (q *Queue) Next(ctx context.Context) (*Event, error) {
+	for {
+		q.mu.Lock()
+		if len(q.pending) > 0 {
+			event := q.pending[0]
+			q.mu.Unlock()
+			return event, nil
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-q.ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Done removes a replayed event from the queue
+func (q *Queue) Done(event *Event) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if err := q.write(queueLine{Seq: event.Seq, Done: true}); err != nil {
+		return err
+	}
+	for i, pending := range q.pending {
+		if pending == event {
+			q.pending = append(q.pending[:i], q.pending[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// Retry moves an event that failed to replay to the back of the queue and counts the attempt
+func (q *Queue) Retry(event *Event) error {
+	retried := *event
+	retried.Attempts++
+	if err := q.Push(&retried); err != nil {
+		return err
+	}
+	return q.Done(event)
+}
+
+// Len returns the number of pending events
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+// Close closes the queue's file
+func (q *Queue) Close() error {
+	return q.file.Close()
+}
+
+// write appends a line to the queue's file, the caller must hold mu
+func (q *Queue) write(l queueLine) error {
+	data, err := json.Marshal(l)
+	if err != nil {
+		return fmt.Errorf("failed to encode queue entry %d due to: %v", l.Seq, err)
+	}
+	if _, err := q.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write queue entry %d due to: %v", l.Seq, err)
+	}
+	if err := q.file.Sync(); err != nil {
+		return fmt.Errorf("failed to write queue entry %d due to: %v", l.Seq, err)
+	}
+	return nil
+}
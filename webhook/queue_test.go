@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestQueue(t *testing.T) {
+	q, path, teardown := setupQueue(t)
+	defer teardown()
+	ctx := context.Background()
+
+	for _, repo := range []string{"a", "b", "c"} {
+		if err := q.Push(&Event{Kind: KindPush, Repo: repo}); err != nil {
+			t.Fatalf("Push returned error: %v", err)
+		}
+	}
+	first, err := q.Next(ctx)
+	if err != nil || first.Repo != "a" {
+		t.Fatalf("Next = %v, %v, want the event of a", first, err)
+	}
+	if err := q.Done(first); err != nil {
+		t.Fatalf("Done returned error: %v", err)
+	}
+	second, _ := q.Next(ctx)
+	if err := q.Retry(second); err != nil {
+		t.Fatalf("Retry returned error: %v", err)
+	}
+	q.Close()
+
+	// pending events survive a restart in order, retried ones at the back
+	q, err = OpenQueue(path)
+	if err != nil {
+		t.Fatalf("OpenQueue returned error: %v", err)
+	}
+	defer q.Close()
+	var repos []string
+	for q.Len() > 0 {
+		event, _ := q.Next(ctx)
+		repos = append(repos, event.Repo)
+		if event.Repo == "b" && event.Attempts != 1 {
+			t.Errorf("retried event has %d attempts, want 1", event.Attempts)
+		}
+		q.Done(event)
+	}
+	if len(repos) != 2 || repos[0] != "c" || repos[1] != "b" {
+		t.Errorf("reopened queue replayed %v, want [c b]", repos)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := q.Next(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Next on an empty queue returned %v, want %v", err, context.DeadlineExceeded)
+	}
+}
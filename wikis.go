@@ -3,8 +3,10 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 
 	"github.com/artur-sak13/gitmv/provider"
+	"github.com/artur-sak13/gitmv/state"
 )
 
 const wikisHelp = `Migrate all wikis from one Git provider to another.`
@@ -23,17 +25,37 @@ func (cmd *wikisCommand) Run(ctx context.Context, args []string) error {
 	return runCommand(ctx, cmd.handleWikis)
 }
 
-// handleWikis will return
+// handleWikis will mirror the wiki of every source repository into its destination repository
 func (cmd *wikisCommand) handleWikis(ctx context.Context, src, dest provider.GitProvider) error {
-	repos, err := src.GetRepositories()
+	repos, err := src.GetRepositories(ctx)
 	if err != nil {
 		return err
 	}
+	repos = repoFilter.Select(repos)
+
+	destRepos, err := dest.GetRepositories(ctx)
+	if err != nil {
+		return err
+	}
+
+	destByName := make(map[string]*provider.GitRepository)
+	for _, repo := range destRepos {
+		destByName[repo.Name] = repo
+	}
+
 	for _, repo := range repos {
-		if repo.Fork || repo.Empty {
+		destRepo, ok := destByName[repo.Name]
+		if !ok {
+			fmt.Printf("Missing repo: %s\n", repo.Name)
+			continue
+		}
+		if journal.Lookup(repo.Name, state.KindWiki, repo.Name, nil) {
 			continue
 		}
-		if err := provider.MigrateWiki(repo, dest.GetAuth()); err != nil {
+		if err := provider.MigrateWiki(ctx, repo, destRepo); err != nil {
+			return err
+		}
+		if err := journal.Record(repo.Name, state.KindWiki, repo.Name, destRepo.Name); err != nil {
 			return err
 		}
 	}